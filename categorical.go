@@ -0,0 +1,54 @@
+package deidentify
+
+import (
+	"slices"
+	"strings"
+)
+
+// suppressedCategory replaces TypeCategorical values that are suppressed or have no
+// parent category to generalize to
+const suppressedCategory = "*"
+
+// Categorical anonymizes a categorical quasi-identifier such as gender or marital
+// status with mode instead of pseudonymizing it: Suppress replaces it with "*", and
+// Generalize with its parent category from WithCategoryHierarchy, or "*" when it has
+// none. Equal values give equal results, so the output can still be grouped when
+// checking k-anonymity.
+func (d *Deidentifier) Categorical(value string, mode CategoricalMode) (string, error) {
+	core := strings.TrimSpace(value)
+	if core == "" {
+		return value, nil
+	}
+	start := strings.Index(value, core)
+	return value[:start] + d.generalizeCategory(core, mode) + value[start+len(core):], nil
+}
+
+// generalizeCategory suppresses value or replaces it with its parent category,
+// matching the hierarchy ignoring case
+func (d *Deidentifier) generalizeCategory(value string, mode CategoricalMode) string {
+	if mode == Suppress {
+		return suppressedCategory
+	}
+
+	hierarchy := d.categoryHierarchy
+	if hierarchy == nil {
+		hierarchy = categoryHierarchyOptions
+	}
+	if parent, ok := hierarchy[value]; ok {
+		return parent
+	}
+	for category, parent := range hierarchy {
+		if strings.EqualFold(strings.TrimSpace(category), value) {
+			return parent
+		}
+	}
+	return suppressedCategory
+}
+
+// isCategoricalValue reports whether value is a gender or marital status that column
+// type inference takes for TypeCategorical
+func (d *Deidentifier) isCategoricalValue(value string) bool {
+	return slices.ContainsFunc(categoricalValueOptions, func(category string) bool {
+		return strings.EqualFold(category, value)
+	})
+}
@@ -0,0 +1,65 @@
+package deidentify
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// controlColumn returns the index of the key column among names that selects control
+// rows: the WithControlSampleRate column, or the first column when none was named. It
+// returns -1 when no control group is configured.
+func (d *Deidentifier) controlColumn(names []string) (int, error) {
+	if d.controlSampleRate <= 0 {
+		return -1, nil
+	}
+	if d.controlKeyColumn == "" {
+		return 0, nil
+	}
+	for i, name := range names {
+		if name == d.controlKeyColumn {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("control key column %q not found", d.controlKeyColumn)
+}
+
+// controlRows reports, for each row of table, whether it belongs to the control group
+func (d *Deidentifier) controlRows(table *Table) ([]bool, error) {
+	names := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		names[i] = col.Name
+	}
+	keyCol, err := d.controlColumn(names)
+	if err != nil || keyCol < 0 || keyCol >= len(names) {
+		return nil, err
+	}
+
+	values := table.Columns[keyCol].Values
+	control := make([]bool, len(values))
+	for row, value := range values {
+		if value != nil {
+			control[row] = d.isControlKey(fmt.Sprintf("%v", value))
+		}
+	}
+	return control, nil
+}
+
+// isControlKey reports whether the row with the given key value falls in the control
+// group. The choice depends only on the secret key and the trimmed value, so the same
+// rows are always left unchanged.
+func (d *Deidentifier) isControlKey(key string) bool {
+	hash := d.deterministicHash("control:" + strings.TrimSpace(key))
+	fraction := float64(binary.BigEndian.Uint64(hash[:8])) / math.MaxUint64
+	return fraction < d.controlSampleRate
+}
+
+// isControlRow reports whether a row of slice data belongs to the control group
+func (d *Deidentifier) isControlRow(row []string, names []string) (bool, error) {
+	keyCol, err := d.controlColumn(names)
+	if err != nil || keyCol < 0 || keyCol >= len(row) {
+		return false, err
+	}
+	return d.isControlKey(row[keyCol]), nil
+}
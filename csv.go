@@ -0,0 +1,217 @@
+package deidentify
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvSampleRows is how many data rows CSV reads ahead to infer unhinted column types
+const csvSampleRows = 10
+
+// csvCrosswalk streams the column,original,replacement rows of a CSV call to the
+// writer set by WithCrosswalkWriter, once per distinct value. written holds every
+// column and original recorded, so it grows with the distinct values of the input.
+type csvCrosswalk struct {
+	writer  *csv.Writer
+	written map[[2]string]bool
+}
+
+// CSV streams CSV data from r to w, deidentifying every data row. The first record is
+// a header whose cells name the columns and double as mapping namespaces. A header cell
+// may declare its column's type inline as name:type, for example "email:email" or
+// "id:formatted_id"; type names follow SupportedTypes, ignoring case, spaces and
+// underscores. Columns without a valid hint have their type inferred from the first
+// rows. The header written to w carries the plain column names. With
+// WithCrosswalkWriter, every replaced value is also written to the crosswalk.
+func (d *Deidentifier) CSV(r io.Reader, w io.Writer) error {
+	return d.CSVContext(context.Background(), r, w)
+}
+
+// CSVContext is like CSV but checks ctx before each row and returns the context's
+// error once it is cancelled or its deadline passes
+func (d *Deidentifier) CSVContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	reader := csv.NewReader(r)
+	writer := csv.NewWriter(w)
+
+	config, sample, err := d.readCSVHeader(reader)
+	if err != nil || config == nil {
+		return err
+	}
+	if err := writer.Write(config.columnNames); err != nil {
+		return err
+	}
+	crosswalk, err := d.newCSVCrosswalk()
+	if err != nil {
+		return err
+	}
+	defer crosswalk.flush()
+
+	rowIndex := 0
+	writeRow := func(row []string) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		out := make([]string, len(row))
+		if err := d.fillSliceRow(out, row, config, rowIndex); err != nil {
+			return err
+		}
+		if err := crosswalk.record(config.columnNames, row, out); err != nil {
+			return err
+		}
+		rowIndex++
+		return writer.Write(out)
+	}
+
+	for _, row := range sample {
+		if err := writeRow(row); err != nil {
+			return err
+		}
+	}
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read CSV row %d: %w", rowIndex, err)
+		}
+		if err := writeRow(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	return crosswalk.flush()
+}
+
+// inferCSVColumnTypes fills in the types of columns that had no header hint
+func (d *Deidentifier) inferCSVColumnTypes(sample [][]string, config *slicesConfig, hinted []bool) error {
+	inferred, err := d.inferColumnTypes(sample)
+	if err != nil {
+		return fmt.Errorf("failed to infer column types: %w", err)
+	}
+	for i := range config.columnTypes {
+		if hinted[i] {
+			continue
+		}
+		config.columnTypes[i] = TypeGeneric
+		if i < len(inferred) {
+			config.columnTypes[i] = inferred[i]
+		}
+	}
+	return nil
+}
+
+// newCSVCrosswalk starts the crosswalk of a CSV call with its header row, or returns nil
+// when WithCrosswalkWriter is not set
+func (d *Deidentifier) newCSVCrosswalk() (*csvCrosswalk, error) {
+	if d.crosswalk == nil {
+		return nil, nil
+	}
+
+	c := &csvCrosswalk{writer: csv.NewWriter(d.crosswalk), written: make(map[[2]string]bool)}
+	if err := c.writer.Write([]string{"column", "original", "replacement"}); err != nil {
+		return nil, fmt.Errorf("failed to write crosswalk: %w", err)
+	}
+	return c, nil
+}
+
+// parseCSVHeader splits name:type hints off the header cells, reporting which columns
+// carried a valid hint
+func (d *Deidentifier) parseCSVHeader(header []string) (*slicesConfig, []bool) {
+	config := &slicesConfig{
+		columnTypes: make([]DataType, len(header)),
+		columnNames: make([]string, len(header)),
+		numCols:     len(header),
+	}
+	hinted := make([]bool, len(header))
+
+	for i, cell := range header {
+		config.columnNames[i] = cell
+		sep := strings.LastIndex(cell, ":")
+		if sep < 0 {
+			continue
+		}
+		if dataType, err := ParseDataType(cell[sep+1:]); err == nil {
+			config.columnNames[i] = cell[:sep]
+			config.columnTypes[i] = dataType
+			hinted[i] = true
+		}
+	}
+	return config, hinted
+}
+
+// readCSVHeader reads the header and the sample rows and settles every column's type.
+// It returns a nil config for empty input.
+func (d *Deidentifier) readCSVHeader(reader *csv.Reader) (*slicesConfig, [][]string, error) {
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	config, hinted := d.parseCSVHeader(header)
+	sample, err := d.readCSVRows(reader, csvSampleRows)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := d.inferCSVColumnTypes(sample, config, hinted); err != nil {
+		return nil, nil, err
+	}
+	return config, sample, nil
+}
+
+// readCSVRows reads up to limit records, stopping early at the end of input
+func (d *Deidentifier) readCSVRows(reader *csv.Reader, limit int) ([][]string, error) {
+	rows := make([][]string, 0, limit)
+	for len(rows) < limit {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row %d: %w", len(rows), err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// flush writes buffered crosswalk rows to the underlying writer
+func (c *csvCrosswalk) flush() error {
+	if c == nil {
+		return nil
+	}
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		return fmt.Errorf("failed to write crosswalk: %w", err)
+	}
+	return nil
+}
+
+// record writes a crosswalk row for each value of row that out replaced and that was not
+// recorded before, in column order, so the same input always gives the same crosswalk
+func (c *csvCrosswalk) record(columns, row, out []string) error {
+	if c == nil {
+		return nil
+	}
+	for j, original := range row {
+		if j >= len(columns) || out[j] == original || c.written[[2]string{columns[j], original}] {
+			continue
+		}
+		c.written[[2]string{columns[j], original}] = true
+		if err := c.writer.Write([]string{columns[j], original, out[j]}); err != nil {
+			return fmt.Errorf("failed to write crosswalk: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,5 +1,7 @@
 package deidentify
 
+import "fmt"
+
 // String lists for data generation
 var (
 	// Names for generating anonymous identities (100+ options)
@@ -89,4 +91,126 @@ var (
 		"Plaza Mayor", "Via Veneto", "Friedrichstraße", "Bond Street", "Broadway", "Champs-Élysées",
 		"Sheikh Zayed Road", "Las Ramblas", "Nevsky Prospekt", "Puerta del Sol", "Andrássy Avenue", "Khao San Road",
 	}
+
+	// Town names for the locality line of multi-line addresses
+	localityOptions = []string{
+		"Springfield", "Riverton", "Fairview", "Greenville", "Franklin", "Clinton", "Madison", "Georgetown",
+		"Salem", "Oakdale", "Ashland", "Burlington", "Centerville", "Dover", "Hudson", "Kingston",
+		"Lakewood", "Marion", "Milford", "Newport", "Oxford", "Riverside", "Shelby", "Winchester",
+	}
+
+	// Common given names matched by WithGivenNameDictionary when no list is supplied
+	commonGivenNameOptions = []string{
+		"Aaron", "Abigail", "Aisha", "Ana", "Andrew", "Anna", "Anthony", "Ashley", "Barbara", "Benjamin",
+		"Brian", "Carlos", "Carol", "Catherine", "Chen", "Christopher", "Daniel", "David", "Deborah", "Diego",
+		"Dmitri", "Elizabeth", "Emily", "Emma", "Fatima", "Francesca", "Hannah", "Hiroshi", "Ibrahim", "James",
+		"Jennifer", "Jessica", "John", "Jose", "Joseph", "Joshua", "Juan", "Karen", "Kevin", "Laura",
+		"Linda", "Lisa", "Luis", "Mahmoud", "Maria", "Mark", "Mary", "Matthew", "Mei", "Michael",
+		"Michelle", "Mohammed", "Nancy", "Nicole", "Olivia", "Patricia", "Paul", "Priya", "Rachel", "Rahul",
+		"Richard", "Robert", "Sandra", "Sarah", "Sophia", "Stephanie", "Steven", "Susan", "Thomas", "Wei",
+		"William", "Yuki",
+	}
+
+	// Words that introduce an inline mailbox rather than belong to its display name,
+	// as in "Contact Jane Doe <jane@x.com>"
+	mailboxCueWordOptions = []string{
+		"Also", "And", "Ask", "Attn", "Bcc", "Best", "By", "Cc", "Cheers", "Contact", "Dear", "Email", "For",
+		"From", "Hello", "Hi", "Message", "Or", "Ping", "Please", "Reach", "Regards", "Send", "Thanks", "To",
+		"Via", "With", "Write",
+	}
+
+	// Role, team and service words that make a mailbox display name something other than
+	// a person, as in "Acme Support <support@acme.com>" or "Terms of Service <tos@x.com>"
+	mailboxRoleWordOptions = []string{
+		"Accounts", "Admin", "Billing", "Careers", "Desk", "Help", "Helpdesk", "Info", "Legal", "Marketing",
+		"News", "Newsletter", "Noreply", "Notifications", "Office", "Orders", "Press", "Privacy", "Sales",
+		"Security", "Service", "Services", "Support", "Team", "Terms",
+	}
+
+	// Words that address a role or group rather than a person after "Dear" or "Attn:"
+	salutationStopWordOptions = []string{
+		"All", "Applicant", "Board", "Candidate", "Client", "Colleague", "Colleagues", "Committee", "Customer",
+		"Customers", "Editor", "Everyone", "Friend", "Friends", "God", "Hiring", "Madam", "Manager", "Member",
+		"Members", "Parent", "Parents", "Partner", "Partners", "Patient", "Reader", "Recipient", "Sir",
+		"Sirs", "Student", "Students", "Team", "User", "Valued",
+	}
+
+	// Sign-offs and role, team and company words that fill short capitalized lines of an
+	// email signature without being a name
+	signatureStopWordOptions = []string{
+		"Best", "Cheers", "Company", "Confidential", "Corp", "Customer", "Department", "Director", "Email",
+		"Engineer", "Founder", "Group", "Inc", "Ltd", "Manager", "Mobile", "Office", "Officer", "Phone",
+		"President", "Regards", "Sales", "Sent", "Sincerely", "Support", "Team", "Tel", "Thanks", "Warmly",
+		"Yours",
+	}
+
+	// Words that make a capitalized phrase with a particle a place, as in "Museo del
+	// Prado" or "Notre Dame de Paris", rather than a name
+	particlePlaceWordOptions = []string{
+		"Banco", "Basilica", "Cabo", "Campo", "Casa", "Castello", "Catedral", "Cathedral", "Cerro", "Chateau",
+		"Ciudad", "Col", "Colegio", "Dame", "Gare", "Hotel", "Iglesia", "Isla", "Jardin", "Lago", "Mercado",
+		"Museo", "Museu", "Museum", "Notre", "Palacio", "Palais", "Palazzo", "Parc", "Parque", "Paseo", "Piazza",
+		"Plaza", "Ponte", "Porta", "Porto", "Puente", "Puerta", "Puerto", "Teatro", "Universidad",
+	}
+
+	// Default hierarchy Generalize uses for TypeCategorical values: marital statuses
+	// become whether the person has a partner
+	categoryHierarchyOptions = map[string]string{
+		"Married": "Partnered", "Domestic partnership": "Partnered", "Civil union": "Partnered",
+		"Single": "Not partnered", "Never married": "Not partnered", "Divorced": "Not partnered",
+		"Separated": "Not partnered", "Widowed": "Not partnered",
+	}
+
+	// Genders and marital statuses that make column type inference choose TypeCategorical
+	categoricalValueOptions = []string{
+		"Male", "Female", "Non-binary", "Nonbinary", "Transgender", "Intersex", "Man", "Woman",
+		"Married", "Single", "Divorced", "Separated", "Widowed", "Never married", "Domestic partnership",
+		"Civil union",
+	}
+
+	// Second-level labels that form a multi-label TLD with a country code, as in co.uk,
+	// kept whole by WithPreserveTLD
+	multiLabelTLDOptions = []string{
+		"ac.jp", "ac.uk", "co.in", "co.jp", "co.kr", "co.nz", "co.uk", "co.za", "com.ar", "com.au",
+		"com.br", "com.cn", "com.mx", "com.sg", "com.tr", "edu.au", "gov.au", "gov.uk", "net.au",
+		"org.au", "org.nz", "org.uk",
+	}
+
+	// North American toll-free area codes, which vanity numbers commonly use
+	tollFreeAreaCodeOptions = []string{"800", "833", "844", "855", "866", "877", "888"}
+
+	// Telephone keypad digit of each letter A-Z, for reading vanity numbers
+	vanityKeypadDigits = "22233344455566677778889999"
+
+	// Area codes used when phone area codes are redistributed
+	nanpAreaCodeOptions = buildNANPAreaCodes()
+
+	// Letters permitted in the two-letter prefix of a UK National Insurance Number
+	ninoFirstLetterOptions  = "ABCEGHJKLMNOPRSTWXYZ"
+	ninoSecondLetterOptions = "ABCEGHJKLMNPRSTWXYZ"
+	ninoSuffixOptions       = "ABCD"
+
+	// Prefixes that HMRC never allocates
+	invalidNINOPrefixes = map[string]bool{
+		"BG": true, "GB": true, "KN": true, "NK": true, "NT": true, "TN": true, "ZZ": true,
+	}
 )
+
+// buildNANPAreaCodes lists every structurally valid NANP area code (NXX with N=2-9 and
+// a middle digit of 0-8), leaving out N11 service codes and the 37X and 96X blocks
+// reserved for future expansion
+func buildNANPAreaCodes() []string {
+	var codes []string
+	for n := 2; n <= 9; n++ {
+		for x := 0; x <= 8; x++ {
+			for y := 0; y <= 9; y++ {
+				reserved := (n == 3 && x == 7) || (n == 9 && x == 6)
+				serviceCode := x == 1 && y == 1
+				if !reserved && !serviceCode {
+					codes = append(codes, fmt.Sprintf("%d%d%d", n, x, y))
+				}
+			}
+		}
+	}
+	return codes
+}
@@ -0,0 +1,146 @@
+package deidentify
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxDateShiftDays bounds the deterministic shift applied to resolved relative dates
+const maxDateShiftDays = 30
+
+// relativeDateNumbers maps the number words relative dates use to their values
+var relativeDateNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+// dateTimeLayouts lists the formats TypeDateTime values are parsed in. A value keeps the
+// first layout that formats it back unchanged.
+var dateTimeLayouts = []string{
+	time.RFC3339, time.RFC3339Nano, "2006-01-02T15:04:05.000Z07:00", "2006-01-02T15:04:05",
+	"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02", "01/02/2006 15:04:05", "01/02/2006 15:04",
+	"01/02/2006", "1/2/2006", "Jan 2, 2006", "2 Jan 2006", time.RFC1123Z, time.RFC1123,
+}
+
+// addDateUnits moves reference by n days, weeks, months or years and returns the layout
+// that date is precise to: a day for days and weeks, a month or a year otherwise
+func (d *Deidentifier) addDateUnits(reference time.Time, n int, unit string) (time.Time, string) {
+	switch strings.ToLower(unit) {
+	case "week":
+		return reference.AddDate(0, 0, 7*n), "2006-01-02"
+	case "month":
+		return reference.AddDate(0, n, 0), "2006-01"
+	case "year":
+		return reference.AddDate(n, 0, 0), "2006"
+	default:
+		return reference.AddDate(0, 0, n), "2006-01-02"
+	}
+}
+
+// dateShiftDays returns the deterministic shift that resolved dates, and TypeDateTime
+// values under WithConstantDateShift, move by. It depends on the key alone, so intervals
+// between dates in a document or dataset are kept.
+func (d *Deidentifier) dateShiftDays() int {
+	return d.hashShiftDays("date-shift")
+}
+
+// hashShiftDays derives a shift between -maxDateShiftDays and maxDateShiftDays days,
+// but never 0, from a keyed hash of input
+func (d *Deidentifier) hashShiftDays(input string) int {
+	shift := d.hashToIndex(d.deterministicHash(input), 2*maxDateShiftDays) - maxDateShiftDays
+	if shift >= 0 {
+		shift++
+	}
+	return shift
+}
+
+// processRelativeDates replaces relative dates such as "3 days ago", "yesterday" or
+// "last Tuesday" when WithRelativeDates is set: with [DATE], or with the absolute date
+// they resolve to against the reference date, shifted by dateShiftDays
+func (d *Deidentifier) processRelativeDates(run *textRun, text string) string {
+	if !d.relativeDates {
+		return text
+	}
+
+	dateRegex := regexp.MustCompile(relativeDateRegexPattern)
+	return d.replaceMatches(run, text, dateRegex, func(match, _ string) string {
+		if d.dateReference.IsZero() {
+			return d.protect(run, "[DATE]")
+		}
+		date, layout := d.resolveRelativeDate(dateRegex.FindStringSubmatch(match))
+		return d.protect(run, date.AddDate(0, 0, d.dateShiftDays()).Format(layout))
+	})
+}
+
+// relativeDateNumber converts a count written as digits or a number word
+func (d *Deidentifier) relativeDateNumber(count string) int {
+	if n, err := strconv.Atoi(count); err == nil {
+		return n
+	}
+	return relativeDateNumbers[strings.ToLower(count)]
+}
+
+// resolveRelativeDate turns the submatches of relativeDateRegexPattern into the date
+// they name relative to the reference date, with the layout it is precise to
+func (d *Deidentifier) resolveRelativeDate(parts []string) (time.Time, string) {
+	reference := d.dateReference
+	switch {
+	case parts[2] != "":
+		return d.addDateUnits(reference, -d.relativeDateNumber(parts[1]), parts[2])
+	case parts[4] != "":
+		return d.addDateUnits(reference, d.relativeDateNumber(parts[3]), parts[4])
+	case strings.EqualFold(parts[7], "yesterday"):
+		return d.addDateUnits(reference, -1, "day")
+	case parts[7] != "":
+		return d.addDateUnits(reference, 1, "day")
+	}
+
+	direction := 1
+	if strings.EqualFold(parts[5], "last") {
+		direction = -1
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		if strings.EqualFold(parts[6], weekday.String()) {
+			// The closest such weekday strictly before or after the reference date
+			days := (direction*int(weekday-reference.Weekday()) + 7) % 7
+			if days == 0 {
+				days = 7
+			}
+			return d.addDateUnits(reference, direction*days, "day")
+		}
+	}
+	return d.addDateUnits(reference, direction, parts[6])
+}
+
+// shiftDateTime moves a date or timestamp by whole days, keeping its layout and time of
+// day: by a shift derived from the value itself, or by dateShiftDays for every value
+// under WithConstantDateShift. Unix timestamps in seconds or milliseconds are shifted as
+// numbers.
+func (d *Deidentifier) shiftDateTime(value string) (string, error) {
+	days := d.dateShiftDays()
+	if !d.constantDateShift {
+		days = d.hashShiftDays("date-shift:" + value)
+	}
+
+	if regexp.MustCompile(unixTimestampRegexPattern).MatchString(value) {
+		epoch, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid date time %q: %w", value, err)
+		}
+		unit := int64(1)
+		if len(value) == 13 {
+			unit = 1000
+		}
+		return strconv.FormatInt(epoch+int64(days)*86400*unit, 10), nil
+	}
+
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil && t.Format(layout) == value {
+			return t.AddDate(0, 0, days).Format(layout), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date time %q", value)
+}
@@ -1,16 +1,74 @@
 package deidentify
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/base32"
 	"encoding/hex"
 	"fmt"
+	"maps"
 	"math/big"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GeneratorVersion identifies the current generation algorithms. It changes whenever a
+// release makes the same input and key produce a different fake, and WithVersionTag
+// embeds it in generated values.
+const GeneratorVersion = "v1"
+
+// minSecretKeyBytes is how much key material NewDeidentifierStrict requires, matching
+// the 32 random bytes GenerateSecretKey returns
+const minSecretKeyBytes = 32
+
+// phoneContextWindow is how many characters before a bare digit run are searched for a
+// phone cue
+const phoneContextWindow = 30
+
+// Signature detection bounds: how many trailing lines of a text are searched for a
+// signature, and how many lines after its name line may hold the contact details
+const (
+	signatureTailLines    = 12
+	signatureContactLines = 4
+)
+
+// headSampleRows is how many leading rows column type inference scores by default
+const headSampleRows = 10
+
+// maxCollisionRetries bounds how often generateDistinct retries a colliding replacement
+const maxCollisionRetries = 8
+
+// addressWindow is how many bytes around a street type word findAddressMatches searches
+// for the rest of an address, bounding the work done per candidate
+const addressWindow = 160
+
+// addressSpaces lists the bytes addressRegexPattern treats as whitespace
+const addressSpaces = " \t\n\f\r"
+
+// streetTypeWords lists the alternatives of streetTypeRegexPattern
+var streetTypeWords = strings.Split(streetTypeRegexPattern, "|")
+
+// canonicalColumnNames maps types to the column names Text and the convenience methods
+// share their mappings through
+var canonicalColumnNames = map[DataType]string{
+	TypeName: "name", TypeEmail: "email", TypePhone: "phone", TypeSSN: "ssn",
+	TypeCreditCard: "credit_card", TypeAddress: "address", TypeSIN: "sin", TypeNINO: "nino",
+	TypeUsername: "username", TypeCryptoAddress: "crypto_address", TypeLatLong: "lat_long",
+	TypeSWIFT: "swift", TypeIPAddress: "ip_address",
+}
+
+// Age generalization settings: bucket width and the age from which all ages share one bucket
+const (
+	ageBucketSize = 5
+	ageCap        = 90
 )
 
 // DataType represents the type of personally identifiable information
@@ -24,8 +82,26 @@ const (
 	TypeCreditCard
 	TypeAddress
 	TypeGeneric
+	TypeSIN
+	TypeNINO
+	TypeUsername
+	TypeCryptoAddress
+	TypeAge
+	TypeFormattedID
+	TypeLatLong
+	TypeNumericNoise
+	TypeSWIFT
+	TypeDateTime
+	TypeIPAddress
+	TypeCategorical
 )
 
+// TypeInfer asks for a type to be inferred from the values: a column's in Table,
+// Column and the column types given to Slices, a single value's in Deidentify.
+// SlicesChan cannot buffer rows to infer from and rejects it. It is a sentinel rather
+// than a PII type, so it is not listed by SupportedTypes.
+const TypeInfer DataType = -1
+
 // Column represents a single column in a table with its data type and values
 type Column struct {
 	Name     string
@@ -33,11 +109,21 @@ type Column struct {
 	Values   []interface{}
 }
 
+// ColumnSpec describes one column of slice data, keeping its name and type together
+type ColumnSpec struct {
+	Name string
+	Type DataType
+}
+
 // Deidentifier handles the deidentification of PII data
 type Deidentifier struct {
-	secretKey     []byte
-	mappingTables map[string]map[string]string
-	mutex         sync.RWMutex
+	secretKey      []byte
+	mappings       MappingStore
+	columnTypes    *columnTypeIndex
+	customPatterns *patternRegistry
+	pools          *replacementPools
+	occurrences    atomic.Uint64
+	options
 }
 
 // Table represents a collection of columns
@@ -45,15 +131,29 @@ type Table struct {
 	Columns []Column
 }
 
+// TypeInfo describes a supported DataType for tools that list the library's capabilities
+type TypeInfo struct {
+	Type           DataType
+	Name           string
+	DetectedInText bool // whether Text finds this type in free text
+	Example        string
+}
+
 // patternSet holds compiled regex patterns for type inference
 type patternSet struct {
-	email       *regexp.Regexp
-	phone       *regexp.Regexp
-	ssn         *regexp.Regexp
-	creditCard  *regexp.Regexp
-	name        *regexp.Regexp
-	address     *regexp.Regexp
-	addressWord *regexp.Regexp
+	email          *regexp.Regexp
+	phone          *regexp.Regexp
+	ssn            *regexp.Regexp
+	creditCard     *regexp.Regexp
+	name           *regexp.Regexp
+	address        *regexp.Regexp
+	addressWord    *regexp.Regexp
+	addressCountry *regexp.Regexp
+	specialAddress []*regexp.Regexp
+	sin            *regexp.Regexp
+	nino           *regexp.Regexp
+	username       *regexp.Regexp
+	cryptoAddress  *regexp.Regexp
 }
 
 // slicesConfig holds the configuration for slice processing
@@ -63,6 +163,17 @@ type slicesConfig struct {
 	numCols     int
 }
 
+// textRun carries per-call state through the steps of a single Text call
+type textRun struct {
+	err          error                        // first replacement error, recorded under FailFast
+	protected    []string                     // replacements hidden from later steps, see protect
+	mentioned    map[DataType]map[string]bool // replacements already written, see WithRepeatAlias
+	collect      bool                         // whether findings are recorded, see TextWithFindings
+	findings     []Finding
+	replacements []string // replacements written, kept for WithUncertainRedaction
+	origins      []int    // text byte each byte came from, -1 in replacements; see replaceSpans
+}
+
 // Address is a convenience method to deidentify a single address
 func (d *Deidentifier) Address(address string) (string, error) {
 	// Check for a label prefix (like "European HQ:") and extract the actual address part
@@ -129,11 +240,107 @@ func (d *Deidentifier) Address(address string) (string, error) {
 	return deidentified, nil
 }
 
-// ClearMappings clears all stored mappings (useful for testing)
+// Age generalizes a single age into a 5-year bucket such as "30-34", with every age
+// of 90 or more reported as "90+" as HIPAA Safe Harbor requires
+func (d *Deidentifier) Age(age string) (string, error) {
+	return d.deidentifyValue(age, TypeAge, "age")
+}
+
+// Auto deidentifies a single value of unknown type. The type is chosen with the scoring
+// column inference uses, and the value is replaced as the convenience method for that
+// type would, sharing its mappings. Values no type matches, or that match several types
+// equally well, get the generic replacement as TypeGeneric.
+func (d *Deidentifier) Auto(value string) (string, DataType, error) {
+	scores := d.initializeTypeScores()
+	d.scoreValue(strings.TrimSpace(value), d.compilePatterns(), scores)
+	dataType, top := d.findHighestScoringType(scores)
+	for other, score := range scores {
+		if top == 0 || score == top && other != dataType {
+			// deidentifyValue passes TypeGeneric through, so the replacement is made here
+			core := strings.TrimSpace(value)
+			if core == "" {
+				return value, TypeGeneric, nil
+			}
+			return strings.Replace(value, core, d.generateGeneric(core), 1), TypeGeneric, nil
+		}
+	}
+
+	var result string
+	var err error
+	if dataType == TypeAddress {
+		result, err = d.Address(value)
+	} else {
+		result, err = d.deidentifyValue(value, dataType, canonicalColumnNames[dataType])
+	}
+	if err != nil {
+		return "", dataType, err
+	}
+	return result, dataType, nil
+}
+
+// ClearMappings clears all stored mappings (useful for testing).
+// Custom stores are only cleared if they provide a Clear() method.
 func (d *Deidentifier) ClearMappings() {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
-	d.mappingTables = make(map[string]map[string]string)
+	if store, ok := d.mappings.(interface{ Clear() }); ok {
+		store.Clear()
+	}
+}
+
+// CollisionReport estimates, per data type, the share of distinct originals that
+// share a replacement with another original, as 1 - replacements/originals over the
+// mappings made so far. A high rate means the replacement pool is small for the data's
+// cardinality and joins on the deidentified values may merge different people. It
+// returns nil when the mapping store cannot be enumerated, as with most custom stores.
+func (d *Deidentifier) CollisionReport() map[DataType]float64 {
+	store, ok := d.mappings.(interface {
+		snapshot() map[string]map[string]string
+	})
+	if !ok {
+		return nil
+	}
+
+	originals := make(map[DataType]map[string]bool)
+	replacements := make(map[DataType]map[string]bool)
+	for column, table := range store.snapshot() {
+		dataType, known := d.columnTypes.get(column)
+		if !known {
+			continue
+		}
+		if originals[dataType] == nil {
+			originals[dataType] = make(map[string]bool)
+			replacements[dataType] = make(map[string]bool)
+		}
+		for original, replacement := range table {
+			originals[dataType][original] = true
+			replacements[dataType][replacement] = true
+		}
+	}
+
+	report := make(map[DataType]float64, len(originals))
+	for dataType, seen := range originals {
+		report[dataType] = 1 - float64(len(replacements[dataType]))/float64(len(seen))
+	}
+	return report
+}
+
+// Column deidentifies one column's values with the column's shared mapping. The type
+// is inferred from the values as Slices does unless given; TypeInfer requests
+// inference explicitly. Empty values stay empty and a nil slice gives nil.
+func (d *Deidentifier) Column(name string, values []string, t ...DataType) ([]string, error) {
+	dataType, err := d.columnDataType(values, t)
+	if err != nil || values == nil {
+		return nil, err
+	}
+
+	result := make([]string, len(values))
+	for i, value := range values {
+		deidentified, err := d.deidentifyDeclared(value, dataType, name)
+		if err != nil {
+			return nil, fmt.Errorf("error deidentifying column %s, row %d: %w", name, i, err)
+		}
+		result[i] = deidentified
+	}
+	return result, nil
 }
 
 // CreditCard is a convenience method to deidentify a single credit card number
@@ -141,11 +348,96 @@ func (d *Deidentifier) CreditCard(cc string) (string, error) {
 	return d.deidentifyValue(cc, TypeCreditCard, "credit_card")
 }
 
+// CryptoAddress is a convenience method to deidentify a single cryptocurrency wallet
+// address, keeping its kind (Ethereum, Bitcoin bech32 or Bitcoin Base58)
+func (d *Deidentifier) CryptoAddress(address string) (string, error) {
+	return d.deidentifyValue(address, TypeCryptoAddress, "crypto_address")
+}
+
+// DateTime shifts a single date or timestamp by whole days, keeping its format and time
+// of day. By default each value gets its own shift of up to 30 days, which hides exact
+// dates but can reorder events; with WithConstantDateShift every value moves by the
+// same key-derived offset, which keeps ordering and intervals.
+func (d *Deidentifier) DateTime(value string) (string, error) {
+	return d.deidentifyValue(value, TypeDateTime, "date_time")
+}
+
+// Deidentify replaces value according to dataType, using columnName as the mapping
+// namespace. It is the general entry point when the type is only known at runtime;
+// TypeGeneric values are returned unchanged. Text and the convenience methods use the
+// canonical column names listed in the README ("email", "phone", "address", ...), so
+// passing the same name here yields the same replacement they do. With TypeInfer the
+// type is inferred from value alone, as Column does for a column of one value.
+func (d *Deidentifier) Deidentify(value string, dataType DataType, columnName string) (string, error) {
+	if dataType == TypeInfer {
+		dataType, _ = d.columnDataType([]string{value}, nil)
+	}
+	return d.deidentifyDeclared(value, dataType, columnName)
+}
+
+// DiffMappings counts, per column of sample values, how many values d and other would
+// replace differently, to gauge the blast radius of a key, option, pool or version
+// change before deploying it. Each column's type is inferred from its samples by d.
+// Replacements are computed with empty mappings, so neither deidentifier's stored
+// mappings are read or changed; failing values count as different unless both fail.
+func (d *Deidentifier) DiffMappings(other *Deidentifier, values map[string][]string) map[string]int {
+	before, after := d.detached(), other.detached()
+	diffs := make(map[string]int, len(values))
+	for column, samples := range values {
+		dataType, _ := d.columnDataType(samples, nil)
+		diffs[column] = 0
+		for _, value := range samples {
+			old, oldErr := before.deidentifyValue(value, dataType, column)
+			updated, newErr := after.deidentifyValue(value, dataType, column)
+			if old != updated || (oldErr == nil) != (newErr == nil) {
+				diffs[column]++
+			}
+		}
+	}
+	return diffs
+}
+
 // Email is a convenience method to deidentify a single email
 func (d *Deidentifier) Email(email string) (string, error) {
 	return d.deidentifyValue(email, TypeEmail, "email")
 }
 
+// IPAddress is a convenience method to deidentify a single IPv4 address, keeping a
+// ":port" or "/mask" suffix
+func (d *Deidentifier) IPAddress(address string) (string, error) {
+	return d.deidentifyValue(address, TypeIPAddress, "ip_address")
+}
+
+// LastRunMappings returns copies of the mappings made so far, keyed by column (or
+// "group:<name>" for column groups): forward maps original→replacement and reverse
+// maps replacement→original. The copies can be stored in a vault for controlled
+// re-identification and are never shared with the Deidentifier. It returns nil maps
+// when the mapping store cannot be enumerated, as with most custom stores.
+func (d *Deidentifier) LastRunMappings() (forward, reverse map[string]map[string]string) {
+	store, ok := d.mappings.(interface {
+		snapshot() map[string]map[string]string
+	})
+	if !ok {
+		return nil, nil
+	}
+
+	forward = store.snapshot()
+	reverse = make(map[string]map[string]string, len(forward))
+	for column, table := range forward {
+		reversed := make(map[string]string, len(table))
+		for original, replacement := range table {
+			reversed[replacement] = original
+		}
+		reverse[column] = reversed
+	}
+	return forward, reverse
+}
+
+// NINO is a convenience method to deidentify a single UK National Insurance Number
+func (d *Deidentifier) NINO(nino string) (string, error) {
+	return d.deidentifyValue(nino, TypeNINO, "nino")
+}
+
 // Name is a convenience method to deidentify a single name
 func (d *Deidentifier) Name(name string) (string, error) {
 	return d.deidentifyValue(name, TypeName, "name")
@@ -156,19 +448,126 @@ func (d *Deidentifier) Phone(phone string) (string, error) {
 	return d.deidentifyValue(phone, TypePhone, "phone")
 }
 
+// Rekey regenerates a stored mapping table under newKey for key rotation, without the
+// source data: oldMappings is a forward table keyed by column, as LastRunMappings
+// returns, and every original in it gets the replacement a Deidentifier with newKey and
+// d's options would give it. Column types come from d's own mappings when it made them,
+// then from the names of custom patterns and of the columns Text and the convenience
+// methods use, and are otherwise inferred from the originals; a column whose type
+// cannot be told is an error. d and its mappings are not changed.
+func (d *Deidentifier) Rekey(newKey string, oldMappings map[string]map[string]string) (map[string]map[string]string, error) {
+	if newKey == "" {
+		return nil, fmt.Errorf("new key is empty")
+	}
+	if d.nonDeterministic {
+		return nil, fmt.Errorf("non-deterministic mode keeps no mappings to rekey")
+	}
+
+	// Tables are already keyed by namespace, and every original needs a replacement
+	rekeyed := d.detached()
+	rekeyed.secretKey = []byte(newKey)
+	rekeyed.columnGroups = nil
+	rekeyed.skipAlreadyFake = false
+
+	result := make(map[string]map[string]string, len(oldMappings))
+	for column, table := range oldMappings {
+		dataType, err := d.mappingType(column, slices.Collect(maps.Keys(table)))
+		if err != nil {
+			return nil, err
+		}
+		entries := make(map[string]string, len(table))
+		for original := range table {
+			replacement, err := rekeyed.deidentifyValue(original, dataType, column)
+			if err != nil {
+				return nil, fmt.Errorf("error rekeying column %s: %w", column, err)
+			}
+			entries[original] = replacement
+		}
+		result[column] = entries
+	}
+	return result, nil
+}
+
+// SIN is a convenience method to deidentify a single Canadian Social Insurance Number
+func (d *Deidentifier) SIN(sin string) (string, error) {
+	return d.deidentifyValue(sin, TypeSIN, "sin")
+}
+
 // SSN is a convenience method to deidentify a single SSN
 func (d *Deidentifier) SSN(ssn string) (string, error) {
 	return d.deidentifyValue(ssn, TypeSSN, "ssn")
 }
 
+// SWIFT is a convenience method to deidentify a single SWIFT/BIC code
+func (d *Deidentifier) SWIFT(code string) (string, error) {
+	return d.deidentifyValue(code, TypeSWIFT, "swift")
+}
+
 // Slices processes a slice of string slices ([][]string)
 // Each inner slice represents a row of data
 // Optional parameters:
-//   - columnTypes: DataType for each column (will infer if not provided)
+//   - columnTypes: DataType for each column (will infer if not provided, or for
+//     columns given TypeInfer)
 //   - columnNames: names for each column (will generate if not provided)
 //
 // Usage: Slices(data) or Slices(data, columnTypes) or Slices(data, columnTypes, columnNames)
 func (d *Deidentifier) Slices(data [][]string, optional ...interface{}) ([][]string, error) {
+	return d.SlicesContext(context.Background(), data, optional...)
+}
+
+// SlicesChan deidentifies rows as they arrive on in and sends the results to out, so
+// pipelines built on channels need not materialize the whole dataset. Inference would
+// require buffering, so the schema is required. Mappings stay consistent with every
+// other method. It returns when in is closed or a row fails, and closes out either way.
+// For the same reason a TypeInfer column is an error.
+func (d *Deidentifier) SlicesChan(in <-chan []string, out chan<- []string, schema []ColumnSpec) error {
+	return d.SlicesChanContext(context.Background(), in, out, schema)
+}
+
+// SlicesChanContext behaves like SlicesChan but stops with the context's error when ctx
+// is cancelled, including while waiting to receive or send a row
+func (d *Deidentifier) SlicesChanContext(ctx context.Context, in <-chan []string, out chan<- []string, schema []ColumnSpec) error {
+	defer close(out)
+	if len(schema) == 0 {
+		return fmt.Errorf("a schema is required to process rows from a channel")
+	}
+
+	columnTypes, columnNames := d.splitSchema(schema)
+	if col := slices.Index(columnTypes, TypeInfer); col >= 0 {
+		return fmt.Errorf("column %d (%s) has TypeInfer, but rows from a channel cannot be inferred from", col, columnNames[col])
+	}
+	config := &slicesConfig{columnTypes: columnTypes, columnNames: columnNames, numCols: len(schema)}
+	for rowIndex := 0; ; rowIndex++ {
+		var row []string
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case row, ok = <-in:
+			if !ok {
+				return nil
+			}
+		}
+
+		if len(row) != config.numCols {
+			return fmt.Errorf("row %d has %d columns, schema has %d", rowIndex, len(row), config.numCols)
+		}
+		deidentified := make([]string, len(row))
+		if err := d.fillSliceRow(deidentified, row, config, rowIndex); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case out <- deidentified:
+		}
+	}
+}
+
+// SlicesContext is like Slices but checks ctx before each row and returns the
+// context's error as soon as it is cancelled or its deadline passes.
+func (d *Deidentifier) SlicesContext(ctx context.Context, data [][]string, optional ...interface{}) ([][]string, error) {
 	if len(data) == 0 {
 		return [][]string{}, nil
 	}
@@ -178,30 +577,73 @@ func (d *Deidentifier) Slices(data [][]string, optional ...interface{}) ([][]str
 		return nil, err
 	}
 
-	return d.processSliceData(data, config)
+	return d.processSliceData(ctx, data, config)
+}
+
+// SlicesInPlace behaves like Slices but writes the deidentified values back into data
+// instead of allocating a new [][]string, which keeps peak memory down for large
+// datasets. The caller's slices are mutated; on error, rows before the failing row
+// have already been overwritten.
+//
+// Usage: SlicesInPlace(data) or SlicesInPlace(data, columnTypes) or SlicesInPlace(data, columnTypes, columnNames)
+func (d *Deidentifier) SlicesInPlace(data [][]string, optional ...interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	config, err := d.parseSlicesParameters(data, optional...)
+	if err != nil {
+		return err
+	}
+
+	for i, row := range data {
+		if err := d.fillSliceRow(row, row, config, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SlicesWithSchema processes a slice of string slices using a schema in which each
+// column's name and type travel together, avoiding misaligned parallel slices
+func (d *Deidentifier) SlicesWithSchema(data [][]string, schema []ColumnSpec) ([][]string, error) {
+	if len(data) == 0 {
+		return [][]string{}, nil
+	}
+
+	if len(schema) != len(data[0]) {
+		return nil, fmt.Errorf("mismatch between data columns (%d) and schema columns (%d)",
+			len(data[0]), len(schema))
+	}
+
+	columnTypes, columnNames := d.splitSchema(schema)
+	return d.Slices(data, columnTypes, columnNames)
 }
 
 // Table processes an entire table
 func (d *Deidentifier) Table(table *Table) (*Table, error) {
+	return d.TableContext(context.Background(), table)
+}
+
+// TableContext is like Table but checks ctx before each value and returns the
+// context's error as soon as it is cancelled or its deadline passes.
+func (d *Deidentifier) TableContext(ctx context.Context, table *Table) (*Table, error) {
 	result := &Table{
 		Columns: make([]Column, len(table.Columns)),
 	}
+	control, err := d.controlRows(table)
+	if err != nil {
+		return nil, err
+	}
 
 	for i, col := range table.Columns {
-		deidentifiedValues := make([]interface{}, len(col.Values))
-
-		for j, value := range col.Values {
-			if value == nil {
-				deidentifiedValues[j] = nil
-				continue
-			}
+		if col.DataType == TypeInfer {
+			col.DataType = d.inferTableColumnType(col)
+		}
 
-			strValue := fmt.Sprintf("%v", value)
-			deidentifiedValue, err := d.deidentifyValue(strValue, col.DataType, col.Name)
-			if err != nil {
-				return nil, fmt.Errorf("error deidentifying column %s, row %d: %w", col.Name, j, err)
-			}
-			deidentifiedValues[j] = deidentifiedValue
+		deidentifiedValues, err := d.processTableColumn(ctx, col, control)
+		if err != nil {
+			return nil, err
 		}
 
 		result.Columns[i] = Column{
@@ -216,21 +658,22 @@ func (d *Deidentifier) Table(table *Table) (*Table, error) {
 
 // Text identifies and deidentifies PII from a text string
 func (d *Deidentifier) Text(text string) (string, error) {
-	if text == "" {
-		return "", nil
-	}
+	return d.runText(&textRun{}, text)
+}
 
-	result := text
-	result = d.processEmails(result)
-	result = d.processPhones(result)
-	result = d.processSSNs(result, text)
-	result = d.processCreditCards(result)
-	result = d.processContextAddresses(result)
-	result = d.processSpecialAddresses(result)
-	result = d.processNames(result)
-	result = d.processStandardAddresses(result)
+// Tokenize returns an opaque token for value: the base32 HMAC of the column and value,
+// truncated to the configured token length (16 by default, see WithTokenLength). Unlike
+// the other methods it keeps no format or meaning, only stable equality, which suits
+// privacy-preserving joins between datasets processed with the same key.
+func (d *Deidentifier) Tokenize(value, columnName string) string {
+	hash := d.deterministicHash(columnName + "\x00" + value)
+	token := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(hash))
+	return token[:d.tokenLength]
+}
 
-	return result, nil
+// Username is a convenience method to deidentify a single social handle, keeping a leading @
+func (d *Deidentifier) Username(username string) (string, error) {
+	return d.deidentifyValue(username, TypeUsername, "username")
 }
 
 // GenerateSecretKey generates a cryptographically secure random key
@@ -243,12 +686,170 @@ func GenerateSecretKey() (string, error) {
 	return hex.EncodeToString(key), nil
 }
 
-// NewDeidentifier creates a new deidentifier with a secret key
-func NewDeidentifier(secretKey string) *Deidentifier {
-	return &Deidentifier{
-		secretKey:     []byte(secretKey),
-		mappingTables: make(map[string]map[string]string),
+// NewDeidentifier creates a new deidentifier with a secret key and optional settings
+func NewDeidentifier(secretKey string, opts ...Option) *Deidentifier {
+	d := &Deidentifier{
+		secretKey:      []byte(secretKey),
+		mappings:       newMemoryStore(),
+		columnTypes:    newColumnTypeIndex(),
+		customPatterns: &patternRegistry{},
+		pools:          newReplacementPools(),
+		options: options{
+			maxStreetNumber:     defaultMaxStreetNumber,
+			tokenLength:         defaultTokenLength,
+			numericNoisePercent: defaultNumericNoisePercent,
+		},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// NewDeidentifierStrict creates a new deidentifier like NewDeidentifier, but returns an
+// error for a key with less than 32 bytes of material. A hex key, such as one from
+// GenerateSecretKey, counts its decoded bytes; any other key counts its raw bytes.
+func NewDeidentifierStrict(secretKey string, opts ...Option) (*Deidentifier, error) {
+	material := len(secretKey)
+	if decoded, err := hex.DecodeString(secretKey); err == nil {
+		material = len(decoded)
+	}
+	if material < minSecretKeyBytes {
+		return nil, fmt.Errorf("secret key has %d bytes of material, at least %d are required", material, minSecretKeyBytes)
+	}
+	return NewDeidentifier(secretKey, opts...), nil
+}
+
+// ParseDataType returns the DataType named by name, matching the names reported by
+// SupportedTypes while ignoring case, spaces, underscores and hyphens, so "credit_card",
+// "Credit card" and "creditcard" all give TypeCreditCard
+func ParseDataType(name string) (DataType, error) {
+	normalize := strings.NewReplacer(" ", "", "_", "", "-", "")
+	key := normalize.Replace(strings.ToLower(strings.TrimSpace(name)))
+	for _, info := range SupportedTypes() {
+		if normalize.Replace(strings.ToLower(info.Name)) == key {
+			return info.Type, nil
+		}
+	}
+	return TypeGeneric, fmt.Errorf("unknown data type %q", name)
+}
+
+// SupportedTypes lists every DataType with a human-readable name, whether Text detects
+// it in free text, and an example input
+func SupportedTypes() []TypeInfo {
+	return []TypeInfo{
+		{Type: TypeName, Name: "Name", DetectedInText: true, Example: "Bilbo Baggins"},
+		{Type: TypeEmail, Name: "Email", DetectedInText: true, Example: "bilbo@bag-end.shire"},
+		{Type: TypePhone, Name: "Phone", DetectedInText: true, Example: "(555) 123-4567"},
+		{Type: TypeSSN, Name: "SSN", DetectedInText: true, Example: "123-45-6789"},
+		{Type: TypeCreditCard, Name: "Credit card", DetectedInText: true, Example: "4111-1111-1111-1111"},
+		{Type: TypeAddress, Name: "Address", DetectedInText: true, Example: "42 Elm Street"},
+		{Type: TypeGeneric, Name: "Generic", DetectedInText: false, Example: ""},
+		{Type: TypeSIN, Name: "SIN", DetectedInText: true, Example: "SIN 046 454 286"},
+		{Type: TypeNINO, Name: "NINO", DetectedInText: true, Example: "AB 12 34 56 C"},
+		{Type: TypeUsername, Name: "Username", DetectedInText: true, Example: "@frodo_b"},
+		{Type: TypeCryptoAddress, Name: "Crypto address", DetectedInText: true, Example: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+		{Type: TypeAge, Name: "Age", DetectedInText: false, Example: "37"},
+		{Type: TypeFormattedID, Name: "Formatted ID", DetectedInText: false, Example: "EMP-000123"},
+		{Type: TypeLatLong, Name: "Lat Long", DetectedInText: true, Example: "lat: 37.7749, lng: -122.4194"},
+		{Type: TypeNumericNoise, Name: "Numeric noise", DetectedInText: false, Example: "1,234.50"},
+		{Type: TypeSWIFT, Name: "SWIFT", DetectedInText: true, Example: "SWIFT: DEUTDEFF500"},
+		{Type: TypeDateTime, Name: "Date time", DetectedInText: false, Example: "2024-03-15T09:30:00Z"},
+		{Type: TypeIPAddress, Name: "IP address", DetectedInText: true, Example: "from 203.0.113.7:443"},
+		{Type: TypeCategorical, Name: "Categorical", DetectedInText: false, Example: "Married"},
+	}
+}
+
+// addressCandidates returns the positions of the street type words addressRegexPattern
+// requires, preceded by whitespace or a comma. Every address match contains one, so text
+// without any cannot hold an address.
+func (d *Deidentifier) addressCandidates(text string) []int {
+	var candidates []int
+	for i := 1; i < len(text); i++ {
+		if strings.IndexByte(addressSpaces+",", text[i-1]) < 0 || strings.IndexByte(addressSpaces+",", text[i]) >= 0 {
+			continue
+		}
+		if d.isStreetTypeAt(text, i) {
+			candidates = append(candidates, i)
+		}
+	}
+	return candidates
+}
+
+// applyChecksum replaces the last letter or digit of a generated formatted ID with the
+// check digit WithChecksum configures for columnName
+func (d *Deidentifier) applyChecksum(id string, dataType DataType, columnName string) string {
+	algo, ok := d.checksums[columnName]
+	if !ok || dataType != TypeFormattedID {
+		return id
+	}
+	pos := strings.LastIndexFunc(id, func(r rune) bool {
+		return r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z'
+	})
+	if pos < 0 {
+		return id
+	}
+	var payload strings.Builder
+	for _, r := range id[:pos] {
+		if r >= '0' && r <= '9' {
+			payload.WriteRune(r)
+		}
+	}
+	if payload.Len() == 0 {
+		return id
+	}
+
+	var check string
+	switch algo {
+	case ChecksumMod11:
+		check = d.calculateMod11CheckDigit(payload.String())
+	default:
+		// calculateLuhnCheckDigit expects the check position to be excluded, as here
+		check = strconv.Itoa(d.calculateLuhnCheckDigit(payload.String()))
+	}
+	return id[:pos] + check + id[pos+1:]
+}
+
+// applySeparator rewrites the separators between the digit groups of a generated SSN,
+// phone or credit card number to the WithSeparatorNormalization separator
+func (d *Deidentifier) applySeparator(value string, dataType DataType) string {
+	if !d.normalizeSeparators || dataType != TypeSSN && dataType != TypePhone && dataType != TypeCreditCard {
+		return value
+	}
+	separatorRegex := regexp.MustCompile(separatorRunRegexPattern)
+	normalized := separatorRegex.ReplaceAllString(value, d.separator)
+	if d.separator == "" {
+		return normalized
+	}
+	// A leading "(" leaves a separator with no digit group before it
+	return strings.TrimPrefix(normalized, d.separator)
+}
+
+// applyOutputCase sets the case of a generated email or username as WithOutputCase
+// asks, following original for Preserve. Other types and values passed through, such
+// as allowlisted emails, are returned unchanged.
+func (d *Deidentifier) applyOutputCase(original, generated string, dataType DataType) string {
+	if dataType != TypeEmail && dataType != TypeUsername || generated == original {
+		return generated
+	}
+
+	switch d.outputCase {
+	case Upper:
+		return strings.ToUpper(generated)
+	case Preserve:
+		if original == strings.ToUpper(original) && original != strings.ToLower(original) {
+			return strings.ToUpper(generated)
+		}
+		first := strings.IndexFunc(original, unicode.IsLetter)
+		if first < 0 || strings.IndexFunc(original, unicode.IsUpper) != first {
+			return generated
+		}
+		// Generated emails and usernames are ASCII, so the first letter is one byte
+		if i := strings.IndexFunc(generated, unicode.IsLetter); i >= 0 {
+			return generated[:i] + strings.ToUpper(generated[i:i+1]) + generated[i+1:]
+		}
 	}
+	return generated
 }
 
 // calculateLuhnCheckDigit calculates the Luhn checksum digit
@@ -274,23 +875,139 @@ func (d *Deidentifier) calculateLuhnCheckDigit(cardNumber string) int {
 	return (10 - (sum % 10)) % 10
 }
 
+// calculateMod11CheckDigit calculates the mod-11 check digit of digits, weighting them
+// 2, 3, 4, ... from the right; a check value of 10 is written as X
+func (d *Deidentifier) calculateMod11CheckDigit(digits string) string {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		sum += int(digits[i]-'0') * (len(digits) - i + 1)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return "X"
+	}
+	return strconv.Itoa(check)
+}
+
+// capLength cuts a name or address replacement to the WithReplacementLengthCap of its
+// type, at the last word boundary that fits when there is one
+func (d *Deidentifier) capLength(value string, dataType DataType) string {
+	limit := d.lengthCaps[dataType]
+	runes := []rune(value)
+	if limit <= 0 || len(runes) <= limit {
+		return value
+	}
+
+	cut := string(runes[:limit])
+	if next := runes[limit]; next != ' ' {
+		if space := strings.LastIndex(cut, " "); space > 0 {
+			cut = cut[:space]
+		}
+	}
+	return strings.TrimRight(cut, " ,")
+}
+
+// columnDataType returns the type given to Column, inferring it from the values when
+// none or TypeInfer was given, or an error for more than one type
+func (d *Deidentifier) columnDataType(values []string, t []DataType) (DataType, error) {
+	if len(t) > 1 {
+		return TypeGeneric, fmt.Errorf("expected at most one data type, got %d", len(t))
+	}
+	if len(t) == 1 && t[0] != TypeInfer {
+		return t[0], nil
+	}
+
+	data := make([][]string, 0, len(values))
+	for _, value := range values {
+		data = append(data, []string{value})
+	}
+	return d.inferSingleColumnType(data, 0, d.compilePatterns()), nil
+}
+
 // compilePatterns compiles all regex patterns once for efficiency
 func (d *Deidentifier) compilePatterns() *patternSet {
 	return &patternSet{
-		email:       regexp.MustCompile(emailRegexPattern),
-		phone:       regexp.MustCompile(phoneRegexPattern),
-		ssn:         regexp.MustCompile(ssnRegexPattern),
-		creditCard:  regexp.MustCompile(creditCardRegexPattern),
-		name:        regexp.MustCompile(nameRegexPattern),
-		address:     regexp.MustCompile(addressRegexPattern),
-		addressWord: regexp.MustCompile(addressWordRegexPattern),
+		email:          regexp.MustCompile(emailRegexPattern),
+		phone:          regexp.MustCompile(phoneRegexPattern),
+		ssn:            regexp.MustCompile(ssnValueRegexPattern),
+		creditCard:     regexp.MustCompile(creditCardRegexPattern),
+		name:           regexp.MustCompile(nameRegexPattern),
+		address:        regexp.MustCompile(addressRegexPattern),
+		addressWord:    regexp.MustCompile(addressWordRegexPattern),
+		addressCountry: regexp.MustCompile(addressCountryRegexPattern),
+		specialAddress: []*regexp.Regexp{
+			regexp.MustCompile(specialAddressPattern1),
+			regexp.MustCompile(specialAddressPattern2),
+		},
+		sin:           regexp.MustCompile(sinRegexPattern),
+		nino:          regexp.MustCompile(ninoRegexPattern),
+		username:      regexp.MustCompile(usernameRegexPattern),
+		cryptoAddress: regexp.MustCompile(cryptoAddressRegexPattern),
+	}
+}
+
+// deidentifyDeclared deidentifies a value whose type was declared or inferred for its
+// column rather than detected in text, first routing values without the shape of that
+// type to WithFormatMismatchHandler
+func (d *Deidentifier) deidentifyDeclared(value string, dataType DataType, columnName string) (string, error) {
+	core := strings.TrimSpace(value)
+	if !d.checkTypeFormats || core == "" || d.matchesTypeFormat(core, dataType) {
+		return d.deidentifyValue(value, dataType, columnName)
+	}
+	if d.formatMismatchHandler == nil {
+		return value, nil
+	}
+	return d.formatMismatchHandler(value, dataType, columnName), nil
+}
+
+// deidentifyGivenName replaces a standalone given name as a name in the "given_name"
+// namespace, whose replacements are single given names
+func (d *Deidentifier) deidentifyGivenName(run *textRun, name string) string {
+	deidentified, err := d.deidentifyTextValue(run, name, TypeName, "given_name")
+	if err != nil {
+		return d.redactionError(run, name, "[NAME REDACTION ERROR]", err)
 	}
+	return deidentified
+}
+
+// deidentifyTextValue replaces a value found by Text, writing the type's alias from
+// WithRepeatAlias instead when the same replacement was already written in this run
+func (d *Deidentifier) deidentifyTextValue(run *textRun, value string, dataType DataType, columnName string) (string, error) {
+	return d.deidentifyCasedTextValue(run, value, dataType, columnName, func(s string) string { return s })
+}
+
+// deidentifyCasedTextValue is deidentifyTextValue for a value written in another case
+// than its mapping, as in "JOHN SMITH": caseOf is applied to the replacement before it is
+// recorded, so the Finding holds what is written, while an alias is written as it is
+func (d *Deidentifier) deidentifyCasedTextValue(run *textRun, value string, dataType DataType, columnName string, caseOf func(string) string) (string, error) {
+	deidentified, err := d.deidentifyValue(value, dataType, columnName)
+	if err != nil {
+		return "", err
+	}
+
+	if alias, ok := d.repeatAliases[dataType]; ok && d.mentionedBefore(run, dataType, deidentified) {
+		d.recordFinding(run, dataType, value, alias)
+		return d.protect(run, alias), nil
+	}
+	deidentified = caseOf(deidentified)
+	d.recordFinding(run, dataType, value, deidentified)
+	return deidentified, nil
 }
 
 // deidentifyValue handles individual value deidentification
 func (d *Deidentifier) deidentifyValue(value string, dataType DataType, columnName string) (string, error) {
-	if value == "" {
-		return "", nil
+	// Surrounding whitespace is kept for fixed-width data; only the core is replaced
+	core := strings.TrimSpace(value)
+	if core == "" {
+		return value, nil
+	}
+	if core != value {
+		result, err := d.deidentifyValue(core, dataType, columnName)
+		if err != nil {
+			return "", err
+		}
+		start := strings.Index(value, core)
+		return value[:start] + result + value[start+len(core):], nil
 	}
 
 	// Generic type means no PII detected — return value unchanged
@@ -298,33 +1015,65 @@ func (d *Deidentifier) deidentifyValue(value string, dataType DataType, columnNa
 		return value, nil
 	}
 
+	// Redacted types, generalized ages, perturbed numbers, categories and shifted dates
+	// are not pseudonymized, so no mapping is needed
+	if d.redactedTypes[dataType] {
+		return d.redactionToken(dataType), nil
+	}
+	if dataType == TypeAge {
+		return d.generalizeAge(value)
+	}
+	if dataType == TypeNumericNoise {
+		return d.perturbNumber(value, d.numericNoisePercent)
+	}
+	if dataType == TypeCategorical {
+		return d.generalizeCategory(value, d.categoricalMode), nil
+	}
+	if dataType == TypeDateTime {
+		return d.shiftDateTime(value)
+	}
+
+	if d.skipAlreadyFake && d.isAlreadyFake(value, dataType) {
+		return value, nil
+	}
+
+	original := value
+	value = d.normalizeValue(value, dataType)
+
+	if d.nonDeterministic {
+		return d.applyOutputCase(original, d.generateOccurrence(value, dataType, columnName), dataType), nil
+	}
+
 	// Check for existing mapping first for deterministic results
 	if mapped := d.getMapping(columnName, value); mapped != "" {
-		return mapped, nil
+		return d.applyOutputCase(original, mapped, dataType), nil
 	}
 
 	var result string
-
-	switch dataType {
-	case TypeName:
-		result = d.generateName(value)
-	case TypeEmail:
-		result = d.generateEmail(value)
-	case TypePhone:
-		result = d.generatePhone(value)
-	case TypeSSN:
-		result = d.generateSSN(value)
-	case TypeCreditCard:
-		result = d.generateCreditCard(value)
-	case TypeAddress:
-		result = d.generateAddress(value)
-	default:
-		result = d.generateGeneric(value)
-	}
+	if d.metrics != nil {
+		start := time.Now()
+		result = d.generateDistinct(value, dataType, columnName)
+		d.metrics(dataType, time.Since(start))
+	} else {
+		result = d.generateDistinct(value, dataType, columnName)
+	}
 
 	// Store mapping for consistency
-	d.setMapping(columnName, value, result)
-	return result, nil
+	d.setMapping(columnName, dataType, value, result)
+	return d.applyOutputCase(original, result, dataType), nil
+}
+
+// detached returns a copy of d sharing its key, configuration, patterns and pools but
+// with empty mappings of its own
+func (d *Deidentifier) detached() *Deidentifier {
+	return &Deidentifier{
+		secretKey:      d.secretKey,
+		mappings:       newMemoryStore(),
+		columnTypes:    newColumnTypeIndex(),
+		customPatterns: d.customPatterns,
+		pools:          d.pools,
+		options:        d.options,
+	}
 }
 
 // deterministicHash creates a consistent hash using HMAC
@@ -334,6 +1083,48 @@ func (d *Deidentifier) deterministicHash(input string) []byte {
 	return h.Sum(nil)
 }
 
+// emailTLD returns the lowercase TLD of an email's domain, keeping multi-label TLDs
+// such as co.uk whole, or "" when the domain has no dot
+func (d *Deidentifier) emailTLD(email string) string {
+	labels := strings.Split(strings.ToLower(email[strings.LastIndex(email, "@")+1:]), ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	if n := len(labels); n >= 3 && slices.Contains(multiLabelTLDOptions, labels[n-2]+"."+labels[n-1]) {
+		return labels[n-2] + "." + labels[n-1]
+	}
+	return labels[len(labels)-1]
+}
+
+// findAddressMatches returns the locations of addressRegexPattern matches in text. It
+// runs the full pattern only on a window of addressWindow bytes around each street type
+// word found by addressCandidates, so long text without street words is never scanned
+// by it. Windows are widened to whitespace so a house number or city is never cut.
+func (d *Deidentifier) findAddressMatches(text string, addrRegex *regexp.Regexp) [][]int {
+	var matches [][]int
+	searched := 0
+	for _, candidate := range d.addressCandidates(text) {
+		for candidate >= searched {
+			start := max(searched, candidate-addressWindow)
+			for start > searched && strings.IndexByte(addressSpaces, text[start-1]) < 0 {
+				start--
+			}
+			end := min(len(text), candidate+addressWindow)
+			for end < len(text) && strings.IndexByte(addressSpaces, text[end]) < 0 {
+				end++
+			}
+
+			loc := addrRegex.FindStringIndex(text[start:end])
+			if loc == nil {
+				break
+			}
+			matches = append(matches, []int{start + loc[0], start + loc[1]})
+			searched = start + loc[1]
+		}
+	}
+	return matches
+}
+
 // findHighestScoringType finds the type with the highest score
 func (d *Deidentifier) findHighestScoringType(typeScores map[DataType]int) (DataType, int) {
 	bestType := TypeGeneric
@@ -348,13 +1139,134 @@ func (d *Deidentifier) findHighestScoringType(typeScores map[DataType]int) (Data
 	return bestType, maxScore
 }
 
+// fittingEntries returns the pool entries that fit the WithReplacementLengthCap of
+// dataType alongside used other characters, or the whole pool when the type is not
+// capped or no entry fits
+func (d *Deidentifier) fittingEntries(pool []string, dataType DataType, used int) []string {
+	limit := d.lengthCaps[dataType]
+	if limit <= 0 {
+		return pool
+	}
+
+	var fitting []string
+	for _, entry := range pool {
+		if utf8.RuneCountInString(entry)+used <= limit {
+			fitting = append(fitting, entry)
+		}
+	}
+	if len(fitting) == 0 {
+		return pool
+	}
+	return fitting
+}
+
+// formatDigitsLike places digits into the layout of original, keeping its separators.
+// If original doesn't contain exactly len(digits) digits, digits is returned unformatted.
+func (d *Deidentifier) formatDigitsLike(original, digits string) string {
+	count := 0
+	for _, r := range original {
+		if r >= '0' && r <= '9' {
+			count++
+		}
+	}
+	if count != len(digits) {
+		return digits
+	}
+
+	var b strings.Builder
+	next := 0
+	for _, r := range original {
+		if r >= '0' && r <= '9' {
+			b.WriteByte(digits[next])
+			next++
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// fillSliceRow deidentifies row into dst, which may be row itself for in-place processing
+func (d *Deidentifier) fillSliceRow(dst, row []string, config *slicesConfig, rowIndex int) error {
+	control, err := d.isControlRow(row, config.columnNames)
+	if err != nil {
+		return fmt.Errorf("error deidentifying row %d: %w", rowIndex, err)
+	}
+	if control {
+		copy(dst, row)
+		return nil
+	}
+
+	for j, value := range row {
+		if value == "" {
+			dst[j] = ""
+			continue
+		}
+
+		deidentifiedValue, err := d.deidentifyDeclared(value, config.columnTypes[j], config.columnNames[j])
+		if err != nil {
+			return fmt.Errorf("error deidentifying row %d, column %d (%s): %w",
+				rowIndex, j, config.columnNames[j], err)
+		}
+
+		dst[j] = deidentifiedValue
+	}
+	return nil
+}
+
+// generalizeAge maps an age onto its bucket label
+func (d *Deidentifier) generalizeAge(value string) (string, error) {
+	age, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || age < 0 {
+		return "", fmt.Errorf("invalid age %q", value)
+	}
+	if age >= ageCap {
+		return fmt.Sprintf("%d+", ageCap), nil
+	}
+
+	low := age - age%ageBucketSize
+	return fmt.Sprintf("%d-%d", low, low+ageBucketSize-1), nil
+}
+
 // generateAddress creates a deterministic fake address
 func (d *Deidentifier) generateAddress(original string) string {
+	if strings.Contains(original, "\n") {
+		return d.generateAddressBlock(original)
+	}
+
 	hash := d.deterministicHash(original)
-	number := 1 + d.hashToIndex(hash[:8], 9999)
-	streetIdx := d.hashToIndex(hash[8:16], len(streetNameOptions))
+	streets := d.fittingEntries(d.pools.streetPool(), TypeAddress, len("1 "))
+	street := streets[d.hashToIndex(hash[8:16], len(streets))]
+	number := 1 + d.hashToIndex(hash[:8], d.streetNumberLimit(street))
+
+	return fmt.Sprintf("%d %s", number, street)
+}
 
-	return fmt.Sprintf("%d %s", number, streetNameOptions[streetIdx])
+// generateAddressBlock creates a deterministic fake multi-line address with the same
+// lines as original: street, optional unit, and a locality line that keeps the state
+func (d *Deidentifier) generateAddressBlock(original string) string {
+	hash := d.deterministicHash(original)
+	lines := strings.Split(original, "\n")
+	localityRegex := regexp.MustCompile(addressLocalityRegexPattern)
+
+	for i, line := range lines {
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		switch {
+		case i == 0:
+			lines[i] = indent + d.generateAddress(strings.TrimSpace(line))
+		case localityRegex.MatchString(line):
+			parts := localityRegex.FindStringSubmatch(line)
+			town := localityOptions[d.hashToIndex(hash[:8], len(localityOptions))]
+			zip := fmt.Sprintf("%05d", d.hashToIndex(hash[8:16], 100000))
+			if parts[3] != "" {
+				zip += fmt.Sprintf("-%04d", d.hashToIndex(hash[16:24], 10000))
+			}
+			lines[i] = fmt.Sprintf("%s%s, %s %s", parts[1], town, parts[2], zip)
+		default:
+			lines[i] = fmt.Sprintf("%sUnit %d", indent, 1+d.hashToIndex(hash[24:32], 999))
+		}
+	}
+	return strings.Join(lines, "\n")
 }
 
 // generateCreditCard creates a deterministic fake credit card with valid Luhn checksum
@@ -373,11 +1285,15 @@ func (d *Deidentifier) generateCreditCard(original string) string {
 	checkDigit := d.calculateLuhnCheckDigit(cardNumber)
 	cardNumber += strconv.Itoa(checkDigit)
 
-	// Format with spaces every 4 digits
+	// Format with spaces every 4 digits, or dots when the original uses them
+	separator := " "
+	if strings.Contains(original, ".") {
+		separator = "."
+	}
 	formatted := ""
 	for i, char := range cardNumber {
 		if i > 0 && i%4 == 0 {
-			formatted += " "
+			formatted += separator
 		}
 		formatted += string(char)
 	}
@@ -385,29 +1301,211 @@ func (d *Deidentifier) generateCreditCard(original string) string {
 	return formatted
 }
 
+// generateDistinct generates a replacement that differs from the original, with the
+// separator, length cap and check digit already applied so they cannot turn it back
+// into the original. When the first candidate happens to equal it, generation is
+// retried with keys derived from the secret key, so the alternate is as deterministic
+// as the first choice. Should every retry still equal the original, as for a formatted
+// ID with nothing to vary such as "--", the generic replacement is used instead. A whole
+// private IP block such as 10.0.0.0/8 is the exception and kept on purpose. Nothing here
+// reads the mapping table, so instances sharing a key agree without sharing mappings.
+func (d *Deidentifier) generateDistinct(value string, dataType DataType, columnName string) string {
+	finish := func(generated string) string {
+		return d.applyChecksum(d.capLength(d.applySeparator(generated, dataType), dataType), dataType, columnName)
+	}
+	result := finish(d.generateValue(value, dataType, columnName))
+	if dataType == TypeEmail && d.isAllowlistedEmail(value) {
+		return result
+	}
+
+	for attempt := 1; result == value && attempt <= maxCollisionRetries; attempt++ {
+		alternate := &Deidentifier{
+			secretKey: d.deterministicHash(fmt.Sprintf("collision-guard:%d", attempt)),
+			pools:     d.pools,
+			options:   d.options,
+		}
+		result = finish(alternate.generateValue(value, dataType, columnName))
+	}
+	if result == value && dataType != TypeIPAddress {
+		return d.generateGeneric(value)
+	}
+	return result
+}
+
 // generateEmail creates a deterministic fake email
 func (d *Deidentifier) generateEmail(original string) string {
+	if d.isAllowlistedEmail(original) {
+		return original
+	}
+
+	tag := ""
+	if d.preservePlusTag {
+		original, tag = d.splitPlusTag(original)
+	}
+
 	hash := d.deterministicHash(original)
 	userIdx := d.hashToIndex(hash[:8], len(emailUsernameOptions))
-	domainIdx := d.hashToIndex(hash[8:16], len(emailDomainOptions))
-	suffix := d.hashToIndex(hash[16:24], 9999)
+	domains := d.pools.domainPool()
+	domainIdx := d.hashToIndex(hash[8:16], len(domains))
+	// A six-digit suffix from 16 hash bytes keeps emails near-injective at 100k+ values
+	suffix := d.hashToIndex(hash[16:32], 1000000)
+
+	domain := domains[domainIdx]
+	if tld := d.emailTLD(original); d.preserveTLD && tld != "" {
+		domain = strings.SplitN(domain, ".", 2)[0] + "." + tld
+	}
+	if tag != "" {
+		tag = "+" + tag
+	}
+	return fmt.Sprintf("%s%06d%s%s@%s", emailUsernameOptions[userIdx], suffix, d.versionTag("."), tag, domain)
+}
 
-	return fmt.Sprintf("%s%d@%s", emailUsernameOptions[userIdx], suffix, emailDomainOptions[domainIdx])
+// generateFormattedID creates a deterministic fake ID with the shape of original: digits
+// become digits and letters letters of the same case, while separators and a leading
+// letter prefix such as "EMP" are kept. The prefix is only kept when digits follow it,
+// so an ID made of letters alone, such as "ABCDEF", is regenerated whole.
+func (d *Deidentifier) generateFormattedID(original string) string {
+	hash := d.deterministicHash(original)
+	out := []byte(original)
+	prefix := strings.ContainsAny(strings.TrimLeft(original, "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"), "0123456789")
+	for i := 0; i < len(out); i++ {
+		if len(hash) <= i {
+			hash = append(hash, d.deterministicHash(original+"\x00"+strconv.Itoa(i))...)
+		}
+		c := out[i]
+		switch {
+		case c >= '0' && c <= '9':
+			out[i] = '0' + hash[i]%10
+		case prefix && (c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'):
+			continue
+		case c >= 'A' && c <= 'Z':
+			out[i] = 'A' + hash[i]%26
+		case c >= 'a' && c <= 'z':
+			out[i] = 'a' + hash[i]%26
+		}
+		prefix = false
+	}
+	return string(out)
 }
 
 // generateGeneric creates a deterministic replacement for generic data
 func (d *Deidentifier) generateGeneric(original string) string {
 	hash := d.deterministicHash(original)
-	return fmt.Sprintf("DATA_%s", hex.EncodeToString(hash[:8]))
+	return fmt.Sprintf("DATA_%s%s", hex.EncodeToString(hash[:8]), d.versionTag("_"))
 }
 
-// generateName creates a deterministic fake name
-func (d *Deidentifier) generateName(original string) string {
+// generateGivenName creates a deterministic fake given name
+func (d *Deidentifier) generateGivenName(original string) string {
+	hash := d.deterministicHash(original)
+	firstNames, _ := d.pools.namePools()
+	return firstNames[d.hashToIndex(hash[:8], len(firstNames))]
+}
+
+// generateLocalPhone creates a deterministic fake 7-digit local number, keeping the
+// separator, and falls back to replacing the digits of other formats
+func (d *Deidentifier) generateLocalPhone(original string) string {
+	localRegex := regexp.MustCompile(localPhoneFormatRegexPattern)
+	matches := localRegex.FindStringSubmatch(original)
+	if matches == nil {
+		return d.generatePhoneDigits(original)
+	}
+
+	hash := d.deterministicHash(original)
+	exchange := 200 + d.hashToIndex(hash[:8], 799)
+	number := 1000 + d.hashToIndex(hash[8:16], 8999)
+	return fmt.Sprintf("%03d%s%04d", exchange, matches[1], number)
+}
+
+// generateNameWords builds a fake name of count words for WithNameWordCount: a single
+// given name for a mononym, or first, middle and last names around the same first and
+// last name a two-word name would get
+func (d *Deidentifier) generateNameWords(base string, count int, first, last string) string {
+	if count <= 1 {
+		return first
+	}
+
+	firstNames, _ := d.pools.namePools()
+	words := []string{first}
+	for i := 1; i < count-1; i++ {
+		hash := d.deterministicHash(fmt.Sprintf("%s\x00%d", base, i))
+		words = append(words, firstNames[d.hashToIndex(hash[:8], len(firstNames))])
+	}
+	return strings.Join(append(words, last), " ")
+}
+
+// generateNINO creates a deterministic fake National Insurance Number, preserving spacing
+func (d *Deidentifier) generateNINO(original string) string {
 	hash := d.deterministicHash(original)
-	firstIdx := d.hashToIndex(hash[:8], len(firstNameOptions))
-	lastIdx := d.hashToIndex(hash[8:16], len(lastNameOptions))
 
-	return fmt.Sprintf("%s %s", firstNameOptions[firstIdx], lastNameOptions[lastIdx])
+	first := ninoFirstLetterOptions[d.hashToIndex(hash[:4], len(ninoFirstLetterOptions))]
+	secondIdx := d.hashToIndex(hash[4:8], len(ninoSecondLetterOptions))
+	prefix := string([]byte{first, ninoSecondLetterOptions[secondIdx]})
+	if invalidNINOPrefixes[prefix] {
+		// Shifting the second letter by one never lands on another unallocated prefix
+		prefix = string([]byte{first, ninoSecondLetterOptions[(secondIdx+1)%len(ninoSecondLetterOptions)]})
+	}
+
+	number := d.hashToIndex(hash[8:16], 1000000)
+	suffix := ninoSuffixOptions[d.hashToIndex(hash[16:24], len(ninoSuffixOptions))]
+
+	if strings.Contains(original, " ") {
+		return fmt.Sprintf("%s %02d %02d %02d %c", prefix, number/10000, number/100%100, number%100, suffix)
+	}
+	return fmt.Sprintf("%s%06d%c", prefix, number, suffix)
+}
+
+// generateName creates a deterministic fake name, keeping any generational suffix.
+// "Last, First" names are hashed as "First Last" so both orderings share one identity.
+// The suffix is hashed too, however it is punctuated, so "John Smith Jr." and "John
+// Smith Sr." stay two different people.
+func (d *Deidentifier) generateName(original string) string {
+	base, suffix := d.splitNameSuffix(original)
+	reversedRegex := regexp.MustCompile(reversedNameRegexPattern)
+	parts := reversedRegex.FindStringSubmatch(base)
+	reversed := parts != nil && parts[0] == base
+	if reversed {
+		base = parts[2] + " " + parts[1]
+	}
+
+	key := base
+	if suffix != "" {
+		key += " " + strings.Trim(suffix, ", .")
+	}
+	hash := d.deterministicHash(key)
+	firstNames, lastNames := d.pools.namePools()
+	firstNames = d.fittingEntries(firstNames, TypeName, len(suffix)+len(", ")+d.shortestEntry(lastNames))
+	first := firstNames[d.hashToIndex(hash[:8], len(firstNames))]
+	lastNames = d.fittingEntries(lastNames, TypeName, len(suffix)+len(first)+len(", "))
+	last := lastNames[d.hashToIndex(hash[8:16], len(lastNames))]
+
+	if reversed {
+		return fmt.Sprintf("%s, %s%s", last, first, suffix)
+	}
+	if words := len(strings.Fields(base)); d.preserveNameWords && words != 2 {
+		return d.generateNameWords(base, words, first, last) + suffix
+	}
+	return fmt.Sprintf("%s %s%s", first, last, suffix)
+}
+
+// generateNameForColumn creates a fake full name, or a single given name for names in
+// the "given_name" namespace, which holds standalone given names found in text
+func (d *Deidentifier) generateNameForColumn(value, columnName string) string {
+	if columnName == "given_name" {
+		return d.generateGivenName(value)
+	}
+	return d.generateName(value)
+}
+
+// generateOccurrence generates the replacement for one occurrence of value under
+// WithNonDeterministicMode, keyed by a per-instance call counter: repeated values get
+// different fakes, while rerunning the same sequence of calls reproduces them
+func (d *Deidentifier) generateOccurrence(value string, dataType DataType, columnName string) string {
+	occurrence := &Deidentifier{
+		secretKey: d.deterministicHash(fmt.Sprintf("occurrence:%d", d.occurrences.Add(1))),
+		pools:     d.pools,
+		options:   d.options,
+	}
+	return occurrence.generateDistinct(value, dataType, columnName)
 }
 
 // generatePhone creates a deterministic fake phone number preserving format
@@ -417,8 +1515,7 @@ func (d *Deidentifier) generatePhone(original string) string {
 	matches := phoneRegex.FindStringSubmatch(original)
 
 	if len(matches) == 0 {
-		// Fallback for non-standard formats
-		return d.generateGeneric(original)
+		return d.generateLocalPhone(original)
 	}
 
 	prefix := matches[1]        // +1 or country code (preserve)
@@ -433,27 +1530,176 @@ func (d *Deidentifier) generatePhone(original string) string {
 	exchange := 200 + d.hashToIndex(hash[:8], 799)   // Valid exchange range
 	number := 1000 + d.hashToIndex(hash[8:16], 8999) // Valid number range
 
+	if d.redistributeAreaCodes {
+		areaCode = nanpAreaCodeOptions[d.hashToIndex(hash[16:24], len(nanpAreaCodeOptions))]
+	}
+
+	if d.phoneLayout != "" {
+		digits := fmt.Sprintf("%s%03d%04d", areaCode, exchange, number)
+		layout := d.phoneLayout
+		for _, digit := range digits {
+			layout = strings.Replace(layout, "X", string(digit), 1)
+		}
+		return layout
+	}
+
 	// Create proper formatting
 	return fmt.Sprintf("%s%s%s%s%03d%s%04d",
 		prefix, openParen, areaCode, afterAreaCode, exchange, separator, number)
 }
 
+// generatePhoneDigits creates a deterministic fake for phone numbers in formats
+// generatePhone does not know, such as international ones, by replacing every digit and
+// keeping everything else. A country code and a leading trunk "0" are kept as well, so
+// "+44 20 7946 0958" stays a UK number. Input without digits gets a generic replacement.
+func (d *Deidentifier) generatePhoneDigits(original string) string {
+	if !strings.ContainsAny(original, "0123456789") {
+		return d.generateGeneric(original)
+	}
+
+	start := 0
+	if loc := regexp.MustCompile(phoneCountryCodeRegexPattern).FindStringIndex(original); loc != nil {
+		start = loc[1]
+	}
+	if first := strings.IndexAny(original[start:], "0123456789"); first >= 0 && original[start+first] == '0' {
+		start += first + 1
+	}
+
+	hash := d.deterministicHash(original)
+	out := []byte(original)
+	leading := true
+	for i := start; i < len(out); i++ {
+		if len(hash) <= i {
+			hash = append(hash, d.deterministicHash(original+"\x00"+strconv.Itoa(i))...)
+		}
+		switch {
+		case out[i] < '0' || out[i] > '9':
+			continue
+		case leading:
+			out[i] = '1' + hash[i]%9 // a new leading 0 would read as a trunk prefix
+		default:
+			out[i] = '0' + hash[i]%10
+		}
+		leading = false
+	}
+	return string(out)
+}
+
+// generateSIN creates a deterministic fake SIN with a valid Luhn checksum, preserving format
+func (d *Deidentifier) generateSIN(original string) string {
+	hash := d.deterministicHash(original)
+
+	// First digit 1-7 matches a regular province-of-registration SIN
+	sin := strconv.Itoa(1 + d.hashToIndex(hash[:2], 7))
+	for i := 1; i < 8; i++ {
+		sin += strconv.Itoa(d.hashToIndex(hash[i*2:i*2+2], 10))
+	}
+	sin += strconv.Itoa(d.calculateLuhnCheckDigit(sin))
+
+	return d.formatDigitsLike(original, sin)
+}
+
 // generateSSN creates a deterministic fake SSN with valid format
 func (d *Deidentifier) generateSSN(original string) string {
 	hash := d.deterministicHash(original)
 
-	// Avoid invalid SSN patterns (666, 900-999 area numbers)
+	// Avoid invalid SSN patterns (666, 900-999 area numbers) unless asked for them
 	area := 100 + d.hashToIndex(hash[:8], 565) // 100-665
 	if area == 666 {
 		area = 667
 	}
+	group := 1 + d.hashToIndex(hash[8:16], 99) // 01-99
+	if d.invalidSSNRange {
+		// 900-999 is never issued as an SSN, and groups below 50 are never ITINs
+		area = 900 + d.hashToIndex(hash[:8], 100)
+		group = 1 + d.hashToIndex(hash[8:16], 49)
+	}
 
-	group := 1 + d.hashToIndex(hash[8:16], 99)     // 01-99
 	serial := 1 + d.hashToIndex(hash[16:24], 9999) // 0001-9999
 
 	return fmt.Sprintf("%03d-%02d-%04d", area, group, serial)
 }
 
+// generateSWIFT creates a deterministic fake SWIFT/BIC code of the same length, keeping
+// the country code for routing analytics and a primary-office "XXX" branch. Values that
+// are not 8 or 11 characters keep their shape as formatted IDs.
+func (d *Deidentifier) generateSWIFT(original string) string {
+	code := strings.ToUpper(original)
+	if !regexp.MustCompile(swiftRegexPattern).MatchString(code) {
+		return d.generateFormattedID(original)
+	}
+
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	const alphanumerics = letters + "0123456789"
+	hash := d.deterministicHash(code)
+	var b strings.Builder
+	for i := 0; i < 4; i++ {
+		b.WriteByte(letters[hash[i]%26])
+	}
+	b.WriteString(code[4:6])
+	b.WriteByte(alphanumerics[hash[4]%36])
+	b.WriteByte((letters + "123456789")[hash[5]%35]) // a "0" here would mark a test BIC
+	if len(code) == 11 {
+		branch := code[8:]
+		if branch != "XXX" {
+			branch = string([]byte{alphanumerics[hash[6]%36], alphanumerics[hash[7]%36], alphanumerics[hash[8]%36]})
+		}
+		b.WriteString(branch)
+	}
+	return b.String()
+}
+
+// generateUsername creates a deterministic fake social handle, keeping a leading @
+func (d *Deidentifier) generateUsername(original string) string {
+	handle := strings.TrimPrefix(original, "@")
+	hash := d.deterministicHash(handle)
+	nameIdx := d.hashToIndex(hash[:8], len(firstNameOptions))
+	suffix := d.hashToIndex(hash[8:16], 9999)
+
+	fake := fmt.Sprintf("%s_%d%s", strings.ToLower(firstNameOptions[nameIdx]), suffix, d.versionTag("_"))
+	if strings.HasPrefix(original, "@") {
+		return "@" + fake
+	}
+	return fake
+}
+
+// generateValue creates the replacement for value according to dataType, using
+// columnName only to tell standalone given names from full names
+func (d *Deidentifier) generateValue(value string, dataType DataType, columnName string) string {
+	switch dataType {
+	case TypeName:
+		return d.generateNameForColumn(value, columnName)
+	case TypeEmail:
+		return d.generateEmail(value)
+	case TypePhone:
+		return d.generatePhone(value)
+	case TypeSSN:
+		return d.generateSSN(value)
+	case TypeCreditCard:
+		return d.generateCreditCard(value)
+	case TypeAddress:
+		return d.generateAddress(value)
+	case TypeSIN:
+		return d.generateSIN(value)
+	case TypeNINO:
+		return d.generateNINO(value)
+	case TypeUsername:
+		return d.generateUsername(value)
+	case TypeCryptoAddress:
+		return d.generateCryptoAddress(value)
+	case TypeFormattedID:
+		return d.generateFormattedID(value)
+	case TypeLatLong:
+		return d.generateLatLong(value)
+	case TypeSWIFT:
+		return d.generateSWIFT(value)
+	case TypeIPAddress:
+		return d.generateIPAddress(value)
+	default:
+		return d.generateGeneric(value)
+	}
+}
+
 // getConfidenceThreshold returns the confidence threshold for a given type
 func (d *Deidentifier) getConfidenceThreshold(dataType DataType, validValues int) int {
 	if dataType == TypeName {
@@ -464,15 +1710,42 @@ func (d *Deidentifier) getConfidenceThreshold(dataType DataType, validValues int
 
 // getMapping retrieves an existing mapping for deterministic results
 func (d *Deidentifier) getMapping(columnName, original string) string {
-	d.mutex.RLock()
-	defer d.mutex.RUnlock()
-
-	if columnMap, exists := d.mappingTables[columnName]; exists {
-		return columnMap[original]
+	if mapped, exists := d.mappings.Get(d.mappingNamespace(columnName), original); exists {
+		return mapped
 	}
 	return ""
 }
 
+// hasContactLine reports whether one of the first signatureContactLines lines, before a
+// blank line, holds contact details: an email, URL, phone-like run of digits or an
+// already replaced value
+func (d *Deidentifier) hasContactLine(lines []string) bool {
+	for i, line := range lines {
+		if i == signatureContactLines || strings.TrimSpace(line) == "" {
+			return false
+		}
+		digits := 0
+		for _, r := range line {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits >= 7 || strings.ContainsAny(line, "@"+string(protectedStart)) || strings.Contains(line, "www.") || strings.Contains(line, "http") {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPaymentContext checks the text preceding a card candidate for payment wording
+func (d *Deidentifier) hasPaymentContext(before string, contextRegex, nonPaymentRegex *regexp.Regexp) bool {
+	const window = 40
+	if len(before) > window {
+		before = before[len(before)-window:]
+	}
+	return contextRegex.MatchString(before) && !nonPaymentRegex.MatchString(before)
+}
+
 // hashToIndex converts hash bytes to an index within range
 func (d *Deidentifier) hashToIndex(hashBytes []byte, max int) int {
 	if len(hashBytes) == 0 || max <= 0 {
@@ -501,14 +1774,30 @@ func (d *Deidentifier) inferColumnTypes(data [][]string) ([]DataType, error) {
 	return columnTypes, nil
 }
 
-// inferOrValidateColumnTypes infers column types if not provided
+// inferOrValidateColumnTypes infers column types if not provided, and those of columns
+// given TypeInfer
 func (d *Deidentifier) inferOrValidateColumnTypes(data [][]string, config *slicesConfig) error {
+	if slices.Contains(config.columnTypes, TypeInfer) {
+		patterns := d.compilePatterns()
+		config.columnTypes = slices.Clone(config.columnTypes)
+		for col, dataType := range config.columnTypes {
+			if dataType == TypeInfer && col < config.numCols {
+				config.columnTypes[col] = d.inferSingleColumnType(data, col, patterns)
+			}
+		}
+	}
 	if len(config.columnTypes) == 0 {
 		var err error
 		config.columnTypes, err = d.inferColumnTypes(data)
 		if err != nil {
 			return fmt.Errorf("failed to infer column types: %w", err)
 		}
+		for col, dataType := range d.columnTypeOverrides {
+			if col < 0 || col >= config.numCols {
+				return fmt.Errorf("column type override for column %d is out of range for %d columns", col, config.numCols)
+			}
+			config.columnTypes[col] = dataType
+		}
 	}
 	return nil
 }
@@ -516,88 +1805,447 @@ func (d *Deidentifier) inferOrValidateColumnTypes(data [][]string, config *slice
 // inferSingleColumnType analyzes a single column to determine its type
 func (d *Deidentifier) inferSingleColumnType(data [][]string, col int, patterns *patternSet) DataType {
 	typeScores := d.initializeTypeScores()
-	validValues := d.scoreColumnValues(data, col, patterns, typeScores)
+	validValues := d.scoreColumnValues(data, d.sampleRows(len(data)), col, patterns, typeScores)
+	return d.selectBestType(typeScores, validValues)
+}
+
+// inferTableColumnType infers the type of a Table column from its sampled non-nil
+// values. Only those are converted, so huge columns are not copied.
+func (d *Deidentifier) inferTableColumnType(col Column) DataType {
+	var data [][]string
+	add := func(value interface{}) {
+		if value != nil {
+			data = append(data, []string{fmt.Sprintf("%v", value)})
+		}
+	}
+	if d.columnSampler == nil {
+		for i := 0; i < len(col.Values) && len(data) < headSampleRows; i++ {
+			add(col.Values[i])
+		}
+	} else {
+		for _, row := range d.sampleRows(len(col.Values)) {
+			if row >= 0 && row < len(col.Values) {
+				add(col.Values[row])
+			}
+		}
+	}
+
+	rows := make([]int, len(data))
+	for i := range rows {
+		rows[i] = i
+	}
+	typeScores := d.initializeTypeScores()
+	validValues := d.scoreColumnValues(data, rows, 0, d.compilePatterns(), typeScores)
 	return d.selectBestType(typeScores, validValues)
 }
 
 // initializeTypeScores creates a map with zero scores for all types
 func (d *Deidentifier) initializeTypeScores() map[DataType]int {
 	return map[DataType]int{
-		TypeEmail:      0,
-		TypePhone:      0,
-		TypeSSN:        0,
-		TypeCreditCard: 0,
-		TypeAddress:    0,
-		TypeName:       0,
-		TypeGeneric:    0,
+		TypeEmail:         0,
+		TypePhone:         0,
+		TypeSSN:           0,
+		TypeCreditCard:    0,
+		TypeAddress:       0,
+		TypeName:          0,
+		TypeGeneric:       0,
+		TypeSIN:           0,
+		TypeNINO:          0,
+		TypeUsername:      0,
+		TypeCryptoAddress: 0,
+		TypeIPAddress:     0,
+		TypeCategorical:   0,
 	}
 }
 
+// hasShortToken reports whether a name candidate has a word shorter than the
+// configured minimum token length
+func (d *Deidentifier) hasShortToken(name string) bool {
+	if d.minTokenLength == 0 {
+		return false
+	}
+	base, _ := d.splitNameSuffix(name)
+	for _, token := range strings.Fields(base) {
+		if len([]rune(token)) < d.minTokenLength {
+			return true
+		}
+	}
+	return false
+}
+
 // isAddressContext checks if a name candidate is actually part of an address
 func (d *Deidentifier) isAddressContext(name string) bool {
 	addressWordRegex := regexp.MustCompile(addressWordRegexPattern)
 	internationalAddressRegex := regexp.MustCompile(internationalAddressRegexPattern)
 	countryRegex := regexp.MustCompile(countryNameRegexPattern)
 	cityRegex := regexp.MustCompile(cityRegexPattern)
+	placePrefixRegex := regexp.MustCompile(placePrefixRegexPattern)
 
 	return addressWordRegex.MatchString(name) ||
 		internationalAddressRegex.MatchString(name) ||
 		countryRegex.MatchString(name) ||
-		cityRegex.MatchString(name)
+		cityRegex.MatchString(name) ||
+		placePrefixRegex.MatchString(name)
 }
 
-// isValidValue checks if a cell contains a valid value for analysis
-func (d *Deidentifier) isValidValue(data [][]string, row, col int) bool {
-	return col < len(data[row]) && data[row][col] != "" && strings.TrimSpace(data[row][col]) != ""
+// isAddressValue reports whether a sampled cell looks like an address, including
+// comma-formatted international addresses that name a country
+func (d *Deidentifier) isAddressValue(value string, patterns *patternSet) bool {
+	if patterns.address.MatchString(value) || patterns.addressWord.MatchString(value) || patterns.addressCountry.MatchString(value) {
+		return true
+	}
+	for _, special := range patterns.specialAddress {
+		if special.MatchString(value) {
+			return true
+		}
+	}
+	return false
 }
 
-// parseOptionalParameters extracts columnTypes and columnNames from optional parameters
-func (d *Deidentifier) parseOptionalParameters(optional []interface{}, config *slicesConfig) error {
-	if len(optional) > 0 {
-		if types, ok := optional[0].([]DataType); ok {
-			config.columnTypes = types
-		} else {
-			return fmt.Errorf("first optional parameter must be []DataType")
+// isAllowlistedEmail reports whether email belongs to an allowlisted domain or one of
+// its subdomains
+func (d *Deidentifier) isAllowlistedEmail(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || len(d.emailDomainAllowlist) == 0 {
+		return false
+	}
+
+	domain := strings.ToLower(email[at+1:])
+	for _, allowed := range d.emailDomainAllowlist {
+		if domain == allowed || strings.HasSuffix(domain, "."+allowed) {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(optional) > 1 {
-		if names, ok := optional[1].([]string); ok {
-			config.columnNames = names
-		} else {
-			return fmt.Errorf("second optional parameter must be []string")
+// isAlreadyFake reports whether value has one of the library's own output formats: an
+// email with a generated username, ignoring any "+tag", on a placeholder domain, or,
+// under WithInvalidSSNRange, an SSN in the 900-999 area whose group is not one the IRS
+// uses for ITINs. Cards are never recognized, since test-range numbers include real ones.
+func (d *Deidentifier) isAlreadyFake(value string, dataType DataType) bool {
+	switch dataType {
+	case TypeEmail:
+		value, _ = d.splitPlusTag(value)
+		at := strings.LastIndex(value, "@")
+		local := regexp.MustCompile(versionTagRegexPattern).ReplaceAllString(value[:max(at, 0)], "")
+		if len(local) < 6 {
+			return false
 		}
+		user, domain := strings.ToLower(local[:len(local)-6]), strings.ToLower(value[at+1:])
+		return regexp.MustCompile(emailNumberRegexPattern).MatchString(local[len(local)-6:]) &&
+			slices.Contains(emailUsernameOptions, user) && d.isGeneratedDomain(domain)
+	case TypeSSN:
+		parts := regexp.MustCompile(ssnPartsRegexPattern).FindStringSubmatch(value)
+		if !d.invalidSSNRange || parts == nil || parts[1][0] != '9' {
+			return false
+		}
+		group, _ := strconv.Atoi(parts[2])
+		itin := group >= 50 && group <= 65 || group >= 70 && group <= 88 || group >= 90 && group <= 92 || group >= 94
+		return !itin
 	}
+	return false
+}
 
-	return nil
+// isDigitAt reports whether s has an ASCII digit at index i
+func (d *Deidentifier) isDigitAt(s string, i int) bool {
+	return i >= 0 && i < len(s) && s[i] >= '0' && s[i] <= '9'
 }
 
-// parseSlicesParameters parses and validates the optional parameters for Slices
-func (d *Deidentifier) parseSlicesParameters(data [][]string, optional ...interface{}) (*slicesConfig, error) {
-	config := &slicesConfig{
-		numCols: len(data[0]),
-	}
+// isGeneratedDomain reports whether domain is one generated emails use: a pool domain
+// or, with WithPreserveTLD, the first label of one followed by any TLD
+func (d *Deidentifier) isGeneratedDomain(domain string) bool {
+	return slices.ContainsFunc(d.pools.domainPool(), func(pooled string) bool {
+		if pooled == domain {
+			return true
+		}
+		label, _, _ := strings.Cut(pooled, ".")
+		return d.preserveTLD && strings.HasPrefix(domain, label+".")
+	})
+}
 
-	if err := d.parseOptionalParameters(optional, config); err != nil {
-		return nil, err
+// isInvisible reports whether r is a zero-width, formatting or control character other
+// than a line break or tab, which stripInvisible removes
+func (d *Deidentifier) isInvisible(r rune) bool {
+	if r == '\n' || r == '\r' || r == '\t' {
+		return false
 	}
+	return unicode.IsControl(r) || unicode.Is(unicode.Cf, r)
+}
 
-	if err := d.setDefaultColumnNames(config); err != nil {
-		return nil, err
-	}
+// isKnownGivenName reports whether name, in any case, is a common given name or one
+// configured with WithGivenNameDictionary
+func (d *Deidentifier) isKnownGivenName(name string) bool {
+	return d.givenNames[strings.ToLower(name)] || slices.ContainsFunc(commonGivenNameOptions, func(given string) bool {
+		return strings.EqualFold(given, name)
+	})
+}
 
-	if err := d.inferOrValidateColumnTypes(data, config); err != nil {
-		return nil, err
+// isParticlePlace reports whether a particle phrase names a place: it starts with a
+// place prefix such as San or Port, or its first word or the capitalized word before it
+// is a place word such as Museo, Puerto or Notre. The word after the particle is never
+// checked, since it is usually a surname, as in Maria da Costa.
+func (d *Deidentifier) isParticlePlace(match, before string) bool {
+	if regexp.MustCompile(placePrefixRegexPattern).MatchString(match) {
+		return true
 	}
-
-	return config, d.validateSlicesConfig(config)
+	first, _, _ := strings.Cut(match, " ")
+	first, _, _ = strings.Cut(first, "\t")
+	words := strings.Split(first, "-")
+	if previous := regexp.MustCompile(nameWordBeforeRegexPattern).FindString(before); previous != "" {
+		words = append(words, strings.TrimSpace(previous))
+	}
+	return slices.ContainsFunc(words, func(word string) bool {
+		return slices.Contains(particlePlaceWordOptions, word)
+	})
 }
 
-// processContextAddresses handles addresses with contextual clues
-func (d *Deidentifier) processContextAddresses(text string) string {
-	contextAddressPattern := regexp.MustCompile(`(?i)(lives at|located at|resides at|found at|situated at|at address|address is|at location|based at) (\d+[^\n\.]*?(Street|St|Avenue|Ave|Road|Rd|Drive|Dr|Lane|Ln|Place|Pl|Boulevard|Blvd|Way)[^\n\.]*)`)
-	return contextAddressPattern.ReplaceAllStringFunc(text, func(match string) string {
-		parts := contextAddressPattern.FindStringSubmatch(match)
+// isStreetTypeAt reports whether a street type word starts at position i of text and
+// is followed by whitespace, a comma or a word boundary
+func (d *Deidentifier) isStreetTypeAt(text string, i int) bool {
+	isWord := func(c byte) bool {
+		return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+	}
+
+	for _, word := range streetTypeWords {
+		end := i + len(word)
+		if end > len(text) || text[i]|0x20 != word[0]|0x20 || !strings.EqualFold(text[i:end], word) {
+			continue
+		}
+		if end == len(text) {
+			if isWord(text[end-1]) {
+				return true
+			}
+		} else if strings.IndexByte(addressSpaces+",", text[end]) >= 0 || isWord(text[end-1]) != isWord(text[end]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isValidSIN checks whether a value holds nine digits with a valid Luhn checksum
+func (d *Deidentifier) isValidSIN(value string) bool {
+	digits := regexp.MustCompile(`[^0-9]`).ReplaceAllString(value, "")
+	if len(digits) != 9 {
+		return false
+	}
+	return d.calculateLuhnCheckDigit(digits[:8]) == int(digits[8]-'0')
+}
+
+// isValidValue checks if a cell contains a valid value for analysis
+func (d *Deidentifier) isValidValue(data [][]string, row, col int) bool {
+	return col < len(data[row]) && data[row][col] != "" && strings.TrimSpace(data[row][col]) != ""
+}
+
+// mappingNamespace returns the mapping namespace for a column, which is shared by all
+// columns of the same group configured with WithColumnGroups
+func (d *Deidentifier) mappingNamespace(columnName string) string {
+	if group, ok := d.columnGroups[columnName]; ok {
+		return group
+	}
+	return columnName
+}
+
+// mappingType returns the type of the values mapped under namespace: the type recorded
+// when d made the mappings, that of a custom pattern or canonical column name of that
+// name, or one inferred from originals
+func (d *Deidentifier) mappingType(namespace string, originals []string) (DataType, error) {
+	if dataType, known := d.columnTypes.get(namespace); known {
+		return dataType, nil
+	}
+	if namespace == "given_name" {
+		return TypeName, nil
+	}
+	d.customPatterns.mutex.RLock()
+	for _, pattern := range d.customPatterns.patterns {
+		if pattern.name == namespace {
+			d.customPatterns.mutex.RUnlock()
+			return pattern.dataType, nil
+		}
+	}
+	d.customPatterns.mutex.RUnlock()
+	for dataType, name := range canonicalColumnNames {
+		if name == namespace {
+			return dataType, nil
+		}
+	}
+	if dataType, _ := d.columnDataType(originals, nil); dataType != TypeGeneric {
+		return dataType, nil
+	}
+	return TypeGeneric, fmt.Errorf("cannot determine the type of column %s", namespace)
+}
+
+// matchesTypeFormat reports whether value has the basic shape of dataType: a name has
+// letters and no digits, an email one @ before a dotted domain, phones, SSNs and cards
+// the right number of digits and no letters, and an address a letter. Other types
+// always match.
+func (d *Deidentifier) matchesTypeFormat(value string, dataType DataType) bool {
+	digits := len(regexp.MustCompile(`[^0-9]`).ReplaceAllString(value, ""))
+	hasLetter := strings.IndexFunc(value, unicode.IsLetter) >= 0
+	switch dataType {
+	case TypeName:
+		return hasLetter && digits == 0
+	case TypeEmail:
+		return regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`).MatchString(value)
+	case TypePhone:
+		return !hasLetter && digits >= 7 && digits <= 15
+	case TypeSSN:
+		return !hasLetter && digits == 9
+	case TypeCreditCard:
+		return !hasLetter && digits >= 13 && digits <= 19
+	case TypeAddress:
+		return hasLetter
+	}
+	return true
+}
+
+// mentionedBefore reports whether replacement was already written for dataType in this
+// run, and remembers it for later mentions
+func (d *Deidentifier) mentionedBefore(run *textRun, dataType DataType, replacement string) bool {
+	if run.mentioned == nil {
+		run.mentioned = make(map[DataType]map[string]bool)
+	}
+	if run.mentioned[dataType] == nil {
+		run.mentioned[dataType] = make(map[string]bool)
+	}
+	if run.mentioned[dataType][replacement] {
+		return true
+	}
+	run.mentioned[dataType][replacement] = true
+	return false
+}
+
+// normalizeValue brings equivalent spellings of a value to one mapping key: values of
+// case-insensitive types are lowercased, and SSNs written with any mix of hyphens,
+// spaces or no separators become XXX-XX-XXXX
+func (d *Deidentifier) normalizeValue(value string, dataType DataType) string {
+	if d.caseInsensitiveTypes[dataType] {
+		value = strings.ToLower(value)
+	}
+	if dataType == TypeSSN {
+		if parts := regexp.MustCompile(ssnPartsRegexPattern).FindStringSubmatch(value); parts != nil {
+			value = parts[1] + "-" + parts[2] + "-" + parts[3]
+		}
+	}
+	return value
+}
+
+// opensSignature reports whether line i of lines can start a signature block: it follows
+// a "--" delimiter or a sign-off such as "Regards,". A heading at the top of the text or
+// after a blank line is not enough, since any short capitalized line could be one.
+func (d *Deidentifier) opensSignature(lines []string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	previous := strings.TrimSpace(lines[i-1])
+	return previous == "--" || regexp.MustCompile(signOffRegexPattern).MatchString(previous)
+}
+
+// parseOptionalParameters extracts columnTypes and columnNames from optional parameters
+func (d *Deidentifier) parseOptionalParameters(optional []interface{}, config *slicesConfig) error {
+	if len(optional) > 0 {
+		if types, ok := optional[0].([]DataType); ok {
+			config.columnTypes = types
+		} else {
+			return fmt.Errorf("first optional parameter must be []DataType")
+		}
+	}
+
+	if len(optional) > 1 {
+		if names, ok := optional[1].([]string); ok {
+			config.columnNames = names
+		} else {
+			return fmt.Errorf("second optional parameter must be []string")
+		}
+	}
+
+	return nil
+}
+
+// parseSlicesParameters parses and validates the optional parameters for Slices
+func (d *Deidentifier) parseSlicesParameters(data [][]string, optional ...interface{}) (*slicesConfig, error) {
+	config := &slicesConfig{
+		numCols: len(data[0]),
+	}
+
+	if err := d.parseOptionalParameters(optional, config); err != nil {
+		return nil, err
+	}
+
+	if err := d.setDefaultColumnNames(config); err != nil {
+		return nil, err
+	}
+
+	if err := d.inferOrValidateColumnTypes(data, config); err != nil {
+		return nil, err
+	}
+
+	return config, d.validateSlicesConfig(config)
+}
+
+// processAddressBlocks handles mailing addresses spread over several lines. It runs
+// before the digit-based steps so ZIP codes are not mistaken for SSNs or phones.
+func (d *Deidentifier) processAddressBlocks(run *textRun, text string) string {
+	blockRegex := regexp.MustCompile(addressBlockRegexPattern)
+	return d.replaceMatches(run, text, blockRegex, func(block, _ string) string {
+		deidentified, err := d.deidentifyTextValue(run, block, TypeAddress, "address")
+		if err != nil {
+			return d.redactionError(run, block, "[ADDRESS REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processAges generalizes ages written in prose when WithAgeGeneralization is set,
+// keeping the surrounding words
+func (d *Deidentifier) processAges(run *textRun, text string) string {
+	if !d.generalizeTextAges {
+		return text
+	}
+
+	ageRegex := regexp.MustCompile(ageExpressionRegexPattern)
+	return d.replaceMatches(run, text, ageRegex, func(match, _ string) string {
+		parts := ageRegex.FindStringSubmatch(match)
+		prefix, age, suffix := parts[1], parts[2], ""
+		if age == "" {
+			age, suffix = parts[3], parts[4]
+		}
+		generalized, err := d.deidentifyValue(age, TypeAge, "age")
+		if err != nil {
+			return d.redactionError(run, match, "[AGE REDACTION ERROR]", err)
+		}
+		d.recordFinding(run, TypeAge, age, generalized)
+		return prefix + d.protect(run, generalized) + suffix
+	})
+}
+
+// processBarePhones handles phone numbers stored as bare digits: ten, or eleven with a
+// leading 1, such as "5551234567". Runs attached to more digits are kept. Under
+// WithBarePhoneContext, which spares order numbers and IDs of the same length, runs are
+// only replaced when written with a leading "+" or after a cue such as "phone", "call"
+// or "mobile" in the preceding phoneContextWindow characters.
+func (d *Deidentifier) processBarePhones(run *textRun, text string) string {
+	bareRegex := regexp.MustCompile(barePhoneRegexPattern)
+	contextRegex := regexp.MustCompile(phoneContextRegexPattern)
+	return d.replaceMatches(run, text, bareRegex, func(phone, before string) string {
+		window := before[max(0, len(before)-phoneContextWindow):]
+		cued := strings.HasPrefix(phone, "+") || contextRegex.MatchString(window)
+		if d.isDigitAt(before, len(before)-1) || d.requirePhoneContext && !cued {
+			return phone
+		}
+		deidentified, err := d.deidentifyTextValue(run, phone, TypePhone, "phone")
+		if err != nil {
+			return d.redactionError(run, phone, "[PHONE REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processContextAddresses handles addresses with contextual clues
+func (d *Deidentifier) processContextAddresses(run *textRun, text string) string {
+	contextAddressPattern := regexp.MustCompile(`(?i)(lives at|located at|resides at|found at|situated at|at address|address is|at location|based at) (\d+[^\n\.]*?(Street|St|Avenue|Ave|Road|Rd|Drive|Dr|Lane|Ln|Place|Pl|Boulevard|Blvd|Way)\b` + addressTailRegexPattern + `)`)
+	return d.replaceMatches(run, text, contextAddressPattern, func(match, _ string) string {
+		parts := contextAddressPattern.FindStringSubmatch(match)
 		if len(parts) < 3 {
 			return match
 		}
@@ -605,72 +2253,447 @@ func (d *Deidentifier) processContextAddresses(text string) string {
 		prefix := parts[1]
 		address := strings.TrimSpace(parts[2])
 
-		deidentified, err := d.deidentifyValue(address, TypeAddress, "address")
+		deidentified, err := d.deidentifyTextValue(run, address, TypeAddress, "address")
 		if err != nil {
-			return match
+			return d.redactionError(run, match, match, err)
 		}
 
-		return prefix + " " + deidentified
+		return prefix + " " + d.protect(run, deidentified)
 	})
 }
 
 // processCreditCards handles credit card deidentification
-func (d *Deidentifier) processCreditCards(text string) string {
+func (d *Deidentifier) processCreditCards(run *textRun, text string) string {
 	ccRegex := regexp.MustCompile(creditCardRegexPattern)
-	return ccRegex.ReplaceAllStringFunc(text, func(cc string) string {
-		deidentified, err := d.deidentifyValue(cc, TypeCreditCard, "credit_card")
+	contextRegex := regexp.MustCompile(creditCardContextRegexPattern)
+	nonPaymentRegex := regexp.MustCompile(creditCardNonPaymentRegexPattern)
+
+	return d.replaceMatches(run, text, ccRegex, func(cc, before string) string {
+		if d.requireCardContext && !d.hasPaymentContext(before, contextRegex, nonPaymentRegex) {
+			return cc
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, cc, TypeCreditCard, "credit_card")
 		if err != nil {
-			return "[CC REDACTION ERROR]"
+			return d.redactionError(run, cc, "[CC REDACTION ERROR]", err)
 		}
 		return deidentified
 	})
 }
 
+// processCryptoAddresses handles wallet address deidentification. It runs first and
+// protects its output, since the digit runs inside addresses would otherwise be picked
+// up by the phone and card steps.
+func (d *Deidentifier) processCryptoAddresses(run *textRun, text string) string {
+	cryptoRegex := regexp.MustCompile(cryptoAddressRegexPattern)
+	return d.replaceMatches(run, text, cryptoRegex, func(address, _ string) string {
+		if !d.isCryptoAddress(address) {
+			return address
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, address, TypeCryptoAddress, "crypto_address")
+		if err != nil {
+			return d.redactionError(run, address, "[CRYPTO REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
 // processEmails handles email deidentification
-func (d *Deidentifier) processEmails(text string) string {
+func (d *Deidentifier) processEmails(run *textRun, text string) string {
 	emailRegex := regexp.MustCompile(emailRegexPattern)
-	return emailRegex.ReplaceAllStringFunc(text, func(email string) string {
-		deidentified, err := d.deidentifyValue(email, TypeEmail, "email")
+	return d.replaceMatches(run, text, emailRegex, func(email, _ string) string {
+		deidentified, err := d.deidentifyTextValue(run, email, TypeEmail, "email")
 		if err != nil {
-			return "[EMAIL REDACTION ERROR]"
+			return d.redactionError(run, email, "[EMAIL REDACTION ERROR]", err)
 		}
 		return deidentified
 	})
 }
 
+// processGivenNames handles standalone dictionary given names in greeting or comma context
+func (d *Deidentifier) processGivenNames(run *textRun, text string) string {
+	if len(d.givenNames) == 0 {
+		return text
+	}
+
+	givenNameRegex := regexp.MustCompile(givenNameRegexPattern)
+	greetingRegex := regexp.MustCompile(greetingRegexPattern)
+	nameWordRegex := regexp.MustCompile(nameWordBeforeRegexPattern)
+
+	return d.replaceSpans(run, text, givenNameRegex.FindAllStringIndex(text, -1), func(loc []int) string {
+		name := text[loc[0]:loc[1]]
+		before, after := text[:loc[0]], text[loc[1]:]
+		if !d.givenNames[strings.ToLower(name)] || d.hasShortToken(name) || (strings.HasPrefix(after, " ") && d.startsCapitalized(after[1:])) {
+			return name // unknown, or the first part of a longer name processNames already handled
+		}
+		greeted := greetingRegex.MatchString(before)
+		if !greeted && (!strings.HasPrefix(after, ",") || nameWordRegex.MatchString(before)) {
+			return name
+		}
+		return d.deidentifyGivenName(run, name)
+	})
+}
+
+// processHeaderNames handles the display names in From, To, Cc, Bcc, Reply-To and
+// Sender headers, as in "From: Legolas Greenleaf <legolas@mirkwood.elf>", which are
+// names whatever their case or word count
+func (d *Deidentifier) processHeaderNames(run *textRun, text string) string {
+	headerRegex := regexp.MustCompile(emailHeaderRegexPattern)
+	mailboxRegex := regexp.MustCompile(headerMailboxRegexPattern)
+	return d.replaceMatches(run, text, headerRegex, func(line, _ string) string {
+		parts := headerRegex.FindStringSubmatch(line)
+		mailboxes := mailboxRegex.ReplaceAllStringFunc(parts[2], func(mailbox string) string {
+			m := mailboxRegex.FindStringSubmatch(mailbox)
+			if m[3] != "" {
+				return m[1] + m[2] + `"` + d.replaceDisplayName(run, m[3]) + `"` + m[5]
+			}
+			return m[1] + m[2] + d.replaceDisplayName(run, m[4]) + m[5]
+		})
+		return parts[1] + mailboxes
+	})
+}
+
+// processIPAddresses handles IPv4 addresses, with an optional ":port" or "/mask" suffix
+// that is kept. It skips parts of longer dotted numbers such as version strings, and
+// loopback, unspecified, multicast and netmask-like addresses, which identify no one.
+func (d *Deidentifier) processIPAddresses(run *textRun, text string) string {
+	ipRegex := regexp.MustCompile(ipAddressRegexPattern)
+	return d.replaceMatches(run, text, ipRegex, func(match, before string) string {
+		addr, _, _, ok := d.parseIPAddress(match)
+		if !ok || strings.HasSuffix(before, ".") || addr.IsLoopback() || addr.IsUnspecified() || addr.As4()[0] >= 224 {
+			return match
+		}
+		deidentified, err := d.deidentifyTextValue(run, match, TypeIPAddress, "ip_address")
+		if err != nil {
+			return d.redactionError(run, match, "[IP REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processLabeledSSNs handles SSNs directly after an SSN label, whatever adornment such
+// as "#", "no." or ":" sits between them, keeping the label and adornment. Like other
+// SSNs, the replacement uses the canonical XXX-XX-XXXX layout.
+func (d *Deidentifier) processLabeledSSNs(run *textRun, text string) string {
+	labeledRegex := regexp.MustCompile(ssnLabeledRegexPattern)
+	return d.replaceMatches(run, text, labeledRegex, func(match, _ string) string {
+		parts := labeledRegex.FindStringSubmatch(match)
+		label, ssn := parts[1]+parts[2], parts[3]
+		deidentified, err := d.deidentifyTextValue(run, ssn, TypeSSN, "ssn")
+		if err != nil {
+			return label + d.redactionError(run, ssn, "[SSN REDACTION ERROR]", err)
+		}
+		return d.protect(run, label+deidentified) // keeps "Social Security" from processNames
+	})
+}
+
+// processLatLongs handles labeled coordinate pairs such as "lat: 37.77, lng: -122.41",
+// keeping the labels and shifting both numbers as one location
+func (d *Deidentifier) processLatLongs(run *textRun, text string) string {
+	latLongRegex := regexp.MustCompile(latLongLabeledRegexPattern)
+	return d.replaceMatches(run, text, latLongRegex, func(match, _ string) string {
+		parts := latLongRegex.FindStringSubmatch(match)
+		coordinates := parts[2] + ", " + parts[4]
+		deidentified, err := d.deidentifyValue(coordinates, TypeLatLong, "lat_long")
+		if err != nil {
+			return d.redactionError(run, match, "[LATLONG REDACTION ERROR]", err)
+		}
+		d.recordFinding(run, TypeLatLong, coordinates, deidentified)
+		lat, long, _ := strings.Cut(deidentified, ", ")
+		return parts[1] + d.protect(run, lat) + parts[3] + d.protect(run, long)
+	})
+}
+
+// processLocalPhones handles 7-digit local numbers written as ddd-dddd. It runs before
+// processPhones and skips candidates attached to more digits, such as the tail of
+// "(555) 123-4567" or part of a longer hyphenated number.
+func (d *Deidentifier) processLocalPhones(run *textRun, text string) string {
+	localRegex := regexp.MustCompile(localPhoneRegexPattern)
+	beforeRegex := regexp.MustCompile(localPhoneBeforeRegexPattern)
+	afterRegex := regexp.MustCompile(localPhoneAfterRegexPattern)
+
+	return d.replaceSpans(run, text, localRegex.FindAllStringIndex(text, -1), func(loc []int) string {
+		phone := text[loc[0]:loc[1]]
+		before := text[max(0, loc[0]-2):loc[0]]
+		after := text[loc[1]:min(len(text), loc[1]+2)]
+		if beforeRegex.MatchString(before) || afterRegex.MatchString(after) {
+			return phone
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, phone, TypePhone, "phone")
+		if err != nil {
+			return d.redactionError(run, phone, "[PHONE REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processMailboxNames handles display names before an email address in angle brackets
+// outside headers, as in "write to Jane Doe <jane@x.com>". A quoted name is taken
+// whole; otherwise the capitalized words before the bracket are, less leading cue
+// words such as "Contact". Names with a role word, as in "Acme Support", are kept.
+func (d *Deidentifier) processMailboxNames(run *textRun, text string) string {
+	mailboxRegex := regexp.MustCompile(inlineMailboxRegexPattern)
+	wordRegex := regexp.MustCompile(`\S+`)
+	return d.replaceMatches(run, text, mailboxRegex, func(match, _ string) string {
+		parts := mailboxRegex.FindStringSubmatch(match)
+		if parts[1] != "" {
+			return `"` + d.replaceDisplayName(run, parts[1]) + `"` + parts[3]
+		}
+
+		words := wordRegex.FindAllStringIndex(parts[2], -1)
+		word := func(i int) string { return strings.TrimRight(parts[2][words[i][0]:words[i][1]], ".") }
+		cues := 0
+		for cues < len(words) && slices.Contains(mailboxCueWordOptions, word(cues)) {
+			cues++
+		}
+		for i := cues; i < len(words); i++ {
+			if slices.Contains(mailboxRoleWordOptions, word(i)) {
+				return match
+			}
+		}
+		if cues == len(words) {
+			return match
+		}
+		prefix := parts[2][:words[cues][0]]
+		return prefix + d.replaceDisplayName(run, parts[2][len(prefix):]) + parts[3]
+	})
+}
+
 // processNames handles name deidentification with address context checking
-func (d *Deidentifier) processNames(text string) string {
+func (d *Deidentifier) processNames(run *textRun, text string) string {
 	nameRegex := regexp.MustCompile(nameRegexPattern)
-	return nameRegex.ReplaceAllStringFunc(text, func(name string) string {
-		if d.isAddressContext(name) {
+	replaceName := func(name string) string {
+		if d.isAddressContext(name) || d.hasShortToken(name) {
 			return name
 		}
 
-		deidentified, err := d.deidentifyValue(name, TypeName, "name")
+		deidentified, err := d.deidentifyTextValue(run, name, TypeName, "name")
+		if err != nil {
+			return d.redactionError(run, name, "[NAME REDACTION ERROR]", err)
+		}
+		return deidentified
+	}
+	if len(d.givenNames) == 0 {
+		return d.replaceMatches(run, text, nameRegex, func(name, _ string) string { return replaceName(name) })
+	}
+
+	// With a given-name dictionary, a greeting is not the first half of a name:
+	// rescan from the word after it and leave standalone names to processGivenNames
+	greetingWordRegex := regexp.MustCompile(greetingWordRegexPattern)
+	var locs [][]int
+	pos := 0
+	for {
+		loc := nameRegex.FindStringIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		start, end := pos+loc[0], pos+loc[1]
+		if greeting := greetingWordRegex.FindString(text[start:end]); greeting != "" {
+			pos = start + len(greeting)
+			continue
+		}
+		locs = append(locs, []int{start, end})
+		pos = end
+	}
+	return d.replaceSpans(run, text, locs, func(loc []int) string { return replaceName(text[loc[0]:loc[1]]) })
+}
+
+// processNicknameNames handles names with a nickname in quotes or parentheses, as in
+// Robert "Bob" Smith, when the first word is a known given name so that phrases like
+// The Project (Alpha) Team are kept. The whole construct becomes the fake of the name
+// without the nickname, so it matches other mentions of Robert Smith and the nickname
+// cannot leak.
+func (d *Deidentifier) processNicknameNames(run *textRun, text string) string {
+	nicknameRegex := regexp.MustCompile(nicknameNameRegexPattern)
+	return d.replaceMatches(run, text, nicknameRegex, func(match, _ string) string {
+		parts := nicknameRegex.FindStringSubmatch(match)
+		if !d.isKnownGivenName(parts[1]) {
+			return match
+		}
+		name := parts[1] + " " + parts[2]
+		deidentified, err := d.deidentifyTextValue(run, name, TypeName, "name")
+		if err != nil {
+			return d.redactionError(run, match, "[NAME REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processNINOs handles UK National Insurance Number deidentification
+func (d *Deidentifier) processNINOs(run *textRun, text string) string {
+	ninoRegex := regexp.MustCompile(ninoRegexPattern)
+	return d.replaceMatches(run, text, ninoRegex, func(nino, _ string) string {
+		if invalidNINOPrefixes[strings.ToUpper(nino[:2])] {
+			return nino
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, nino, TypeNINO, "nino")
 		if err != nil {
-			return "[NAME REDACTION ERROR]"
+			return d.redactionError(run, nino, "[NINO REDACTION ERROR]", err)
 		}
 		return deidentified
 	})
 }
 
-// processPhones handles phone number deidentification
-func (d *Deidentifier) processPhones(text string) string {
+// processParticleNames handles names whose surname has a lowercase particle, as in
+// Ludwig van Beethoven or Omar al-Farsi, which the capitalized pair of processNames would
+// cut short. Street names such as Rue de la Paix or 12 Jan van Galen Street are kept for
+// the address steps. Places such as Museo del Prado (see isParticlePlace) are kept and,
+// like names, protected so later steps cannot read van or de la as a street type or
+// take a place word for a first name.
+func (d *Deidentifier) processParticleNames(run *textRun, text string) string {
+	particleRegex := regexp.MustCompile(particleNameRegexPattern)
+	houseNumberRegex := regexp.MustCompile(`\d[\w-]*[\s,]+$`)
+	addressWordRegex := regexp.MustCompile(addressWordRegexPattern)
+	return d.replaceMatches(run, text, particleRegex, func(match, before string) string {
+		if particleRegex.FindStringSubmatch(match)[1] != "" || houseNumberRegex.MatchString(before) || addressWordRegex.MatchString(match) {
+			return match
+		}
+		if d.isParticlePlace(match, before) {
+			return d.protect(run, match)
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, match, TypeName, "name")
+		if err != nil {
+			return d.redactionError(run, match, "[NAME REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processPhones handles phone number deidentification. Digit runs without separators
+// are left to processBarePhones, and candidates attached to more digits, such as part
+// of a longer account number, are skipped.
+func (d *Deidentifier) processPhones(run *textRun, text string) string {
 	phoneRegex := regexp.MustCompile(phoneRegexPattern)
-	return phoneRegex.ReplaceAllStringFunc(text, func(phone string) string {
-		deidentified, err := d.deidentifyValue(phone, TypePhone, "phone")
+	return d.replaceMatches(run, text, phoneRegex, func(phone, before string) string {
+		after := text[len(before)+len(phone):]
+		bare := strings.Trim(phone, "0123456789") == ""
+		if bare || strings.HasSuffix(before, "+") || d.isDigitAt(before, len(before)-1) || d.isDigitAt(after, 0) {
+			return phone
+		}
+		deidentified, err := d.deidentifyTextValue(run, phone, TypePhone, "phone")
 		if err != nil {
-			return "[PHONE REDACTION ERROR]"
+			return d.redactionError(run, phone, "[PHONE REDACTION ERROR]", err)
 		}
 		return deidentified
 	})
 }
 
+// processPhoneURIs handles the numbers of tel:, sms: and callto: links, keeping the
+// scheme and any query. North American numbers are replaced as phones; other numbers,
+// such as international ones, keep their shape with fresh digits.
+func (d *Deidentifier) processPhoneURIs(run *textRun, text string) string {
+	uriRegex := regexp.MustCompile(phoneURIRegexPattern)
+	numberRegex := regexp.MustCompile(phoneURINumberRegexPattern)
+	return d.replaceMatches(run, text, uriRegex, func(uri, _ string) string {
+		parts := uriRegex.FindStringSubmatch(uri)
+		numbers := numberRegex.ReplaceAllStringFunc(parts[2], func(number string) string {
+			digits := regexp.MustCompile(`\D`).ReplaceAllString(number, "")
+			dataType := TypePhone
+			if len(digits) != 10 && (len(digits) != 11 || digits[0] != '1') {
+				dataType = TypeFormattedID
+			}
+
+			deidentified, err := d.deidentifyTextValue(run, number, dataType, "phone")
+			if err != nil {
+				return d.redactionError(run, number, "[PHONE REDACTION ERROR]", err)
+			}
+			return d.protect(run, deidentified)
+		})
+		return parts[1] + ":" + numbers
+	})
+}
+
+// processReversedNames handles "Last, First" names. Such a pair is only treated as a
+// name when the text also contains it as "First Last", which keeps phrases like
+// "Paris, France" intact; both orderings then receive the same fake identity.
+func (d *Deidentifier) processReversedNames(run *textRun, text, originalText string) string {
+	reversedRegex := regexp.MustCompile(reversedNameRegexPattern)
+	return d.replaceMatches(run, text, reversedRegex, func(match, _ string) string {
+		parts := reversedRegex.FindStringSubmatch(match)
+		forwardRegex := regexp.MustCompile(`\b` + parts[2] + ` ` + parts[1] + `\b`)
+		if !forwardRegex.MatchString(originalText) {
+			return match
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, match, TypeName, "name")
+		if err != nil {
+			return d.redactionError(run, match, "[NAME REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processSalutationNames handles names after "Dear", "Attn:" and "Attention:" cues,
+// which are names even when single or titled, as in "Dear Maria," or "Dear Ms. Patricia
+// Martinez,". A single untitled word is a given name when it is a known one; other
+// single words, as in "ATTN: Watson" or "Dear Dr. Okafor", are surnames and replaced as
+// names. Roles such as "Dear Sir" or "Dear Customer" are kept, and protected so
+// processNames does not take the cue for a first name.
+func (d *Deidentifier) processSalutationNames(run *textRun, text string) string {
+	salutationRegex := regexp.MustCompile(salutationNameRegexPattern)
+	return d.replaceMatches(run, text, salutationRegex, func(match, _ string) string {
+		parts := salutationRegex.FindStringSubmatch(match)
+		cue, name := parts[1], parts[2]
+		first := strings.FieldsFunc(name, func(r rune) bool { return r == ' ' || r == '\'' || r == '-' })[0]
+		if slices.Contains(salutationStopWordOptions, first) {
+			return d.protect(run, match)
+		}
+		titled := len(strings.Fields(cue)) > 1
+		if !strings.Contains(name, " ") && !titled && d.isKnownGivenName(name) {
+			return d.protect(run, cue+d.deidentifyGivenName(run, name))
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, name, TypeName, "name")
+		if err != nil {
+			return cue + d.redactionError(run, name, "[NAME REDACTION ERROR]", err)
+		}
+		return d.protect(run, cue+deidentified)
+	})
+}
+
+// processSignatureNames handles the name line of an email signature, which is a name
+// even as a single word: a short capitalized line opening a signature block (see
+// opensSignature) and followed within a few lines by contact details, with a title line
+// allowed between, as in "Best,\nPriya\nAccount Manager\n+1 555 123 4567". Only the
+// last signatureTailLines lines are searched, since signatures end a message, and lines
+// with sign-off, role or company words are kept.
+func (d *Deidentifier) processSignatureNames(run *textRun, text string) string {
+	nameRegex := regexp.MustCompile(signatureNameRegexPattern)
+	lines := strings.Split(text, "\n")
+	var locs [][]int
+	lineStart := 0
+	for i, line := range lines {
+		if i >= len(lines)-signatureTailLines && i < len(lines)-1 {
+			m := nameRegex.FindStringSubmatchIndex(line)
+			if m != nil && d.opensSignature(lines, i) && d.hasContactLine(lines[i+1:]) {
+				name := line[m[2]:m[3]]
+				if !slices.ContainsFunc(strings.FieldsFunc(name, func(r rune) bool { return r == ' ' || r == '\'' || r == '-' }), func(word string) bool {
+					return slices.Contains(signatureStopWordOptions, word)
+				}) {
+					locs = append(locs, []int{lineStart + m[2], lineStart + m[3]})
+				}
+			}
+		}
+		lineStart += len(line) + 1
+	}
+	return d.replaceSpans(run, text, locs, func(loc []int) string {
+		return d.replaceSignatureName(run, text[loc[0]:loc[1]])
+	})
+}
+
 // processSliceData processes the slice data using the provided configuration
-func (d *Deidentifier) processSliceData(data [][]string, config *slicesConfig) ([][]string, error) {
+func (d *Deidentifier) processSliceData(ctx context.Context, data [][]string, config *slicesConfig) ([][]string, error) {
 	result := make([][]string, len(data))
 
 	for i, row := range data {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
 		processedRow, err := d.processSliceRow(row, config, i)
 		if err != nil {
 			return nil, err
@@ -684,41 +2707,64 @@ func (d *Deidentifier) processSliceData(data [][]string, config *slicesConfig) (
 // processSliceRow processes a single row of slice data
 func (d *Deidentifier) processSliceRow(row []string, config *slicesConfig, rowIndex int) ([]string, error) {
 	resultRow := make([]string, len(row))
+	if err := d.fillSliceRow(resultRow, row, config, rowIndex); err != nil {
+		return nil, err
+	}
+	return resultRow, nil
+}
 
-	for j, value := range row {
-		if value == "" {
-			resultRow[j] = ""
+// processTableColumn deidentifies the values of a single table column, copying those
+// in control rows unchanged
+func (d *Deidentifier) processTableColumn(ctx context.Context, col Column, control []bool) ([]interface{}, error) {
+	deidentifiedValues := make([]interface{}, len(col.Values))
+
+	for j, value := range col.Values {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if j < len(control) && control[j] {
+			deidentifiedValues[j] = value
 			continue
 		}
 
-		deidentifiedValue, err := d.deidentifyValue(value, config.columnTypes[j], config.columnNames[j])
-		if err != nil {
-			return nil, fmt.Errorf("error deidentifying row %d, column %d (%s): %w",
-				rowIndex, j, config.columnNames[j], err)
+		if value == nil {
+			if d.nilAsEmpty {
+				deidentifiedValues[j] = ""
+			}
+			continue
 		}
 
-		resultRow[j] = deidentifiedValue
+		strValue := fmt.Sprintf("%v", value)
+		if strValue == "" && d.emptyAsNil {
+			continue
+		}
+		deidentifiedValue, err := d.deidentifyDeclared(strValue, col.DataType, col.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error deidentifying column %s, row %d: %w", col.Name, j, err)
+		}
+		deidentifiedValues[j] = deidentifiedValue
 	}
 
-	return resultRow, nil
+	return deidentifiedValues, nil
 }
 
 // processSpecialAddressPattern handles a single special address pattern
-func (d *Deidentifier) processSpecialAddressPattern(text, pattern string) string {
+func (d *Deidentifier) processSpecialAddressPattern(run *textRun, text, pattern string) string {
 	regex := regexp.MustCompile(pattern)
-	return regex.ReplaceAllStringFunc(text, func(addr string) string {
-		deidentified, err := d.deidentifyValue(addr, TypeAddress, "address")
+	return d.replaceMatches(run, text, regex, func(addr, _ string) string {
+		deidentified, err := d.deidentifyTextValue(run, addr, TypeAddress, "address")
 		if err != nil {
-			return "[ADDRESS REDACTION ERROR]"
+			return d.redactionError(run, addr, "[ADDRESS REDACTION ERROR]", err)
 		}
-		return deidentified
+		return d.protect(run, deidentified)
 	})
 }
 
 // processSpecialAddressPattern3 handles special address pattern 3 with prefix handling
-func (d *Deidentifier) processSpecialAddressPattern3(text string) string {
+func (d *Deidentifier) processSpecialAddressPattern3(run *textRun, text string) string {
 	specialAddr3Regex := regexp.MustCompile(specialAddressPattern3)
-	return specialAddr3Regex.ReplaceAllStringFunc(text, func(addr string) string {
+	return d.replaceMatches(run, text, specialAddr3Regex, func(addr, _ string) string {
 		parts := strings.SplitN(addr, " ", 2)
 		if len(parts) < 2 {
 			return addr
@@ -727,74 +2773,499 @@ func (d *Deidentifier) processSpecialAddressPattern3(text string) string {
 		prefix := parts[0]
 		address := strings.TrimSpace(parts[1])
 
-		deidentified, err := d.deidentifyValue(address, TypeAddress, "address")
+		deidentified, err := d.deidentifyTextValue(run, address, TypeAddress, "address")
 		if err != nil {
-			return addr
+			return d.redactionError(run, addr, addr, err)
 		}
 
-		return prefix + " " + deidentified
+		return prefix + " " + d.protect(run, deidentified)
 	})
 }
 
 // processSpecialAddresses handles special address patterns
-func (d *Deidentifier) processSpecialAddresses(text string) string {
-	text = d.processSpecialAddressPattern(text, specialAddressPattern1)
-	text = d.processSpecialAddressPattern(text, specialAddressPattern2)
-	text = d.processSpecialAddressPattern3(text)
+func (d *Deidentifier) processSpecialAddresses(run *textRun, text string) string {
+	text = d.processSpecialAddressPattern(run, text, specialAddressPattern1)
+	text = d.processSpecialAddressPattern(run, text, specialAddressPattern2)
+	text = d.processSpecialAddressPattern3(run, text)
 	return text
 }
 
+// processSINs handles Canadian SIN deidentification. A candidate is only treated as a SIN
+// when the text mentions a SIN and the number passes the Luhn check, which keeps
+// US SSNs out of this path.
+func (d *Deidentifier) processSINs(run *textRun, text, originalText string) string {
+	sinContextRegex := regexp.MustCompile(sinContextRegexPattern)
+	if !sinContextRegex.MatchString(originalText) {
+		return text
+	}
+
+	sinRegex := regexp.MustCompile(sinRegexPattern)
+	return d.replaceMatches(run, text, sinRegex, func(sin, _ string) string {
+		if !d.isValidSIN(sin) {
+			return sin
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, sin, TypeSIN, "sin")
+		if err != nil {
+			return d.redactionError(run, sin, "[SIN REDACTION ERROR]", err)
+		}
+		return deidentified
+	})
+}
+
 // processSSNMatch processes a single SSN match with validation
-func (d *Deidentifier) processSSNMatch(ssn, originalText string) string {
+func (d *Deidentifier) processSSNMatch(run *textRun, ssn, originalText string) string {
 	ssnHyphenRegex := regexp.MustCompile(ssnHyphenRegexPattern)
 	ssnSpaceRegex := regexp.MustCompile(ssnSpaceRegexPattern)
 	ssnContextRegex := regexp.MustCompile(ssnContextRegexPattern)
+	sinContextRegex := regexp.MustCompile(sinContextRegexPattern)
 
 	rawDigits := regexp.MustCompile(`[^0-9]`).ReplaceAllString(ssn, "")
 	isFormatted := ssnHyphenRegex.MatchString(ssn) || ssnSpaceRegex.MatchString(ssn)
 	hasSSNContext := ssnContextRegex.MatchString(originalText)
 
+	// Unformatted Luhn-valid numbers in SIN context were handled by processSINs
+	if !isFormatted && sinContextRegex.MatchString(originalText) && d.isValidSIN(ssn) {
+		return ssn
+	}
+
 	if !isFormatted && !hasSSNContext && len(rawDigits) != 9 {
 		return ssn
 	}
 
-	deidentified, err := d.deidentifyValue(ssn, TypeSSN, "ssn")
+	deidentified, err := d.deidentifyTextValue(run, ssn, TypeSSN, "ssn")
 	if err != nil {
-		return "[SSN REDACTION ERROR]"
+		return d.redactionError(run, ssn, "[SSN REDACTION ERROR]", err)
 	}
 	return deidentified
 }
 
-// processSSNs handles SSN deidentification with context checking
-func (d *Deidentifier) processSSNs(text, originalText string) string {
+// processSSNs handles SSN deidentification with context checking. Candidates attached
+// to more digits, such as part of an account number, are skipped.
+func (d *Deidentifier) processSSNs(run *textRun, text, originalText string) string {
 	ssnRegex := regexp.MustCompile(ssnRegexPattern)
-	return ssnRegex.ReplaceAllStringFunc(text, func(ssn string) string {
-		return d.processSSNMatch(ssn, originalText)
+
+	return d.replaceSpans(run, text, ssnRegex.FindAllStringIndex(text, -1), func(loc []int) string {
+		if d.isDigitAt(text, loc[0]-1) || d.isDigitAt(text, loc[1]) {
+			return text[loc[0]:loc[1]]
+		}
+		return d.processSSNMatch(run, text[loc[0]:loc[1]], originalText)
 	})
 }
 
 // processStandardAddresses handles standard address patterns
-func (d *Deidentifier) processStandardAddresses(text string) string {
+func (d *Deidentifier) processStandardAddresses(run *textRun, text string) string {
 	addrRegex := regexp.MustCompile(addressRegexPattern)
-	return addrRegex.ReplaceAllStringFunc(text, func(addr string) string {
-		deidentified, err := d.deidentifyValue(addr, TypeAddress, "address")
+
+	return d.replaceSpans(run, text, d.findAddressMatches(text, addrRegex), func(loc []int) string {
+		addr := text[loc[0]:loc[1]]
+		deidentified, err := d.deidentifyTextValue(run, addr, TypeAddress, "address")
 		if err != nil {
-			return "[ADDRESS REDACTION ERROR]"
+			return d.redactionError(run, addr, "[ADDRESS REDACTION ERROR]", err)
 		}
 		return deidentified
 	})
 }
 
-// scoreColumnValues analyzes values in a column and updates type scores
-func (d *Deidentifier) scoreColumnValues(data [][]string, col int, patterns *patternSet, typeScores map[DataType]int) int {
-	sampleSize := len(data)
-	if sampleSize > 10 {
-		sampleSize = 10 // Sample first 10 rows for performance
+// processSWIFTs handles SWIFT/BIC codes after a "SWIFT" or "BIC" label, keeping the
+// label. Unlabeled codes are left alone, as they look like any capitalized word.
+func (d *Deidentifier) processSWIFTs(run *textRun, text string) string {
+	swiftRegex := regexp.MustCompile(swiftLabeledRegexPattern)
+	return d.replaceMatches(run, text, swiftRegex, func(match, _ string) string {
+		parts := swiftRegex.FindStringSubmatch(match)
+		deidentified, err := d.deidentifyTextValue(run, parts[2], TypeSWIFT, "swift")
+		if err != nil {
+			return parts[1] + d.redactionError(run, parts[2], "[SWIFT REDACTION ERROR]", err)
+		}
+		return parts[1] + d.protect(run, deidentified)
+	})
+}
+
+// processUncertainTokens replaces the tokens left after detection that still score at
+// least the WithUncertainRedaction threshold with a generic token, skipping replacements
+// written earlier in the run
+func (d *Deidentifier) processUncertainTokens(run *textRun, text string) string {
+	if d.uncertainThreshold <= 0 {
+		return text
+	}
+
+	tokenRegex := regexp.MustCompile(`\S+`)
+	return d.replaceMatches(run, text, tokenRegex, func(token, _ string) string {
+		core := strings.TrimFunc(token, unicode.IsPunct)
+		if core == "" || strings.ContainsRune(core, protectedStart) || d.scoreUncertainToken(core) < d.uncertainThreshold {
+			return token
+		}
+		for _, replacement := range run.replacements {
+			if strings.Contains(replacement, core) {
+				return token
+			}
+		}
+
+		start := strings.Index(token, core)
+		generic := d.generateGeneric(core)
+		d.recordFinding(run, TypeGeneric, core, generic)
+		return token[:start] + d.protect(run, generic) + token[start+len(core):]
+	})
+}
+
+// processUppercaseNames handles all-caps name pairs such as "JOHN SMITH". Only pairs
+// whose first word is a known given name are replaced, which keeps headers such as
+// "TERMS AND CONDITIONS" intact. The replacement is all caps as well and shares its
+// mapping with the mixed-case spelling of the name.
+func (d *Deidentifier) processUppercaseNames(run *textRun, text string) string {
+	upperNameRegex := regexp.MustCompile(uppercaseNameRegexPattern)
+	var locs [][]int
+	pos := 0
+	for {
+		loc := upperNameRegex.FindStringSubmatchIndex(text[pos:])
+		if loc == nil {
+			break
+		}
+		if first := text[pos+loc[2] : pos+loc[3]]; !d.isKnownGivenName(first) {
+			// The second word may still start a name, so rescan from it
+			pos += loc[4]
+			continue
+		}
+		locs = append(locs, []int{pos + loc[0], pos + loc[1]})
+		pos += loc[1]
+	}
+
+	return d.replaceSpans(run, text, locs, func(loc []int) string {
+		name := text[loc[0]:loc[1]]
+		deidentified, err := d.deidentifyCasedTextValue(run, d.titleCase(name), TypeName, "name", strings.ToUpper)
+		if err != nil {
+			return d.redactionError(run, name, "[NAME REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// processUsernames handles social handle deidentification. It runs after processEmails
+// so the domain part of an email address is never mistaken for a handle.
+func (d *Deidentifier) processUsernames(run *textRun, text string) string {
+	usernameRegex := regexp.MustCompile(usernameRegexPattern)
+	return d.replaceMatches(run, text, usernameRegex, func(match, _ string) string {
+		parts := usernameRegex.FindStringSubmatch(match)
+		if len(parts) < 3 {
+			return match
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, parts[2], TypeUsername, "username")
+		if err != nil {
+			return parts[1] + d.redactionError(run, parts[2], "[USERNAME REDACTION ERROR]", err)
+		}
+		return parts[1] + deidentified
+	})
+}
+
+// protect hides value behind a placeholder that no later Text step matches, to be
+// put back by restoreProtected
+func (d *Deidentifier) protect(run *textRun, value string) string {
+	index := len(run.protected)
+	run.protected = append(run.protected, value)
+
+	var b strings.Builder
+	b.WriteRune(protectedStart)
+	for {
+		b.WriteByte(byte('a' + index%26))
+		index /= 26
+		if index == 0 {
+			break
+		}
+	}
+	b.WriteRune(protectedEnd)
+	return b.String()
+}
+
+// processVanityPhones handles vanity numbers that spell their last digits, as in
+// 1-800-FLOWERS or 555-CAKE, when they carry a 1 prefix, a toll-free area code or the
+// 555 exchange, or spell seven letters after a phone cue, so codes such as "part
+// 800-SERIES" are kept. They are read as digits on a phone keypad, with letters past the
+// seventh digit ignored, and replaced by the numeric fake of that number.
+func (d *Deidentifier) processVanityPhones(run *textRun, text string) string {
+	vanityRegex := regexp.MustCompile(vanityPhoneRegexPattern)
+	contextRegex := regexp.MustCompile(phoneContextRegexPattern)
+	return d.replaceMatches(run, text, vanityRegex, func(match, before string) string {
+		parts := vanityRegex.FindStringSubmatch(match)
+		phone, recognized := d.vanityPhoneDigits(parts)
+		window := before[max(0, len(before)-phoneContextWindow):]
+		letters := strings.Map(func(r rune) rune {
+			if r >= 'A' && r <= 'Z' {
+				return r
+			}
+			return -1
+		}, parts[4])
+		spelled := len(letters) >= 7 && contextRegex.MatchString(window)
+		if phone == "" || !recognized && !spelled {
+			return match
+		}
+
+		deidentified, err := d.deidentifyTextValue(run, phone, TypePhone, "phone")
+		if err != nil {
+			return d.redactionError(run, match, "[PHONE REDACTION ERROR]", err)
+		}
+		return d.protect(run, deidentified)
+	})
+}
+
+// redactionError applies the configured ErrorPolicy to a failed replacement of original
+func (d *Deidentifier) redactionError(run *textRun, original, token string, err error) string {
+	switch d.errorPolicy {
+	case KeepOriginal:
+		return original
+	case FailFast:
+		if run.err == nil {
+			run.err = err
+		}
+		return original
+	default:
+		return token
+	}
+}
+
+// redactionToken returns the fixed token WithRedactedTypes uses for dataType, its
+// name in capitals and brackets, such as [ADDRESS]
+func (d *Deidentifier) redactionToken(dataType DataType) string {
+	for _, info := range SupportedTypes() {
+		if info.Type == dataType {
+			return "[" + strings.ToUpper(info.Name) + "]"
+		}
+	}
+	return "[REDACTED]"
+}
+
+// replaceDisplayName replaces the display name of an email header mailbox. A single
+// word is a given name; all-caps and all-lowercase names share the mapping of the
+// capitalized spelling and keep their case. Names that are email addresses or already
+// replaced are kept.
+func (d *Deidentifier) replaceDisplayName(run *textRun, name string) string {
+	if strings.ContainsAny(name, "@"+string(protectedStart)) || strings.TrimSpace(name) == "" {
+		return name
+	}
+
+	caseOf := func(s string) string { return s }
+	switch {
+	case name == strings.ToUpper(name):
+		name, caseOf = d.titleCase(name), strings.ToUpper
+	case name == strings.ToLower(name):
+		name, caseOf = d.titleCase(name), strings.ToLower
+	}
+	if !strings.ContainsAny(name, " ,") {
+		return d.protect(run, caseOf(d.deidentifyGivenName(run, name)))
+	}
+	deidentified, err := d.deidentifyCasedTextValue(run, name, TypeName, "name", caseOf)
+	if err != nil {
+		return d.redactionError(run, name, "[NAME REDACTION ERROR]", err)
+	}
+	return d.protect(run, deidentified)
+}
+
+// replaceMatches replaces each match of re in text with the result of replace, which
+// also receives the text preceding the match for context checks
+func (d *Deidentifier) replaceMatches(run *textRun, text string, re *regexp.Regexp, replace func(match, before string) string) string {
+	return d.replaceSpans(run, text, re.FindAllStringIndex(text, -1), func(loc []int) string {
+		return replace(text[loc[0]:loc[1]], text[:loc[0]])
+	})
+}
+
+// replaceSpans replaces the sorted, disjoint spans of text at locs with what replace
+// returns for each. Every Text step writes its replacements through it, so it keeps
+// run.origins in step with the text; a span replaced by itself keeps its origins.
+func (d *Deidentifier) replaceSpans(run *textRun, text string, locs [][]int, replace func(loc []int) string) string {
+	var b strings.Builder
+	var origins []int
+	last := 0
+	for _, loc := range locs {
+		replacement := replace(loc)
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(replacement)
+		if run.origins != nil {
+			origins = append(origins, run.origins[last:loc[0]]...)
+			if replacement == text[loc[0]:loc[1]] {
+				origins = append(origins, run.origins[loc[0]:loc[1]]...)
+			} else {
+				origins = append(origins, slices.Repeat([]int{-1}, len(replacement))...)
+			}
+		}
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	if run.origins != nil {
+		run.origins = append(origins, run.origins[last:]...)
+	}
+	return b.String()
+}
+
+// replaceSignatureName replaces the name line of a signature, a single word as a given
+// name and longer names as full names
+func (d *Deidentifier) replaceSignatureName(run *textRun, name string) string {
+	if !strings.ContainsAny(name, " '-") {
+		return d.protect(run, d.deidentifyGivenName(run, name))
+	}
+	deidentified, err := d.deidentifyTextValue(run, name, TypeName, "name")
+	if err != nil {
+		return d.redactionError(run, name, "[NAME REDACTION ERROR]", err)
+	}
+	return d.protect(run, deidentified)
+}
+
+// restoreInvisible puts the invisible characters stripped from original to give text
+// back into result, the output of detection over text whose bytes came from the text
+// bytes at origins (see replaceSpans). Characters next to unchanged text are kept, so
+// emoji joiners and bidi marks around and between replaced values survive, while those
+// strictly inside a replaced value are dropped with it.
+func (d *Deidentifier) restoreInvisible(original, text, result string, origins []int) string {
+	// offsets[k] is the index in original of byte k of text
+	offsets := make([]int, 0, len(text)+1)
+	for i := 0; i < len(original); {
+		r, size := utf8.DecodeRuneInString(original[i:])
+		for j := 0; j < size && !d.isInvisible(r); j++ {
+			offsets = append(offsets, i+j)
+		}
+		i += size
+	}
+	if len(offsets) != len(text) || len(origins) != len(result) {
+		return result // invalid UTF-8 changed length when stripped
+	}
+	offsets = append(offsets, len(original))
+
+	// before returns the invisible characters stripped just before byte k of text
+	before := func(k int) string {
+		if k == 0 {
+			return original[:offsets[0]]
+		}
+		return original[offsets[k-1]+1 : offsets[k]]
+	}
+	kept := make([]bool, len(text))
+	for _, k := range origins {
+		if k >= 0 {
+			kept[k] = true
+		}
+	}
+
+	var b strings.Builder
+	if !kept[0] {
+		b.WriteString(before(0))
+	}
+	for i, k := range origins {
+		if k >= 0 {
+			b.WriteString(before(k))
+		}
+		b.WriteByte(result[i])
+		if k >= 0 && k+1 < len(text) && !kept[k+1] {
+			b.WriteString(before(k + 1))
+		}
+	}
+	b.WriteString(before(len(text)))
+	return b.String()
+}
+
+// restoreProtected puts protected values back in place of their placeholders
+func (d *Deidentifier) restoreProtected(run *textRun, text string) string {
+	if len(run.protected) == 0 {
+		return text
+	}
+
+	placeholderRegex := regexp.MustCompile(protectedRegexPattern)
+	return placeholderRegex.ReplaceAllStringFunc(text, func(placeholder string) string {
+		index, scale := 0, 1
+		for _, letter := range strings.Trim(placeholder, string([]rune{protectedStart, protectedEnd})) {
+			index += int(letter-'a') * scale
+			scale *= 26
+		}
+		if index >= len(run.protected) {
+			return placeholder
+		}
+		return d.restoreProtected(run, run.protected[index]) // a protected alias may be nested
+	})
+}
+
+// runText runs the detection pipeline over text, recording errors, protected values
+// and findings in run
+func (d *Deidentifier) runText(run *textRun, text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	// Zero-width and control characters are dropped so they cannot split PII apart and
+	// hide it from the patterns. Only those inside replaced values stay dropped, see
+	// restoreInvisible, and text in which nothing is found is returned untouched.
+	original := text
+	text = d.stripInvisible(text)
+	if text != original {
+		run.origins = make([]int, len(text))
+		for i := range run.origins {
+			run.origins[i] = i
+		}
+	}
+
+	result := text
+	result = d.processCustomPatterns(run, result)
+	result = d.processCryptoAddresses(run, result)
+	result = d.processLatLongs(run, result)
+	result = d.processIPAddresses(run, result)
+	result = d.processAges(run, result)
+	result = d.processRelativeDates(run, result)
+	result = d.processAddressBlocks(run, result)
+	result = d.processEmails(run, result)
+	result = d.processUsernames(run, result)
+	result = d.processPhoneURIs(run, result)
+	result = d.processVanityPhones(run, result)
+	result = d.processLocalPhones(run, result)
+	result = d.processBarePhones(run, result)
+	result = d.processPhones(run, result)
+	result = d.processLabeledSSNs(run, result)
+	result = d.processSINs(run, result, text)
+	result = d.processNINOs(run, result)
+	result = d.processSSNs(run, result, text)
+	result = d.processCreditCards(run, result)
+	result = d.processSWIFTs(run, result)
+	result = d.processContextAddresses(run, result)
+	result = d.processSpecialAddresses(run, result)
+	result = d.processHeaderNames(run, result)
+	result = d.processMailboxNames(run, result)
+	result = d.processNicknameNames(run, result)
+	result = d.processSalutationNames(run, result)
+	result = d.processSignatureNames(run, result)
+	result = d.processReversedNames(run, result, text)
+	result = d.processParticleNames(run, result)
+	result = d.processNames(run, result)
+	result = d.processUppercaseNames(run, result)
+	result = d.processGivenNames(run, result)
+	result = d.processStandardAddresses(run, result)
+	result = d.processUncertainTokens(run, result)
+
+	if run.err != nil {
+		return "", run.err
+	}
+	if result == text {
+		return original, nil
+	}
+	if run.origins != nil {
+		result = d.restoreInvisible(original, text, result, run.origins)
+	}
+	return d.restoreProtected(run, result), nil
+}
+
+// sampleRows returns the rows out of n that inference scores: the first headSampleRows,
+// or those the WithColumnSampler function picks
+func (d *Deidentifier) sampleRows(n int) []int {
+	if d.columnSampler != nil {
+		return d.columnSampler(n)
 	}
+	rows := make([]int, min(n, headSampleRows))
+	for i := range rows {
+		rows[i] = i
+	}
+	return rows
+}
 
+// scoreColumnValues analyzes the values of a column in the given rows and updates type
+// scores. Rows out of range are skipped.
+func (d *Deidentifier) scoreColumnValues(data [][]string, rows []int, col int, patterns *patternSet, typeScores map[DataType]int) int {
 	validValues := 0
-	for row := 0; row < sampleSize; row++ {
-		if d.isValidValue(data, row, col) {
+	for _, row := range rows {
+		if row >= 0 && row < len(data) && d.isValidValue(data, row, col) {
 			value := strings.TrimSpace(data[row][col])
 			validValues++
 			d.scoreValue(value, patterns, typeScores)
@@ -803,6 +3274,57 @@ func (d *Deidentifier) scoreColumnValues(data [][]string, col int, patterns *pat
 	return validValues
 }
 
+// scoreIdentifierValue scores a value against the national ID and account handle types
+func (d *Deidentifier) scoreIdentifierValue(value string, patterns *patternSet, typeScores map[DataType]int) {
+	if patterns.nino.MatchString(value) {
+		typeScores[TypeNINO] += 10
+	}
+	if patterns.username.MatchString(value) {
+		typeScores[TypeUsername] += 10
+	}
+	if address := patterns.cryptoAddress.FindString(value); address != "" && d.isCryptoAddress(address) {
+		typeScores[TypeCryptoAddress] += 10
+	}
+	// Unformatted nine-digit values are left to SSN scoring
+	if patterns.sin.MatchString(value) && !patterns.ssn.MatchString(value) && d.isValidSIN(value) {
+		typeScores[TypeSIN] += 10
+	}
+	if _, _, _, ok := d.parseIPAddress(value); ok {
+		typeScores[TypeIPAddress] += 10
+	}
+}
+
+// scoreUncertainToken scores from 0 to 10 how much a token left after detection still
+// looks like PII: long digit runs as in partial phone, card or account numbers, IDs
+// mixing letters and digits, and anything with an inner @
+func (d *Deidentifier) scoreUncertainToken(token string) int {
+	digits, letters := 0, 0
+	for _, r := range token {
+		if unicode.IsDigit(r) {
+			digits++
+		} else if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+
+	score := 0
+	switch {
+	case digits >= 9:
+		score = 8
+	case digits >= 7:
+		score = 6
+	case digits >= 5:
+		score = 4
+	}
+	if letters > 0 && digits >= 4 && len(token) >= 6 {
+		score = max(score, 5)
+	}
+	if at := strings.Index(token, "@"); at > 0 && at < len(token)-1 {
+		score = max(score, 8)
+	}
+	return score
+}
+
 // scoreValue scores a single value against all patterns
 func (d *Deidentifier) scoreValue(value string, patterns *patternSet, typeScores map[DataType]int) {
 	if patterns.email.MatchString(value) {
@@ -817,12 +3339,17 @@ func (d *Deidentifier) scoreValue(value string, patterns *patternSet, typeScores
 	if patterns.creditCard.MatchString(value) {
 		typeScores[TypeCreditCard] += 10
 	}
-	if patterns.address.MatchString(value) || patterns.addressWord.MatchString(value) {
+	isAddress := d.isAddressValue(value, patterns)
+	if isAddress {
 		typeScores[TypeAddress] += 10
 	}
-	if patterns.name.MatchString(value) && !patterns.addressWord.MatchString(value) {
+	if patterns.name.MatchString(value) && !isAddress {
 		typeScores[TypeName] += 5 // Lower weight since names are harder to detect
 	}
+	if d.inferCategorical && d.isCategoricalValue(value) {
+		typeScores[TypeCategorical] += 10
+	}
+	d.scoreIdentifierValue(value, patterns, typeScores)
 }
 
 // selectBestType determines the best type based on scores and confidence thresholds
@@ -852,14 +3379,97 @@ func (d *Deidentifier) setDefaultColumnNames(config *slicesConfig) error {
 }
 
 // setMapping stores a mapping for deterministic results
-func (d *Deidentifier) setMapping(columnName, original, replacement string) {
-	d.mutex.Lock()
-	defer d.mutex.Unlock()
+func (d *Deidentifier) setMapping(columnName string, dataType DataType, original, replacement string) {
+	namespace := d.mappingNamespace(columnName)
+	d.columnTypes.record(namespace, dataType)
+	d.mappings.Set(namespace, original, replacement)
+}
+
+// shortestEntry returns the length in characters of the shortest entry of pool
+func (d *Deidentifier) shortestEntry(pool []string) int {
+	shortest := 0
+	for i, entry := range pool {
+		if n := utf8.RuneCountInString(entry); i == 0 || n < shortest {
+			shortest = n
+		}
+	}
+	return shortest
+}
 
-	if d.mappingTables[columnName] == nil {
-		d.mappingTables[columnName] = make(map[string]string)
+// splitNameSuffix separates a trailing generational suffix from a name
+func (d *Deidentifier) splitNameSuffix(name string) (string, string) {
+	suffixRegex := regexp.MustCompile(nameSuffixRegexPattern)
+	loc := suffixRegex.FindStringIndex(name)
+	if loc == nil {
+		return name, ""
 	}
-	d.mappingTables[columnName][original] = replacement
+	return name[:loc[0]], name[loc[0]:]
+}
+
+// splitPlusTag removes the "+tag" from the local part of email, returning the untagged
+// address and the tag without its "+"
+func (d *Deidentifier) splitPlusTag(email string) (string, string) {
+	at := strings.LastIndex(email, "@")
+	plus := strings.Index(email[:max(at, 0)], "+")
+	if plus < 0 {
+		return email, ""
+	}
+	return email[:plus] + email[at:], email[plus+1 : at]
+}
+
+// splitSchema converts a schema into parallel column type and name slices
+func (d *Deidentifier) splitSchema(schema []ColumnSpec) ([]DataType, []string) {
+	columnTypes := make([]DataType, len(schema))
+	columnNames := make([]string, len(schema))
+	for i, spec := range schema {
+		columnTypes[i] = spec.Type
+		columnNames[i] = spec.Name
+	}
+	return columnTypes, columnNames
+}
+
+// startsCapitalized reports whether text begins with an uppercase ASCII letter
+func (d *Deidentifier) startsCapitalized(text string) bool {
+	return text != "" && text[0] >= 'A' && text[0] <= 'Z'
+}
+
+// streetNumberLimit returns the largest house number a generated address on street may
+// use: the WithMaxStreetNumber limit, lowered under WithReplacementLengthCap until the
+// number fits beside the street
+func (d *Deidentifier) streetNumberLimit(street string) int {
+	limit := d.maxStreetNumber
+	if d.lengthCaps[TypeAddress] <= 0 {
+		return limit
+	}
+
+	digits := d.lengthCaps[TypeAddress] - utf8.RuneCountInString(street) - 1
+	for digits > 0 && len(strconv.Itoa(limit)) > digits {
+		limit /= 10
+	}
+	return max(limit, 1)
+}
+
+// stripInvisible removes zero-width, formatting and control characters other than
+// line breaks and tabs, which could otherwise be embedded in PII to evade detection
+func (d *Deidentifier) stripInvisible(text string) string {
+	return strings.Map(func(r rune) rune {
+		if d.isInvisible(r) {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// titleCase capitalizes the first letter of each space- or hyphen-separated word and
+// lowercases the rest, so "JOHN SMITH" becomes "John Smith"
+func (d *Deidentifier) titleCase(text string) string {
+	b := []byte(strings.ToLower(text))
+	for i := range b {
+		if (i == 0 || b[i-1] == ' ' || b[i-1] == '\t' || b[i-1] == '-') && b[i] >= 'a' && b[i] <= 'z' {
+			b[i] -= 'a' - 'A'
+		}
+	}
+	return string(b)
 }
 
 // validateSlicesConfig validates that configuration matches data structure
@@ -870,3 +3480,39 @@ func (d *Deidentifier) validateSlicesConfig(config *slicesConfig) error {
 	}
 	return nil
 }
+
+// vanityPhoneDigits converts the submatches of vanityPhoneRegexPattern into the number
+// they spell, formatted as ddd-dddd or with its area code, or "" when the letters are
+// too few. It also reports whether the number is recognizable without a phone cue.
+func (d *Deidentifier) vanityPhoneDigits(parts []string) (string, bool) {
+	prefix, area, exchange := parts[1], parts[2], parts[3]
+	digits := []byte(parts[4])
+	for i, c := range digits {
+		if c >= 'A' && c <= 'Z' {
+			digits[i] = vanityKeypadDigits[c-'A']
+		}
+	}
+
+	// In 1-800-FLOWERS the three digits are the area code and the letters spell seven
+	if area == "" && slices.Contains(tollFreeAreaCodeOptions, exchange) && len(digits) >= 7 {
+		area, exchange, digits = exchange, string(digits[:3]), digits[3:]
+	}
+	if len(digits) < 4 {
+		return "", false
+	}
+
+	recognized := prefix != "" || exchange == "555" || slices.Contains(tollFreeAreaCodeOptions, area)
+	if area == "" {
+		return exchange + "-" + string(digits[:4]), recognized
+	}
+	return prefix + area + "-" + exchange + "-" + string(digits[:4]), recognized
+}
+
+// versionTag returns GeneratorVersion after separator when WithVersionTag is set, and
+// "" otherwise
+func (d *Deidentifier) versionTag(separator string) string {
+	if !d.tagVersion {
+		return ""
+	}
+	return separator + GeneratorVersion
+}
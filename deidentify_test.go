@@ -1,9 +1,24 @@
 package deidentify
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestDeterministicReplacement(t *testing.T) {
@@ -27,6 +42,151 @@ func TestDeterministicReplacement(t *testing.T) {
 	}
 }
 
+func TestNonDeterministicMode(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithNonDeterministicMode())
+	seen := make(map[string]bool)
+	for i := 0; i < 5; i++ {
+		result, err := d.Email("jane@example.com")
+		if err != nil {
+			t.Fatalf("Email failed: %v", err)
+		}
+		if seen[result] {
+			t.Errorf("Expected a new fake for every occurrence, got %s again", result)
+		}
+		seen[result] = true
+	}
+
+	result, err := d.Text("Jane Smith met Jane Smith")
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	first, second, _ := strings.Cut(result, " met ")
+	if first == second || first == "Jane Smith" || second == "Jane Smith" {
+		t.Errorf("Expected two different fakes, got %q", result)
+	}
+	if forward, _ := d.LastRunMappings(); len(forward) != 0 {
+		t.Errorf("Expected no stored mappings, got %v", forward)
+	}
+
+	// Standalone given names are not linkable across calls either
+	greeting, _ := d.Text("Dear Maria, thanks.")
+	again, _ := d.Text("Dear Maria, thanks.")
+	if greeting == again || strings.Contains(greeting, "Maria") {
+		t.Errorf("Expected a new fake given name per call, got %q and %q", greeting, again)
+	}
+	if forward, _ := d.LastRunMappings(); len(forward) != 0 {
+		t.Errorf("Expected no stored given name mappings, got %v", forward)
+	}
+
+	// A fresh instance making the same calls reproduces the sequence
+	a := NewDeidentifier("test-secret-key", WithNonDeterministicMode())
+	b := NewDeidentifier("test-secret-key", WithNonDeterministicMode())
+	for i := 0; i < 3; i++ {
+		x, _ := a.SSN("123-45-6789")
+		y, _ := b.SSN("123-45-6789")
+		if x != y {
+			t.Errorf("Call %d: expected %s from both instances, got %s", i, x, y)
+		}
+	}
+}
+
+func TestAuto(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	testCases := []struct {
+		value    string
+		dataType DataType
+		method   func(string) (string, error)
+	}{
+		{"jane@example.com", TypeEmail, d.Email},
+		{"(555) 123-4567", TypePhone, d.Phone},
+		{"123-45-6789", TypeSSN, d.SSN},
+		{"4111 1111 1111 1111", TypeCreditCard, d.CreditCard},
+		{"Jane Smith", TypeName, d.Name},
+		{"42 Elm Street", TypeAddress, d.Address},
+		{"@frodo_b", TypeUsername, d.Username},
+		{"AB 12 34 56 C", TypeNINO, d.NINO},
+		{"046 454 286", TypeSIN, d.SIN},
+	}
+	for _, tc := range testCases {
+		result, dataType, err := d.Auto(tc.value)
+		if err != nil {
+			t.Fatalf("Auto(%q) failed: %v", tc.value, err)
+		}
+		if dataType != tc.dataType {
+			t.Errorf("Auto(%q): expected type %d, got %d", tc.value, tc.dataType, dataType)
+		}
+		if expected, _ := tc.method(tc.value); result != expected {
+			t.Errorf("Auto(%q): expected %q like the convenience method, got %q", tc.value, expected, result)
+		}
+	}
+
+	// Values no type claims, or that several types claim equally, are still replaced
+	for _, value := range []string{"hello world", "order 42", "4111111111111111", "123 456 789", "+44 20 7946 0958", "jane smith", "JANE SMITH"} {
+		result, dataType, err := d.Auto(value)
+		if err != nil {
+			t.Fatalf("Auto(%q) failed: %v", value, err)
+		}
+		if result == value {
+			t.Errorf("Auto(%q): expected a replacement, got the value back as type %d", value, dataType)
+		}
+		if expected := d.generateGeneric(value); dataType == TypeGeneric && result != expected {
+			t.Errorf("Auto(%q): expected the generic replacement %q, got %q", value, expected, result)
+		}
+	}
+	if result, dataType, err := d.Auto(""); err != nil || dataType != TypeGeneric || result != "" {
+		t.Errorf("Auto(\"\"): expected an empty TypeGeneric value, got %q, %d, %v", result, dataType, err)
+	}
+}
+
+func TestCrossInstanceConsistency(t *testing.T) {
+	text := "Contact Jane Smith at jane.smith@example.com or (555) 123-4567. SSN: 123-45-6789. " +
+		"She lives at 742 Evergreen Terrace, Springfield, IL 62704."
+	rows := [][]string{
+		{"Jane Smith", "jane.smith@example.com", "555-123-4567"},
+		{"Bob Jones", "bob.jones@example.com", "555-987-6543"},
+	}
+	types := []DataType{TypeName, TypeEmail, TypePhone}
+	names := []string{"name", "email", "phone"}
+
+	// Two services with the same key share no mapping table and see different traffic
+	// before the values in question; their replacements must still agree
+	first := NewDeidentifier("shared-secret-key")
+	second := NewDeidentifier("shared-secret-key")
+	if _, err := second.Text("Earlier traffic from Bob Jones, bob@example.org, 555-000-1111"); err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+
+	want, err := first.Text(text)
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	if got, _ := second.Text(text); got != want {
+		t.Errorf("Expected identical text across instances:\n%s\n%s", want, got)
+	}
+
+	wantRows, err := first.Slices(rows, types, names)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	reversed := [][]string{rows[1], rows[0]}
+	gotRows, err := second.Slices(reversed, types, names)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	for i := range wantRows {
+		for j := range wantRows[i] {
+			if got := gotRows[len(gotRows)-1-i][j]; got != wantRows[i][j] {
+				t.Errorf("Row %d column %s: expected %q across instances, got %q", i, names[j], wantRows[i][j], got)
+			}
+		}
+	}
+
+	if other, _ := NewDeidentifier("another-secret-key").Text(text); other == want {
+		t.Error("Different keys should produce different output")
+	}
+}
+
 func TestEmailDeidentification(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
 
@@ -51,6 +211,24 @@ func TestEmailDeidentification(t *testing.T) {
 	}
 }
 
+func TestEmailCollisionRate(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping high-cardinality test in short mode")
+	}
+
+	d := NewDeidentifier("test-secret-key")
+	const total = 100000
+	seen := make(map[string]bool, total)
+	for i := 0; i < total; i++ {
+		seen[d.generateEmail(fmt.Sprintf("customer%d@example.com", i))] = true
+	}
+
+	collisions := total - len(seen)
+	if rate := float64(collisions) / total; rate > 0.001 {
+		t.Errorf("Expected a collision rate below 0.1%%, got %d collisions (%.4f%%)", collisions, rate*100)
+	}
+}
+
 func TestPhoneDeidentification(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
 
@@ -79,6 +257,304 @@ func TestPhoneDeidentification(t *testing.T) {
 	}
 }
 
+func TestBarePhones(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	ten, _ := d.Phone("5551234567")
+	eleven, _ := d.Phone("15551234567")
+	plus, _ := d.Phone("+15551234567")
+	if !regexp.MustCompile(`^\d{10}$`).MatchString(ten) || !regexp.MustCompile(`^1\d{10}$`).MatchString(eleven) {
+		t.Fatalf("Expected same-length digit replacements, got %s and %s", ten, eleven)
+	}
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"Call 5551234567 today", "Call " + ten + " today"},
+		{"mobile number: 15551234567", "mobile number: " + eleven},
+		{"Reach me on +15551234567", "Reach me on " + plus},
+		// Without a cue, bare digit runs are still replaced by default
+		{"Reach me at 5551234567 tomorrow", "Reach me at " + ten + " tomorrow"},
+		{"Customer 15551234567 emailed", "Customer " + eleven + " emailed"},
+		// Part of a longer number is never a phone
+		{"Call about account 123456789012345", "Call about account 123456789012345"},
+	}
+	for _, tc := range testCases {
+		result, err := d.Text(tc.input)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if result != tc.expected {
+			t.Errorf("Text(%q): expected %q, got %q", tc.input, tc.expected, result)
+		}
+	}
+
+	// WithBarePhoneContext keeps un-cued runs, which may be IDs
+	cued := NewDeidentifier("test-secret-key", WithBarePhoneContext())
+	for input, want := range map[string]string{
+		"Order 5551234567 shipped":     "Order 5551234567 shipped",
+		"Customer 15551234567 emailed": "Customer 15551234567 emailed",
+		"Call 5551234567 today":        "Call " + ten + " today",
+		"Reach me on +15551234567":     "Reach me on " + plus,
+	} {
+		if result, _ := cued.Text(input); result != want {
+			t.Errorf("Text(%q) with WithBarePhoneContext: expected %q, got %q", input, want, result)
+		}
+	}
+}
+
+func TestVanityPhones(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	flowers, _ := d.Phone("1-800-356-9377")
+	cake, _ := d.Phone("555-2253")
+	pizza, _ := d.Phone("212-555-7499")
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"Order at 1-800-FLOWERS today", "Order at " + flowers + " today"},
+		{"Dial 555-CAKE", "Dial " + cake},
+		{"phone: 212-555-PIZZA", "phone: " + pizza},
+		// Without a recognizable prefix, or seven letters after a phone cue, codes like
+		// these are kept
+		{"Order 100-PACK ships", "Order 100-PACK ships"},
+		{"Call about part 800-SERIES", "Call about part 800-SERIES"},
+	}
+	for _, tc := range testCases {
+		result, err := d.Text(tc.input)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if result != tc.expected {
+			t.Errorf("Text(%q): expected %q, got %q", tc.input, tc.expected, result)
+		}
+	}
+
+	// The 1- prefix and toll-free area code survive, and only the spelled digits change
+	if !regexp.MustCompile(`^1-800-\d{3}-\d{4}$`).MatchString(flowers) || flowers == "1-800-356-9377" {
+		t.Errorf("Expected a fake toll-free number like 1-800-XXX-XXXX, got %q", flowers)
+	}
+}
+
+func TestPhoneUnusualFormats(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	testCases := []struct {
+		original string
+		pattern  string
+	}{
+		{"+44 20 7946 0958", `^\+44 [1-9]\d \d{4} \d{4}$`},
+		{"+33 1 42 68 53 00", `^\+33 [1-9] \d{2} \d{2} \d{2} \d{2}$`},
+		{"+49 (0)30 123456", `^\+49 \(0\)[1-9]\d \d{6}$`},
+		{"0049-30-1234567", `^0049-[1-9]\d-\d{7}$`},
+		{"020 7946 0958", `^0[1-9]\d \d{4} \d{4}$`},
+		{"12345", `^[1-9]\d{4}$`},
+		{"ext. 42", `^ext\. [1-9]\d$`},
+	}
+
+	for _, tc := range testCases {
+		result, err := d.Phone(tc.original)
+		if err != nil {
+			t.Fatalf("Phone failed: %v", err)
+		}
+		if matched, _ := regexp.MatchString(tc.pattern, result); !matched {
+			t.Errorf("Phone(%q) = %q, expected the format kept (%s)", tc.original, result, tc.pattern)
+		}
+		if result == tc.original {
+			t.Errorf("Phone(%q) should change the digits", tc.original)
+		}
+		if again, _ := d.Phone(tc.original); again != result {
+			t.Errorf("Expected deterministic results, got %q and %q", result, again)
+		}
+	}
+
+	// Only values without any digits get a generic replacement
+	if result, _ := d.Phone("unknown"); !strings.HasPrefix(result, "DATA_") {
+		t.Errorf("Expected a generic replacement without digits, got %q", result)
+	}
+}
+
+func TestRelativeDates(t *testing.T) {
+	text := "Seen 3 days ago and yesterday; follow up in two weeks or next Tuesday. Onset last month."
+
+	if result, _ := NewDeidentifier("test-secret-key").Text(text); result != text {
+		t.Errorf("Expected relative dates to be kept by default, got %q", result)
+	}
+
+	redacted, err := NewDeidentifier("test-secret-key", WithRelativeDates(time.Time{})).Text(text)
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	if redacted != "Seen [DATE] and [DATE]; follow up [DATE] or [DATE]. Onset [DATE]." {
+		t.Errorf("Expected every relative date redacted, got %q", redacted)
+	}
+
+	// Thursday 15 October 2026
+	reference := time.Date(2026, time.October, 15, 9, 0, 0, 0, time.UTC)
+	d := NewDeidentifier("test-secret-key", WithRelativeDates(reference))
+	shift := d.dateShiftDays()
+	if shift == 0 || shift < -maxDateShiftDays || shift > maxDateShiftDays {
+		t.Fatalf("Expected a non-zero shift of at most %d days, got %d", maxDateShiftDays, shift)
+	}
+	date := func(year int, month time.Month, day int, layout string) string {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC).AddDate(0, 0, shift).Format(layout)
+	}
+
+	result, err := d.Text(text)
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	expected := "Seen " + date(2026, time.October, 12, "2006-01-02") +
+		" and " + date(2026, time.October, 14, "2006-01-02") +
+		"; follow up " + date(2026, time.October, 29, "2006-01-02") +
+		" or " + date(2026, time.October, 20, "2006-01-02") +
+		". Onset " + date(2026, time.September, 15, "2006-01") + "."
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	// The closest matching weekday is never the reference date itself
+	if result, _ := d.Text("last Thursday"); result != date(2026, time.October, 8, "2006-01-02") {
+		t.Errorf("Expected the previous Thursday, got %q", result)
+	}
+}
+
+func TestRedactedTypes(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithRedactedTypes())
+	result, err := d.Text("Ship to 10 Downing Street, London, UK for jane@example.com")
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	email, _ := d.Email("jane@example.com")
+	if result != "Ship to [ADDRESS] for "+email {
+		t.Errorf("Expected only the address to be redacted, got %q", result)
+	}
+	if address, _ := d.Address("221B Baker Street"); address != "[ADDRESS]" {
+		t.Errorf("Expected [ADDRESS], got %q", address)
+	}
+	if forward, _ := d.LastRunMappings(); len(forward["address"]) != 0 {
+		t.Errorf("Expected no address mappings, got %v", forward["address"])
+	}
+
+	d = NewDeidentifier("test-secret-key", WithRedactedTypes(TypeSSN, TypeCreditCard))
+	result, _ = d.Text("SSN 123-45-6789, card 4111-1111-1111-1111")
+	if result != "SSN [SSN], card [CREDIT CARD]" {
+		t.Errorf("Expected the listed types to be redacted, got %q", result)
+	}
+	if address, _ := d.Address("221B Baker Street"); address == "[ADDRESS]" {
+		t.Error("Expected unlisted types to keep realistic fakes")
+	}
+
+	// Single given names in greetings, headers and signatures are names too
+	d = NewDeidentifier("test-secret-key", WithRedactedTypes(TypeName))
+	for input, want := range map[string]string{
+		"Dear Maria, John Smith called.":                "Dear [NAME], [NAME] called.",
+		"From: Legolas <legolas@example.com>":           "From: [NAME] <",
+		"Thanks,\nPriya\nwww.priya.example\n":           "Thanks,\n[NAME]\n",
+		"From: Legolas Greenleaf <legolas@example.com>": "From: [NAME] <",
+	} {
+		result, err := d.Text(input)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if !strings.HasPrefix(result, want) {
+			t.Errorf("Text(%q) = %q, want it to start with %q", input, result, want)
+		}
+	}
+}
+
+func TestRedistributedAreaCodes(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithRedistributedAreaCodes())
+
+	valid := make(map[string]bool)
+	for _, code := range nanpAreaCodeOptions {
+		valid[code] = true
+	}
+
+	phoneRegex := regexp.MustCompile(`^\((\d{3})\) \d{3}-\d{4}$`)
+	areaCodes := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		result := d.generatePhone(fmt.Sprintf("(555) 123-%04d", i))
+
+		matches := phoneRegex.FindStringSubmatch(result)
+		if matches == nil {
+			t.Fatalf("Phone format not preserved, got %s", result)
+		}
+		if !valid[matches[1]] {
+			t.Errorf("Area code %s is not a valid NANP area code", matches[1])
+		}
+		areaCodes[matches[1]] = true
+	}
+
+	if len(areaCodes) < 10 {
+		t.Errorf("Expected area codes to spread out, got only %d distinct codes", len(areaCodes))
+	}
+
+	if d.generatePhone("(555) 123-4567") != d.generatePhone("(555) 123-4567") {
+		t.Error("Redistributed area codes should be deterministic")
+	}
+}
+
+func TestLabeledSSNs(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	fake, _ := d.SSN("123-45-6789")
+	cases := map[string]string{
+		"SSN #123456789 on file":            "SSN #" + fake + " on file",
+		"SS# 123-45-6789":                   "SS# " + fake,
+		"SSN no. 123-45-6789":               "SSN no. " + fake,
+		"ssn:123456789.":                    "ssn:" + fake + ".",
+		"Social Security Number: 123456789": "Social Security Number: " + fake,
+	}
+	for input, want := range cases {
+		if got, err := d.Text(input); err != nil || got != want {
+			t.Errorf("Text(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+}
+
+func TestSSNMixedSeparators(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	want, err := d.SSN("123-45-6789")
+	if err != nil {
+		t.Fatalf("SSN failed: %v", err)
+	}
+
+	for _, ssn := range []string{"123-45 6789", "123 45-6789", "123 45 6789", "123456789", "12345-6789", "123-456789", "12345 6789", "123 456789"} {
+		if got, _ := d.SSN(ssn); got != want {
+			t.Errorf("SSN(%q) = %q, want the same replacement as 123-45-6789 (%q)", ssn, got, want)
+		}
+
+		result, err := d.Text("SSN: " + ssn + " on file")
+		if err != nil {
+			t.Fatalf("Text() error = %v", err)
+		}
+		if result != "SSN: "+want+" on file" {
+			t.Errorf("Text with %q: expected the whole SSN replaced by %q, got %q", ssn, want, result)
+		}
+	}
+}
+
+func TestCanonicalPhoneFormat(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithCanonicalPhoneFormat("+1XXXXXXXXXX"))
+	e164 := regexp.MustCompile(`^\+1555\d{7}$`)
+	for _, phone := range []string{"(555) 123-4567", "555.123.4568", "+1 555-123-4569", "555 123 4570"} {
+		result, err := d.Phone(phone)
+		if err != nil {
+			t.Fatalf("Phone(%q) error = %v", phone, err)
+		}
+		if !e164.MatchString(result) {
+			t.Errorf("expected E.164 output for %q, got %q", phone, result)
+		}
+	}
+
+	// Invalid layouts are ignored and the input format is preserved
+	preserved := NewDeidentifier("test-secret-key", WithCanonicalPhoneFormat("+1XXX"))
+	if result, _ := preserved.Phone("(555) 123-4567"); !regexp.MustCompile(`^\(555\) \d{3}-\d{4}$`).MatchString(result) {
+		t.Errorf("expected the input format to be preserved, got %q", result)
+	}
+}
+
 func TestSSNDeidentification(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
 
@@ -137,6 +613,55 @@ func TestSSNPatternMatching(t *testing.T) {
 	}
 }
 
+func TestSSNNotMatchedInsideLongerNumbers(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	input := "Account 1234567890123456 belongs to SSN 123-45-6789, ref 123456789012"
+	result := d.processSSNs(&textRun{}, input, input)
+
+	if !strings.Contains(result, "1234567890123456") {
+		t.Errorf("16-digit account number should not be treated as an SSN, got %s", result)
+	}
+	if !strings.Contains(result, "123456789012") {
+		t.Errorf("12-digit reference should not be treated as an SSN, got %s", result)
+	}
+	if strings.Contains(result, "123-45-6789") {
+		t.Errorf("Standalone SSN should be redacted, got %s", result)
+	}
+
+	// An SSN glued to letters is still an SSN
+	result, err := d.Text("Patient ID123-45-6789 admitted")
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	if strings.Contains(result, "123-45-6789") || !strings.Contains(result, "Patient ID") {
+		t.Errorf("SSN attached to letters should be redacted, got %s", result)
+	}
+}
+
+func TestAdjacentSSNs(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	first, _ := d.SSN("123-45-6789")
+	second, _ := d.SSN("987-65-4321")
+
+	// Each SSN is matched on its own, whatever separates the two
+	for _, input := range []string{
+		"123-45-6789 987-65-4321",
+		"123 45 6789 987 65 4321",
+		"123456789 987654321",
+		"123-45-6789  987-65-4321",
+	} {
+		result, err := d.Text(input)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		fields := strings.Fields(result)
+		if len(fields) != 2 || fields[0] != first || fields[1] != second {
+			t.Errorf("Text(%q): expected %s and %s, got %q", input, first, second, result)
+		}
+	}
+}
+
 func TestCreditCardDeidentification(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
 
@@ -166,6 +691,100 @@ func TestCreditCardDeidentification(t *testing.T) {
 	}
 }
 
+func TestCreditCardDots(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	fake, err := d.CreditCard("4532.1234.5678.9012")
+	if err != nil {
+		t.Fatalf("CreditCard failed: %v", err)
+	}
+	if !regexp.MustCompile(`^\d{4}\.\d{4}\.\d{4}\.\d{4}$`).MatchString(fake) || !isValidLuhn(strings.ReplaceAll(fake, ".", "")) {
+		t.Errorf("Expected a dot-separated valid card, got %s", fake)
+	}
+
+	result, err := d.Text("Card 4532.1234.5678.9012 was charged")
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	if result != "Card "+fake+" was charged" {
+		t.Errorf("Expected the dotted card to be replaced by %s, got %q", fake, result)
+	}
+}
+
+func TestSeparatorNormalization(t *testing.T) {
+	hyphens := NewDeidentifier("test-secret-key", WithSeparatorNormalization("-"))
+	bare := NewDeidentifier("test-secret-key", WithSeparatorNormalization(""))
+
+	testCases := []struct {
+		value    string
+		dataType DataType
+		hyphens  string
+		bare     string
+	}{
+		{"123-45-6789", TypeSSN, `^\d{3}-\d{2}-\d{4}$`, `^\d{9}$`},
+		{"123 45 6789", TypeSSN, `^\d{3}-\d{2}-\d{4}$`, `^\d{9}$`},
+		{"(555) 123-4567", TypePhone, `^\d{3}-\d{3}-\d{4}$`, `^\d{10}$`},
+		{"555.123.4567", TypePhone, `^\d{3}-\d{3}-\d{4}$`, `^\d{10}$`},
+		{"+1 555 123 4567", TypePhone, `^\+1-\d{3}-\d{3}-\d{4}$`, `^\+1\d{10}$`},
+		{"4532 1234 5678 9012", TypeCreditCard, `^\d{4}-\d{4}-\d{4}-\d{4}$`, `^\d{16}$`},
+		{"4532.1234.5678.9012", TypeCreditCard, `^\d{4}-\d{4}-\d{4}-\d{4}$`, `^\d{16}$`},
+	}
+	for _, tc := range testCases {
+		withHyphens, err := hyphens.Deidentify(tc.value, tc.dataType, "value")
+		if err != nil {
+			t.Fatalf("Deidentify(%q) failed: %v", tc.value, err)
+		}
+		if !regexp.MustCompile(tc.hyphens).MatchString(withHyphens) {
+			t.Errorf("Expected %q to get hyphens, got %q", tc.value, withHyphens)
+		}
+		withoutSeparators, _ := bare.Deidentify(tc.value, tc.dataType, "value")
+		if !regexp.MustCompile(tc.bare).MatchString(withoutSeparators) {
+			t.Errorf("Expected %q to get no separators, got %q", tc.value, withoutSeparators)
+		}
+	}
+
+	// Text uses the same separator, and by default formats still follow the input
+	if result, _ := hyphens.Text("Card 4532.1234.5678.9012 was charged"); !regexp.MustCompile(`Card \d{4}-\d{4}-\d{4}-\d{4} was`).MatchString(result) {
+		t.Errorf("Expected a hyphenated card in text, got %q", result)
+	}
+	if phone, _ := NewDeidentifier("test-secret-key").Phone("555.123.4567"); !regexp.MustCompile(`^\d{3}\.\d{3}\.\d{4}$`).MatchString(phone) {
+		t.Errorf("Expected the default to keep dots, got %q", phone)
+	}
+}
+
+func TestCreditCardContext(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithCreditCardContext())
+
+	testCases := []struct {
+		input    string
+		redacted bool
+	}{
+		{"Paid with Visa card 4111 1111 1111 1111 yesterday", true},
+		{"Card number: 4111-1111-1111-1111", true},
+		{"order 4111 1111 1111 1111 units", false},
+		{"Paid for order 4111 1111 1111 1111", false},
+		{"ISBN 9780 3064 0615 7123", false},
+	}
+
+	for _, tc := range testCases {
+		result, err := d.Text(tc.input)
+		if err != nil {
+			t.Fatalf("Text() error = %v", err)
+		}
+		if changed := result != tc.input; changed != tc.redacted {
+			t.Errorf("%q: expected redacted=%v, got %q", tc.input, tc.redacted, result)
+		}
+	}
+
+	// Without the option, card-shaped numbers are always redacted
+	result, err := NewDeidentifier("test-secret-key").Text("order 4111 1111 1111 1111 units")
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if strings.Contains(result, "4111 1111 1111 1111") {
+		t.Errorf("Default detection should redact the number, got %q", result)
+	}
+}
+
 func TestTableDeidentification(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
 
@@ -264,12 +883,586 @@ func TestReferentialIntegrity(t *testing.T) {
 
 }
 
-func TestSecretKeyGeneration(t *testing.T) {
-	key1, err1 := GenerateSecretKey()
-	key2, err2 := GenerateSecretKey()
+// recordingStore is a MappingStore that counts writes, standing in for a shared store
+type recordingStore struct {
+	mutex  sync.Mutex
+	values map[string]string
+	sets   int
+}
 
-	if err1 != nil || err2 != nil {
-		t.Fatal("Error generating secret keys")
+func (s *recordingStore) Get(column, original string) (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	v, ok := s.values[column+"\x00"+original]
+	return v, ok
+}
+
+func (s *recordingStore) Set(column, original, replacement string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.values[column+"\x00"+original] = replacement
+	s.sets++
+}
+
+func TestCustomMappingStore(t *testing.T) {
+	store := &recordingStore{values: make(map[string]string)}
+	d := NewDeidentifier("test-secret-key", WithMappingStore(store))
+
+	first, err := d.Email("john@example.com")
+	if err != nil {
+		t.Fatalf("Email failed: %v", err)
+	}
+	if _, err := d.Email("john@example.com"); err != nil {
+		t.Fatalf("Email failed: %v", err)
+	}
+	if store.sets != 1 {
+		t.Errorf("Expected one write to the custom store, got %d", store.sets)
+	}
+
+	// A second worker with a different key still reuses the shared mapping
+	worker := NewDeidentifier("another-key", WithMappingStore(store))
+	shared, err := worker.Email("john@example.com")
+	if err != nil {
+		t.Fatalf("Email failed: %v", err)
+	}
+	if shared != first {
+		t.Errorf("Workers sharing a store should agree, got %s and %s", first, shared)
+	}
+}
+
+func TestColumnGroups(t *testing.T) {
+	store := &recordingStore{values: make(map[string]string)}
+	d := NewDeidentifier("test-secret-key",
+		WithMappingStore(store),
+		WithColumnGroups(map[string][]string{"people": {"email", "manager_email"}}),
+	)
+
+	table := &Table{
+		Columns: []Column{
+			{Name: "email", DataType: TypeEmail, Values: []interface{}{"gandalf@istari.org"}},
+			{Name: "manager_email", DataType: TypeEmail, Values: []interface{}{"gandalf@istari.org"}},
+			{Name: "backup_email", DataType: TypeEmail, Values: []interface{}{"gandalf@istari.org"}},
+		},
+	}
+
+	result, err := d.Table(table)
+	if err != nil {
+		t.Fatalf("Table failed: %v", err)
+	}
+	if result.Columns[0].Values[0] != result.Columns[1].Values[0] {
+		t.Errorf("Grouped columns should share replacements, got %v and %v",
+			result.Columns[0].Values[0], result.Columns[1].Values[0])
+	}
+
+	// One write for the group, one for the ungrouped column
+	if store.sets != 2 {
+		t.Errorf("Expected grouped columns to share one mapping entry, got %d writes", store.sets)
+	}
+	if _, ok := store.Get("group:people", "gandalf@istari.org"); !ok {
+		t.Error("Expected the mapping to be stored under the group namespace")
+	}
+}
+
+func TestInvalidSSNRange(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithInvalidSSNRange())
+	ssnFormat := regexp.MustCompile(`^9\d{2}-\d{2}-\d{4}$`)
+
+	for i := 0; i < 1000; i++ {
+		result, err := d.SSN(fmt.Sprintf("123-45-%04d", i))
+		if err != nil {
+			t.Fatalf("SSN failed: %v", err)
+		}
+		if !ssnFormat.MatchString(result) {
+			t.Errorf("Expected an SSN in the 900-999 area, got %s", result)
+		}
+		// Groups of 50 and up may be real ITINs, and every fake is recognized as one
+		if group, _ := strconv.Atoi(result[4:6]); group < 1 || group > 49 {
+			t.Errorf("Expected a group below the ITIN range, got %s", result)
+		}
+		if !d.isAlreadyFake(result, TypeSSN) {
+			t.Errorf("Expected %s to be recognized as already fake", result)
+		}
+	}
+
+	// The default stays out of the invalid range
+	result, _ := NewDeidentifier("test-secret-key").SSN("123-45-6789")
+	if strings.HasPrefix(result, "9") {
+		t.Errorf("Default SSNs should avoid the 900-999 area, got %s", result)
+	}
+}
+
+func TestErrorPolicy(t *testing.T) {
+	failure := errors.New("mapping unavailable")
+	tests := []struct {
+		name    string
+		policy  ErrorPolicy
+		want    string
+		wantErr bool
+	}{
+		{"default inlines token", InlineToken, "[EMAIL REDACTION ERROR]", false},
+		{"keep original", KeepOriginal, "frodo@shire.me", false},
+		{"fail fast", FailFast, "frodo@shire.me", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDeidentifier("test-secret-key", WithErrorPolicy(tt.policy))
+			run := &textRun{}
+			got := d.redactionError(run, "frodo@shire.me", "[EMAIL REDACTION ERROR]", failure)
+			if got != tt.want {
+				t.Errorf("redactionError() = %q, want %q", got, tt.want)
+			}
+			if (run.err != nil) != tt.wantErr {
+				t.Errorf("recorded error = %v, wantErr %v", run.err, tt.wantErr)
+			}
+		})
+	}
+
+	if NewDeidentifier("test-secret-key").errorPolicy != InlineToken {
+		t.Error("InlineToken should be the default policy")
+	}
+
+	// End to end through Text, with a custom age pattern whose matches cannot be parsed
+	text := "Patient record: age unknown, email frodo@shire.me"
+	for _, tt := range tests {
+		t.Run(tt.name+" in Text", func(t *testing.T) {
+			d := NewDeidentifier("test-secret-key", WithErrorPolicy(tt.policy))
+			d.RegisterPattern("age", regexp.MustCompile(`\bage unknown\b`), TypeAge)
+			fake, _ := d.Email("frodo@shire.me")
+
+			result, err := d.Text(text)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Text() = %q, want an error", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Text() error = %v", err)
+			}
+			span := "age unknown"
+			if tt.policy == InlineToken {
+				span = "[CUSTOM REDACTION ERROR]"
+			}
+			if want := "Patient record: " + span + ", email " + fake; result != want {
+				t.Errorf("Text() = %q, want %q", result, want)
+			}
+		})
+	}
+}
+
+func TestCollisionReport(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	if report := d.CollisionReport(); len(report) != 0 {
+		t.Errorf("Expected an empty report before any mappings, got %v", report)
+	}
+
+	// Far more distinct names than the first x last name pool can keep apart
+	for i := 0; i < 20000; i++ {
+		if _, err := d.Name(fmt.Sprintf("Person%d Example", i)); err != nil {
+			t.Fatalf("Name failed: %v", err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := d.SSN(fmt.Sprintf("123-45-%04d", i)); err != nil {
+			t.Fatalf("SSN failed: %v", err)
+		}
+	}
+
+	report := d.CollisionReport()
+	if rate := report[TypeName]; rate <= 0 || rate >= 1 {
+		t.Errorf("Expected a partial name collision rate, got %v", rate)
+	}
+	if rate, ok := report[TypeSSN]; !ok || rate != 0 {
+		t.Errorf("Expected no SSN collisions for 10 values, got %v (present %v)", rate, ok)
+	}
+
+	custom := NewDeidentifier("test-secret-key", WithMappingStore(&recordingStore{values: make(map[string]string)}))
+	if report := custom.CollisionReport(); report != nil {
+		t.Errorf("Expected nil for a store that cannot be enumerated, got %v", report)
+	}
+}
+
+func TestMetricsHook(t *testing.T) {
+	var mu sync.Mutex
+	calls := make(map[DataType]int)
+	d := NewDeidentifier("test-secret-key", WithMetrics(func(dataType DataType, dur time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if dur < 0 {
+			t.Errorf("negative duration reported for type %d", dataType)
+		}
+		calls[dataType]++
+	}))
+
+	_, _ = d.Email("john@company.com")
+	_, _ = d.Email("john@company.com") // answered from the mapping store
+	_, _ = d.SSN("123-45-6789")
+	_, _ = d.Deidentify("status", TypeGeneric, "status")
+
+	if calls[TypeEmail] != 1 || calls[TypeSSN] != 1 {
+		t.Errorf("expected one generation per new value, got %v", calls)
+	}
+	if _, reported := calls[TypeGeneric]; reported {
+		t.Error("generic values generate nothing and should not be reported")
+	}
+}
+
+func TestLastRunMappings(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	fakeEmail, _ := d.Email("john@company.com")
+	fakeSSN, _ := d.SSN("123-45-6789")
+
+	forward, reverse := d.LastRunMappings()
+	if got := forward["email"]["john@company.com"]; got != fakeEmail {
+		t.Errorf("forward email mapping: expected %q, got %q", fakeEmail, got)
+	}
+	if got := reverse["email"][fakeEmail]; got != "john@company.com" {
+		t.Errorf("reverse email mapping: expected the original, got %q", got)
+	}
+	if got := reverse["ssn"][fakeSSN]; got != "123-45-6789" {
+		t.Errorf("reverse SSN mapping: expected the original, got %q", got)
+	}
+
+	// The returned maps are copies; changing them leaves the Deidentifier alone
+	forward["email"]["john@company.com"] = "tampered"
+	if again, _ := d.Email("john@company.com"); again != fakeEmail {
+		t.Errorf("mutating the returned map changed the mapping: got %q", again)
+	}
+
+	custom := NewDeidentifier("test-secret-key", WithMappingStore(&recordingStore{values: make(map[string]string)}))
+	if forward, reverse := custom.LastRunMappings(); forward != nil || reverse != nil {
+		t.Errorf("Expected nil maps for a store that cannot be enumerated")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	old := NewDeidentifier("old-key")
+	if _, err := old.Text("Jane Smith wrote from jane@company.com, phone 555-123-4567"); err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	if _, err := old.Slices([][]string{{"Frodo Baggins", "123-45-6789"}}, []DataType{TypeName, TypeSSN}, []string{"customer", "tax_id"}); err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	stored, _ := old.LastRunMappings()
+
+	// The source is gone: a fresh Deidentifier rekeys the stored table alone
+	rekeyed, err := NewDeidentifier("old-key").Rekey("new-key", stored)
+	if err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	updated := NewDeidentifier("new-key")
+	for _, tc := range []struct {
+		column, original string
+		dataType         DataType
+	}{
+		{"name", "Jane Smith", TypeName},
+		{"email", "jane@company.com", TypeEmail},
+		{"phone", "555-123-4567", TypePhone},
+		{"customer", "Frodo Baggins", TypeName},
+		{"tax_id", "123-45-6789", TypeSSN},
+	} {
+		expected, _ := updated.Deidentify(tc.original, tc.dataType, tc.column)
+		if got := rekeyed[tc.column][tc.original]; got != expected || got == stored[tc.column][tc.original] {
+			t.Errorf("Expected %s in %s to map to %q under the new key, got %q (old %q)", tc.original, tc.column, expected, got, stored[tc.column][tc.original])
+		}
+	}
+	if len(rekeyed) != len(stored) {
+		t.Errorf("Expected %d rekeyed columns, got %d", len(stored), len(rekeyed))
+	}
+
+	// Unknown columns whose type cannot be inferred, and an empty key, are errors
+	if _, err := old.Rekey("new-key", map[string]map[string]string{"notes": {"lorem ipsum": "DATA_1"}}); err == nil {
+		t.Error("Expected an error for a column of unknown type")
+	}
+	if _, err := old.Rekey("", stored); err == nil {
+		t.Error("Expected an error for an empty key")
+	}
+}
+
+func TestSkipAlreadyFake(t *testing.T) {
+	first := NewDeidentifier("first-key", WithInvalidSSNRange())
+	fakeEmail, _ := first.Email("john@company.com")
+	fakeCard, _ := first.CreditCard("4111 1111 1111 1111")
+	fakeSSN, _ := first.SSN("123-45-6789")
+
+	d := NewDeidentifier("second-key", WithSkipAlreadyFake(), WithInvalidSSNRange())
+	for _, tc := range []struct {
+		value    string
+		dataType DataType
+	}{
+		{fakeEmail, TypeEmail},
+		{fakeSSN, TypeSSN},
+	} {
+		got, err := d.Deidentify(tc.value, tc.dataType, "column")
+		if err != nil {
+			t.Fatalf("Deidentify(%q) error = %v", tc.value, err)
+		}
+		if got != tc.value {
+			t.Errorf("already fake %q should be kept, got %q", tc.value, got)
+		}
+	}
+
+	text := "Reach " + fakeEmail + " today"
+	if got, _ := d.Text(text); got != text {
+		t.Errorf("Text should keep already fake values, got %q", got)
+	}
+
+	// Real ITINs and test-range cards may belong to real people, and 9xx SSNs are only
+	// fakes when the generator emits them
+	for _, tc := range []struct {
+		value    string
+		dataType DataType
+		d        *Deidentifier
+	}{
+		{"912-78-1234", TypeSSN, d},
+		{fakeSSN, TypeSSN, NewDeidentifier("second-key", WithSkipAlreadyFake())},
+		{fakeCard, TypeCreditCard, d},
+		{"4000 0012 3456 7899", TypeCreditCard, d},
+	} {
+		if got, _ := tc.d.Deidentify(tc.value, tc.dataType, "column"); got == tc.value {
+			t.Errorf("%q should be replaced, got %q", tc.value, got)
+		}
+	}
+
+	// Real values are still replaced, and without the option fakes are processed again
+	if got, _ := d.Email("john@company.com"); got == "john@company.com" {
+		t.Error("real email on a placeholder domain should still be replaced")
+	}
+	if got, _ := NewDeidentifier("second-key").Email(fakeEmail); got == fakeEmail {
+		t.Error("without WithSkipAlreadyFake the fake email should be replaced again")
+	}
+}
+
+func TestLoadReplacementPools(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile failed: %v", err)
+		}
+		return path
+	}
+	firstPath := write("first.txt", "Zephyrine\n\n")
+	lastPath := write("last.txt", "Quillfeather\n")
+	streetPath := write("streets.txt", "Lantern Row\n")
+	domainPath := write("domains.txt", "qa.invalid\n")
+	emptyPath := write("empty.txt", "\n  \n")
+
+	d := NewDeidentifier("test-secret-key")
+	if err := d.LoadNamePools(firstPath, lastPath); err != nil {
+		t.Fatalf("LoadNamePools failed: %v", err)
+	}
+	if err := d.LoadStreetPool(streetPath); err != nil {
+		t.Fatalf("LoadStreetPool failed: %v", err)
+	}
+	if err := d.LoadDomainPool(domainPath); err != nil {
+		t.Fatalf("LoadDomainPool failed: %v", err)
+	}
+
+	if got, _ := d.Name("John Doe"); got != "Zephyrine Quillfeather" {
+		t.Errorf("expected a name from the loaded pools, got %q", got)
+	}
+	if got, _ := d.Address("123 Main Street"); !strings.HasSuffix(got, " Lantern Row") {
+		t.Errorf("expected a street from the loaded pool, got %q", got)
+	}
+	if got, _ := d.Email("john@company.com"); !strings.HasSuffix(got, "@qa.invalid") {
+		t.Errorf("expected a domain from the loaded pool, got %q", got)
+	}
+
+	// Empty or missing files report an error and fall back to the built-in pools
+	builtin := NewDeidentifier("test-secret-key")
+	want, _ := builtin.Name("Jane Smith")
+	if err := d.LoadNamePools(emptyPath, lastPath); err == nil {
+		t.Error("expected an error for an empty pool file")
+	}
+	if err := d.LoadStreetPool(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("expected an error for a missing pool file")
+	}
+	if got, _ := d.Name("Jane Smith"); got != want {
+		t.Errorf("expected the built-in pools after a failed load, got %q want %q", got, want)
+	}
+}
+
+func TestTextStripsInvisibleCharacters(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	cases := []struct {
+		text     string
+		original string
+	}{
+		{"Mail joe\u200b@company.com today", "joe@company.com"},
+		{"SSN: 123-\u200d45-6789", "123-45-6789"},
+		{"Card 4111\u2060 1111 1111 1111", "4111 1111 1111 1111"},
+		{"Call (555)\ufeff 123-4567", "(555) 123-4567"},
+		{"SSN: 123-45\x00-6789", "123-45-6789"},
+	}
+	for _, tc := range cases {
+		result, err := d.Text(tc.text)
+		if err != nil {
+			t.Fatalf("Text(%q) error = %v", tc.text, err)
+		}
+		if strings.Contains(d.stripInvisible(result), tc.original) {
+			t.Errorf("zero-width laced PII should still be redacted in %q, got %q", tc.text, result)
+		}
+	}
+
+	// Text without PII keeps its invisible characters, such as emoji joiners
+	passthrough := "Great job \U0001F469\u200d\U0001F4BB team!"
+	if result, _ := d.Text(passthrough); result != passthrough {
+		t.Errorf("passthrough text should be unchanged, got %q", result)
+	}
+
+	// Invisible characters outside the replaced values are kept, such as an emoji joiner
+	// or bidi mark next to an email
+	fake, _ := d.Email("joe@company.com")
+	mixed := "Thanks \U0001F469\u200d\U0001F4BB \u200ejoe@company.com\u200f!"
+	if result, _ := d.Text(mixed); result != "Thanks \U0001F469\u200d\U0001F4BB \u200e"+fake+"\u200f!" {
+		t.Errorf("invisible characters outside the email should survive, got %q", result)
+	}
+	laced, _ := d.Text("\U0001F469\u200d\U0001F4BB mail joe\u200b@company.com")
+	if laced != "\U0001F469\u200d\U0001F4BB mail "+fake {
+		t.Errorf("expected the joiner kept and the zero-width space dropped with the email, got %q", laced)
+	}
+
+	// A stripped character next to a replaced value is dropped with it, wherever the
+	// pattern ended up matching
+	phone, _ := d.Text("Call (555)\ufeff 123-4567 now")
+	if want, _ := d.Text("Call (555) 123-4567 now"); phone != want {
+		t.Errorf("expected the byte order mark dropped with the phone number, got %q, want %q", phone, want)
+	}
+}
+
+func TestCaseInsensitiveMapping(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithCaseInsensitiveMapping())
+	variants := []string{"test@x.com", "Test@X.com", "TEST@X.COM", "tEsT@x.Com"}
+
+	want, err := d.Email(variants[0])
+	if err != nil {
+		t.Fatalf("Email failed: %v", err)
+	}
+	for _, variant := range variants[1:] {
+		if got, _ := d.Email(variant); got != want {
+			t.Errorf("Email(%q) = %q, want %q", variant, got, want)
+		}
+	}
+	if got, _ := d.Text("Write to Test@X.com"); got != "Write to "+want {
+		t.Errorf("Text() = %q, want the shared replacement", got)
+	}
+
+	// Only the listed types are affected
+	names := NewDeidentifier("test-secret-key", WithCaseInsensitiveMapping(TypeName))
+	lower, _ := names.Email("test@x.com")
+	mixed, _ := names.Email("Test@X.com")
+	if lower == mixed {
+		t.Error("Emails should stay case-sensitive when only TypeName is listed")
+	}
+	plain, _ := names.Name("John Smith")
+	upper, _ := names.Name("JOHN SMITH")
+	if plain != upper {
+		t.Errorf("Names should map case-insensitively when listed, got %q and %q", plain, upper)
+	}
+}
+
+func TestMaxStreetNumber(t *testing.T) {
+	numberRegex := regexp.MustCompile(`^(\d+) `)
+	streetNumber := func(d *Deidentifier, address string) int {
+		t.Helper()
+		result, err := d.Address(address)
+		if err != nil {
+			t.Fatalf("Address failed: %v", err)
+		}
+		match := numberRegex.FindStringSubmatch(result)
+		if match == nil {
+			t.Fatalf("Expected a leading street number, got %s", result)
+		}
+		number, _ := strconv.Atoi(match[1])
+		return number
+	}
+
+	limited := NewDeidentifier("test-secret-key", WithMaxStreetNumber(50))
+	for i := 0; i < 200; i++ {
+		if n := streetNumber(limited, fmt.Sprintf("%d Bagshot Row", i+1)); n < 1 || n > 50 {
+			t.Errorf("Street number %d outside 1-50", n)
+		}
+	}
+
+	// Invalid limits keep the default range
+	fallback := NewDeidentifier("test-secret-key", WithMaxStreetNumber(0))
+	if fallback.maxStreetNumber != defaultMaxStreetNumber {
+		t.Errorf("Expected the default limit, got %d", fallback.maxStreetNumber)
+	}
+}
+
+func TestEmailDomainAllowlist(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithEmailDomainAllowlist([]string{"Example.com"}))
+
+	for _, internal := range []string{"jane@example.com", "ops@mail.EXAMPLE.com"} {
+		if got, _ := d.Email(internal); got != internal {
+			t.Errorf("Email(%q) = %q, allowlisted emails should pass through", internal, got)
+		}
+	}
+	for _, external := range []string{"jane@notexample.com", "jane@example.com.evil.io"} {
+		if got, _ := d.Email(external); got == external {
+			t.Errorf("Email(%q) should be replaced", external)
+		}
+	}
+
+	result, _ := d.Text("Escalate from customer@gmail.com to support@example.com")
+	if strings.Contains(result, "customer@gmail.com") || !strings.Contains(result, "support@example.com") {
+		t.Errorf("Expected only the external email replaced, got %q", result)
+	}
+}
+
+func TestReplacementNeverEqualsOriginal(t *testing.T) {
+	// With one street number the address pool is small enough to find an input whose
+	// first-choice replacement is itself
+	for k := 0; k < 500; k++ {
+		d := NewDeidentifier(fmt.Sprintf("key-%d", k), WithMaxStreetNumber(1))
+		for _, street := range streetNameOptions {
+			original := "1 " + street
+			if d.generateValue(original, TypeAddress, "address") != original {
+				continue
+			}
+
+			result, err := d.Deidentify(original, TypeAddress, "address")
+			if err != nil {
+				t.Fatalf("Deidentify failed: %v", err)
+			}
+			if result == original {
+				t.Errorf("Collision guard did not kick in for %q", original)
+			}
+			if again, _ := NewDeidentifier(fmt.Sprintf("key-%d", k), WithMaxStreetNumber(1)).Deidentify(original, TypeAddress, "address"); again != result {
+				t.Errorf("Alternate should be deterministic, got %q and %q", result, again)
+			}
+			return
+		}
+	}
+	t.Fatal("No colliding input found")
+}
+
+func TestClearMappings(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	if _, err := d.Email("john@example.com"); err != nil {
+		t.Fatalf("Email failed: %v", err)
+	}
+	if d.getMapping("email", "john@example.com") == "" {
+		t.Fatal("Expected mapping to be stored")
+	}
+
+	d.ClearMappings()
+	if d.getMapping("email", "john@example.com") != "" {
+		t.Error("ClearMappings should remove stored mappings")
+	}
+}
+
+func TestSecretKeyGeneration(t *testing.T) {
+	key1, err1 := GenerateSecretKey()
+	key2, err2 := GenerateSecretKey()
+
+	if err1 != nil || err2 != nil {
+		t.Fatal("Error generating secret keys")
 	}
 
 	if key1 == key2 {
@@ -281,6 +1474,34 @@ func TestSecretKeyGeneration(t *testing.T) {
 	}
 }
 
+func TestNewDeidentifierStrict(t *testing.T) {
+	key, _ := GenerateSecretKey()
+	cases := []struct {
+		key     string
+		wantErr bool
+	}{
+		{key, false},
+		{"a passphrase that is thirty-two bytes or longer", false},
+		{"abc", true},
+		{"", true},
+		{key[:32], true}, // hex, so only 16 bytes of material
+	}
+	for _, c := range cases {
+		d, err := NewDeidentifierStrict(c.key)
+		if (err != nil) != c.wantErr {
+			t.Errorf("NewDeidentifierStrict(%q) error = %v, wantErr %v", c.key, err, c.wantErr)
+		}
+		if err == nil && d == nil {
+			t.Errorf("NewDeidentifierStrict(%q) returned no deidentifier", c.key)
+		}
+	}
+
+	d, _ := NewDeidentifierStrict(key, WithTokenLength(8))
+	if got, want := d.Tokenize("value", "col"), NewDeidentifier(key, WithTokenLength(8)).Tokenize("value", "col"); got != want {
+		t.Errorf("Expected the same results as NewDeidentifier, got %q and %q", got, want)
+	}
+}
+
 func TestText(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
 
@@ -372,299 +1593,2850 @@ func TestText(t *testing.T) {
 	}
 }
 
-func TestConvenienceMethods(t *testing.T) {
+func TestEmailPunctuationBoundaries(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
 
-	// Test Email
-	email := "test@example.com"
-	emailResult, err := d.Email(email)
-	if err != nil {
-		t.Fatalf("Email failed: %v", err)
-	}
-	if emailResult == email {
-		t.Errorf("Email should produce different result, got: %s", emailResult)
+	testCases := []struct {
+		input   string
+		pattern string
+	}{
+		{"<joe@x.com>", `^<[a-z0-9-]+@[a-z0-9.]+\.[a-z]{2,}>$`},
+		{"joe@x.com.", `^[a-z0-9-]+@[a-z0-9.]+\.[a-z]{2,}\.$`},
+		{"(joe@x.com)", `^\([a-z0-9-]+@[a-z0-9.]+\.[a-z]{2,}\)$`},
+		{"email:joe@x.com.", `^email:[a-z0-9-]+@[a-z0-9.]+\.[a-z]{2,}\.$`},
+		{"...joe@x.com", `^\.\.\.[a-z0-9-]+@[a-z0-9.]+\.[a-z]{2,}$`},
 	}
-	if !strings.Contains(emailResult, "@") {
-		t.Errorf("Email result doesn't look like an email: %s", emailResult)
+
+	for _, tc := range testCases {
+		result, err := d.Text(tc.input)
+		if err != nil {
+			t.Fatalf("Text() error = %v", err)
+		}
+		if strings.Contains(result, "joe@x.com") {
+			t.Errorf("Email in %q should be redacted, got %q", tc.input, result)
+		}
+		if matched, _ := regexp.MatchString(tc.pattern, result); !matched {
+			t.Errorf("Surrounding punctuation of %q not preserved, got %q", tc.input, result)
+		}
 	}
+}
 
-	// Test Phone
-	phone := "(555) 123-4567"
-	phoneResult, err := d.Phone(phone)
-	if err != nil {
-		t.Fatalf("Phone failed: %v", err)
+func TestPreserveTLD(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithPreserveTLD())
+
+	testCases := []struct {
+		email string
+		tld   string
+	}{
+		{"joe@harvard.edu", ".edu"},
+		{"jane@mail.bbc.co.uk", ".co.uk"},
+		{"ops@agency.gov", ".gov"},
+		{"kim@example.DE", ".de"},
+		{"lee@co.uk", ".uk"},
 	}
-	if phoneResult == phone {
-		t.Errorf("Phone should produce different result, got: %s", phoneResult)
+	for _, tc := range testCases {
+		result, err := d.Email(tc.email)
+		if err != nil {
+			t.Fatalf("Email failed: %v", err)
+		}
+		local, domain, _ := strings.Cut(result, "@")
+		label, tld, _ := strings.Cut(domain, ".")
+		if "."+tld != tc.tld {
+			t.Errorf("Email(%q) = %q, expected TLD %s", tc.email, result, tc.tld)
+		}
+		if local == "" || label == "" || strings.Contains(result, "harvard") || strings.Contains(result, "bbc") {
+			t.Errorf("Email(%q) = %q, expected a generated local part and domain label", tc.email, result)
+		}
 	}
 
-	// Test SSN
-	ssn := "123-45-6789"
-	ssnResult, err := d.SSN(ssn)
+	// Generated emails are still recognized as already fake
+	fake, _ := d.Email("joe@harvard.edu")
+	skipping := NewDeidentifier("test-secret-key", WithPreserveTLD(), WithSkipAlreadyFake())
+	if again, _ := skipping.Email(fake); again != fake {
+		t.Errorf("Expected %s to be recognized as fake, got %s", fake, again)
+	}
+}
+
+func TestPreservePlusTag(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithPreservePlusTag(), WithPreserveTLD())
+
+	newsletter, err := d.Email("joe+newsletter@harvard.edu")
 	if err != nil {
-		t.Fatalf("SSN failed: %v", err)
+		t.Fatalf("Email failed: %v", err)
 	}
-	if ssnResult == ssn {
-		t.Errorf("SSN should produce different result, got: %s", ssnResult)
+	local, domain, _ := strings.Cut(newsletter, "@")
+	base, tag, _ := strings.Cut(local, "+")
+	if tag != "newsletter" || strings.Contains(base, "joe") || !strings.HasSuffix(domain, ".edu") || strings.Contains(domain, "harvard") {
+		t.Errorf("Expected a generated local part with the tag and TLD kept, got %s", newsletter)
 	}
-	if !regexp.MustCompile(`\d{3}-\d{2}-\d{4}`).MatchString(ssnResult) {
-		t.Errorf("SSN result doesn't match SSN format: %s", ssnResult)
+
+	// Every tag of one mailbox, and the untagged address, share the base
+	untagged, _ := d.Email("joe@harvard.edu")
+	billing, _ := d.Email("joe+billing@harvard.edu")
+	if untagged != base+"@"+domain || billing != base+"+billing@"+domain {
+		t.Errorf("Expected %s and %s to share the base %s", untagged, billing, base)
+	}
+	if result, _ := d.Text("Bounce from joe+newsletter@harvard.edu"); result != "Bounce from "+newsletter {
+		t.Errorf("Expected the tagged email in text to become %s, got %q", newsletter, result)
+	}
+
+	// Tagged fakes are still recognized, and without the option the tag is dropped
+	skipping := NewDeidentifier("test-secret-key", WithPreservePlusTag(), WithPreserveTLD(), WithSkipAlreadyFake())
+	if again, _ := skipping.Email(newsletter); again != newsletter {
+		t.Errorf("Expected %s to be recognized as fake, got %s", newsletter, again)
+	}
+	if plain, _ := NewDeidentifier("test-secret-key").Email("joe+newsletter@harvard.edu"); strings.Contains(plain, "+") {
+		t.Errorf("Expected no tag by default, got %s", plain)
 	}
 }
 
-// Helper function to validate Luhn checksum
-func isValidLuhn(cardNumber string) bool {
-	sum := 0
-	alternate := false
+func TestEmailLists(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	emails := []string{"jane.smith@example.com", "bob@y.org", "c.d@z.co.uk", "ops+alerts@x.io"}
+	fakes := make([]string, len(emails))
+	for i, email := range emails {
+		fakes[i], _ = d.Email(email)
+	}
 
-	for i := len(cardNumber) - 1; i >= 0; i-- {
-		digit := int(cardNumber[i] - '0')
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"To: jane.smith@example.com; bob@y.org, c.d@z.co.uk",
+			"To: " + fakes[0] + "; " + fakes[1] + ", " + fakes[2],
+		},
+		{
+			"Cc: jane.smith@example.com,bob@y.org;c.d@z.co.uk;ops+alerts@x.io.",
+			"Cc: " + fakes[0] + "," + fakes[1] + ";" + fakes[2] + ";" + fakes[3] + ".",
+		},
+		{
+			"To: <jane.smith@example.com>, <bob@y.org>;\t<ops+alerts@x.io>",
+			"To: <" + fakes[0] + ">, <" + fakes[1] + ">;\t<" + fakes[3] + ">",
+		},
+	}
 
-		if alternate {
-			digit *= 2
-			if digit > 9 {
-				digit = digit/10 + digit%10
-			}
+	for _, tc := range testCases {
+		result, err := d.Text(tc.input)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if result != tc.expected {
+			t.Errorf("Expected each email replaced with separators kept:\n%q\ngot\n%q", tc.expected, result)
 		}
+	}
+}
 
-		sum += digit
-		alternate = !alternate
+func TestEmailHeaderNames(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	header := "From: Legolas Greenleaf <legolas@mirkwood.elf>\r\n" +
+		"To: \"Baggins, Frodo\" <frodo@shire.me>, Samwise <sam@shire.me>\r\n" +
+		"Cc: GANDALF GREYHAME <gandalf@istari.org>; elrond peredhel <elrond@rivendell.elf>\r\n" +
+		"Subject: The ring\r\n"
+
+	result, err := d.Text(header)
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
 	}
 
-	return sum%10 == 0
+	legolas, _ := d.Name("Legolas Greenleaf")
+	frodo, _ := d.Name("Baggins, Frodo")
+	gandalf, _ := d.Name("Gandalf Greyhame")
+	elrond, _ := d.Name("Elrond Peredhel")
+	for _, expected := range []string{
+		"From: " + legolas + " <",
+		"To: \"" + frodo + "\" <",
+		"Cc: " + strings.ToUpper(gandalf) + " <",
+		"; " + strings.ToLower(elrond) + " <",
+		"Subject: The ring\r\n",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("Expected %q in output, got:\n%s", expected, result)
+		}
+	}
+	for _, name := range []string{"Legolas", "Baggins", "Frodo", "Samwise", "GANDALF", "elrond", "mirkwood"} {
+		if strings.Contains(result, name) {
+			t.Errorf("Expected %q to be replaced, got:\n%s", name, result)
+		}
+	}
+
+	// Outside header lines, "to:" is not a cue
+	if result, _ := d.Text("Meet at five to: Samwise"); result != "Meet at five to: Samwise" {
+		t.Errorf("Expected prose to be kept, got %q", result)
+	}
 }
 
-func BenchmarkEmailGeneration(b *testing.B) {
-	d := NewDeidentifier("benchmark-key")
+func TestInlineMailboxNames(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	aragorn, _ := d.Name("Aragorn Elessar")
+	arwen, _ := d.Name("Evenstar, Arwen")
+	strider, _ := d.Email("strider@gondor.me")
+	evenstar, _ := d.Email("arwen@rivendell.elf")
+	boromir, _ := d.Name("Boromir Denethorson")
+	con, _ := d.Name("Con Smith")
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		d.generateEmail("test@example.com")
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"Please contact Aragorn Elessar <strider@gondor.me> today.", "Please contact " + aragorn + " <" + strider + "> today."},
+		{"Contact Aragorn Elessar <strider@gondor.me>", "Contact " + aragorn + " <" + strider + ">"},
+		{"cc \"Evenstar, Arwen\" <arwen@rivendell.elf>", "cc \"" + arwen + "\" <" + evenstar + ">"},
+		{"Ask BOROMIR DENETHORSON <strider@gondor.me>", "Ask " + strings.ToUpper(boromir) + " <" + strider + ">"},
+		// A cue word alone is not a display name
+		{"Email <strider@gondor.me>", "Email <" + strider + ">"},
+		// A name word inside the cue word does not swallow the cue
+		{"Contact Con Smith <strider@gondor.me>", "Contact " + con + " <" + strider + ">"},
+		// Role and service mailboxes are not people
+		{"email Support <strider@gondor.me>", "email Support <" + strider + ">"},
+		{"Terms of Service <strider@gondor.me>", "Terms of Service <" + strider + ">"},
+	}
+	for _, tc := range testCases {
+		result, err := d.Text(tc.input)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if result != tc.expected {
+			t.Errorf("Text(%q): expected %q, got %q", tc.input, tc.expected, result)
+		}
+	}
+
+	// A single display name word is a given name
+	result, _ := d.Text("Ping Legolas <legolas@mirkwood.elf> later")
+	if strings.Contains(result, "Legolas") {
+		t.Errorf("Expected the display name to be replaced, got %q", result)
 	}
 }
 
-func BenchmarkTableDeidentification(b *testing.B) {
-	d := NewDeidentifier("benchmark-key")
+func TestOutputCase(t *testing.T) {
+	lower := NewDeidentifier("test-secret-key")
+	email, _ := lower.Email("Jane.Smith@Example.com")
+	handle, _ := lower.Username("@JaneSmith")
+	if email != strings.ToLower(email) || handle != strings.ToLower(handle) {
+		t.Errorf("Expected lowercase output by default, got %s and %s", email, handle)
+	}
 
-	table := &Table{
-		Columns: []Column{
-			{Name: "name", DataType: TypeName, Values: make([]interface{}, 1000)},
-			{Name: "email", DataType: TypeEmail, Values: make([]interface{}, 1000)},
-		},
+	upper := NewDeidentifier("test-secret-key", WithOutputCase(Upper))
+	if result, _ := upper.Email("Jane.Smith@Example.com"); result != strings.ToUpper(email) {
+		t.Errorf("Expected %s, got %s", strings.ToUpper(email), result)
+	}
+	if result, _ := upper.Text("Ping @JaneSmith"); result != "Ping "+strings.ToUpper(handle) {
+		t.Errorf("Expected an uppercase handle in text, got %q", result)
 	}
 
-	// Fill with test data
-	for i := 0; i < 1000; i++ {
-		table.Columns[0].Values[i] = "John Doe"
-		table.Columns[1].Values[i] = "john@company.com"
+	// With case-insensitive mapping every spelling shares one replacement, cased per input
+	email, _ = NewDeidentifier("test-secret-key", WithCaseInsensitiveMapping(TypeEmail)).Email("jane.smith@example.com")
+	preserve := NewDeidentifier("test-secret-key", WithOutputCase(Preserve), WithCaseInsensitiveMapping(TypeEmail))
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{"JANE.SMITH@EXAMPLE.COM", strings.ToUpper(email)},
+		{"Jane.Smith@Example.com", strings.ToUpper(email[:1]) + email[1:]},
+		{"jane.smith@example.com", email},
+	}
+	for _, tc := range testCases {
+		if result, _ := preserve.Email(tc.input); result != tc.expected {
+			t.Errorf("Email(%q): expected %s, got %s", tc.input, tc.expected, result)
+		}
+	}
+	if result, _ := preserve.Username("@JaneSmith"); result != "@"+strings.ToUpper(handle[1:2])+handle[2:] {
+		t.Errorf("Expected a capitalized handle, got %s", result)
 	}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := d.Table(table)
+	// Mappings keep the lowercase replacement whatever the output case
+	forward, _ := preserve.LastRunMappings()
+	if mapped := forward["email"]["jane.smith@example.com"]; mapped != email {
+		t.Errorf("Expected the mapping to hold %s, got %s", email, mapped)
+	}
+}
+
+func TestUsernameDeidentification(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	result, err := d.Username("@frodo_b")
+	if err != nil {
+		t.Fatalf("Username failed: %v", err)
+	}
+	if !regexp.MustCompile(`^@[a-z]+_\d+$`).MatchString(result) {
+		t.Errorf("Username should keep the leading @, got %s", result)
+	}
+	if result == "@frodo_b" {
+		t.Error("Username should be anonymized")
+	}
+
+	input := "Ticket from @frodo_b (frodo@shire.me), cc @samwise"
+	text, err := d.Text(input)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if strings.Contains(text, "@frodo_b") || strings.Contains(text, "@samwise") {
+		t.Errorf("Handles should be redacted, got %s", text)
+	}
+	if !strings.Contains(text, "Ticket from "+result+" (") {
+		t.Errorf("Handle in text should match Username(), got %s", text)
+	}
+
+	// The email survives as a single well-formed address
+	emailRegex := regexp.MustCompile(`\(([^()]+)\)`)
+	email := emailRegex.FindStringSubmatch(text)
+	if email == nil || !regexp.MustCompile(`^[a-z0-9-]+@[a-z0-9.]+\.[a-z]{2,}$`).MatchString(email[1]) {
+		t.Errorf("Email should be deidentified as an email, got %s", text)
+	}
+}
+
+func TestNameWordCount(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithNameWordCount())
+	plain := NewDeidentifier("test-secret-key")
+
+	for input, words := range map[string]int{"Madonna": 1, "John Smith": 2, "Mary Jane Watson": 3} {
+		got, err := d.Name(input)
 		if err != nil {
-			b.Fatalf("Table failed: %v", err)
+			t.Fatalf("Name(%q) error = %v", input, err)
+		}
+		if n := len(strings.Fields(got)); n != words {
+			t.Errorf("Name(%q) = %q, want %d words", input, got, words)
+		}
+		if got == input {
+			t.Errorf("Name(%q) was not replaced", input)
+		}
+		if again, _ := d.Name(input); again != got {
+			t.Errorf("Expected a deterministic replacement for %q, got %q and %q", input, got, again)
 		}
 	}
-}
 
-func BenchmarkTextDeidentification(b *testing.B) {
-	d := NewDeidentifier("benchmark-key")
+	// Two-word names and suffixes are unchanged by the option
+	for _, input := range []string{"John Smith", "Martin King Jr."} {
+		got, _ := d.Name(input)
+		want, _ := plain.Name(input)
+		if got != want {
+			t.Errorf("Name(%q) = %q, want %q as without the option", input, got, want)
+		}
+	}
 
-	text := `Contact John Smith at john.smith@example.com or (555) 123-4567.
-His SSN is 123-45-6789 and he lives at 123 Main Street in New York.
-Please process his payment using credit card 4111-1111-1111-1111.`
+	// Without the option every name gets two words
+	if got, _ := plain.Name("Madonna"); len(strings.Fields(got)) != 2 {
+		t.Errorf("Expected a first and last name by default, got %q", got)
+	}
+}
 
-	b.ResetTimer()
-	for i := 0; i < b.N; i++ {
-		_, err := d.Text(text)
+func TestSWIFT(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	for _, code := range []string{"DEUTDEFF", "DEUTDEFF500", "BOFAUS3NXXX"} {
+		result, err := d.SWIFT(code)
 		if err != nil {
-			b.Fatalf("Text failed: %v", err)
+			t.Fatalf("SWIFT failed: %v", err)
+		}
+		if len(result) != len(code) || result == code {
+			t.Errorf("Expected a different code of length %d for %s, got %s", len(code), code, result)
+		}
+		if !regexp.MustCompile(`^` + swiftRegexPattern + `$`).MatchString(result) {
+			t.Errorf("Result %s is not a SWIFT/BIC code", result)
+		}
+		if result[4:6] != code[4:6] {
+			t.Errorf("Expected country code %s to be kept, got %s", code[4:6], result)
+		}
+		if result[7] == '0' {
+			t.Errorf("Result %s looks like a test BIC", result)
 		}
+		again, _ := d.SWIFT(code)
+		if again != result {
+			t.Errorf("Expected deterministic results, got %s and %s", result, again)
+		}
+	}
+	if result, _ := d.SWIFT("BOFAUS3NXXX"); !strings.HasSuffix(result, "XXX") {
+		t.Errorf("Expected the primary-office branch to be kept, got %s", result)
+	}
+
+	direct, _ := d.SWIFT("DEUTDEFF500")
+	result, err := d.Text("Wire to SWIFT/BIC: DEUTDEFF500 today")
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	if result != "Wire to SWIFT/BIC: "+direct+" today" {
+		t.Errorf("Expected the labeled code to be replaced like SWIFT, got %q", result)
+	}
+
+	// Unlabeled uppercase words are not mistaken for codes
+	text := "The DEUTDEFF file and DOCUMENTS are ready"
+	if result, _ := d.Text(text); result != text {
+		t.Errorf("Expected unlabeled words to be kept, got %q", result)
 	}
 }
 
-func TestSlices(t *testing.T) {
+func TestSalutationNames(t *testing.T) {
 	d := NewDeidentifier("test-secret-key")
+	cases := []struct {
+		input  string
+		prefix string
+		leaked []string
+	}{
+		{"Dear Maria,\nThanks for your order.", "Dear ", []string{"Maria"}},
+		{"Attn: John Smith", "Attn: ", []string{"John", "Smith"}},
+		{"Dear Ms. Patricia Martinez, welcome.", "Dear Ms. ", []string{"Patricia", "Martinez"}},
+		{"ATTN: Watson", "ATTN: ", []string{"Watson"}},
+		{"Attention: Maria Lopez-Garcia", "Attention: ", []string{"Maria", "Lopez"}},
+	}
+	for _, c := range cases {
+		result, err := d.Text(c.input)
+		if err != nil {
+			t.Fatalf("Text(%q) error = %v", c.input, err)
+		}
+		if !strings.HasPrefix(result, c.prefix) {
+			t.Errorf("Expected the cue %q to be kept, got %q", c.prefix, result)
+		}
+		for _, leaked := range c.leaked {
+			if strings.Contains(result, leaked) {
+				t.Errorf("Text(%q) leaked %q: %q", c.input, leaked, result)
+			}
+		}
+	}
 
-	// Test data as [][]string
-	data := [][]string{
-		{"John Doe", "john.doe@example.com", "555-123-4567", "123-45-6789"},
-		{"Jane Smith", "jane.smith@company.org", "(555) 987-6543", "987-65-4321"},
-		{"Bob Johnson", "bob@test.co.uk", "555.111.2222", "456-78-9012"},
-		{"", "", "", ""}, // Test empty values
+	// Known given names keep a single word, while surnames are replaced as names
+	given, _ := d.Text("Dear Maria,")
+	surname, _ := d.Name("Watson")
+	for input, want := range map[string]string{
+		"ATTN: Watson":     "ATTN: " + surname,
+		"Dear Mr. Watson,": "Dear Mr. " + surname + ",",
+	} {
+		if result, _ := d.Text(input); result != want {
+			t.Errorf("Text(%q) = %q, want %q", input, result, want)
+		}
+	}
+	if fields := strings.Fields(given); len(fields) != 2 || given == "Dear Maria," {
+		t.Errorf("Expected a single fake given name, got %q", given)
 	}
 
-	columnTypes := []DataType{TypeName, TypeEmail, TypePhone, TypeSSN}
-	columnNames := []string{"name", "email", "phone", "ssn"}
+	// Roles and groups are not names
+	for _, input := range []string{"Dear Sir or Madam,", "Dear Customer,", "Dear Hiring Manager,", "Dear all,"} {
+		if result, _ := d.Text(input); result != input {
+			t.Errorf("Expected %q to be kept, got %q", input, result)
+		}
+	}
+}
 
-	// Test successful deidentification
-	result, err := d.Slices(data, columnTypes, columnNames)
+func TestSignatureNames(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	email := "Hi team,\n\n" +
+		"The quarterly numbers are attached. Let me know if anything looks off before Friday.\n\n" +
+		"Best regards,\n" +
+		"Thandiwe\n" +
+		"Senior Account Manager\n" +
+		"Acme Logistics\n" +
+		"Tel: +1 (555) 234-5678\n" +
+		"thandiwe@acmelogistics.com\n"
+	result, err := d.Text(email)
 	if err != nil {
-		t.Fatalf("Slices failed: %v", err)
+		t.Fatalf("Text failed: %v", err)
+	}
+	lines := strings.Split(result, "\n")
+	if strings.Contains(result, "Thandiwe") || lines[5] == "" {
+		t.Errorf("Expected the signature name to be replaced, got %q", result)
+	}
+	if lines[4] != "Best regards," || !strings.HasPrefix(lines[8], "Tel: ") {
+		t.Errorf("Expected the sign-off and contact labels to be kept, got %q", result)
 	}
 
-	// Check result dimensions
-	checkSlicesDimensions(t, result, data)
+	// A "--" delimiter opens a signature too, and full names are replaced as names
+	result, _ = d.Text("See you then.\n--\nOksana Vrubel-Kowalczyk\nwww.vrubel.example\n")
+	if strings.Contains(result, "Oksana") || strings.Contains(result, "Kowalczyk") || !strings.HasPrefix(result, "See you then.\n--\n") {
+		t.Errorf("Expected the delimited signature name to be replaced, got %q", result)
+	}
 
-	// Test that non-empty values are deidentified
-	checkNonEmptyValuesDeidentified(t, result, data)
+	// Role lines, lines without contact details or a sign-off, and lines far from the
+	// end are kept
+	for _, text := range []string{
+		"Thanks,\nSupport\nwww.example.com/help",
+		"Cheers,\nZanele\n\nsee attached",
+		"Zanele\nhttps://example.com/plan",
+		"Notes\n\nZanele\nhttps://example.com/plan",
+		"Thanks,\nZanele\nhttps://example.com/plan\n" + strings.Repeat("more notes follow here\n", signatureTailLines),
+	} {
+		if result, _ := d.Text(text); result != text {
+			t.Errorf("Expected %q to be kept, got %q", text, result)
+		}
+	}
 
-	// Test that empty values remain empty
-	checkEmptyValuesRemainEmpty(t, result)
+	// Headings above numbers are not signatures
+	for text, heading := range map[string]string{
+		"Invoice\nOrder 12345678 shipped":           "Invoice\n",
+		"Summary\n\nStatus\nTicket 98765432":        "Summary\n\nStatus\n",
+		"Report\n\nOverview\nCall 555 123 4567 now": "Report\n\nOverview\n",
+	} {
+		if result, _ := d.Text(text); !strings.HasPrefix(result, heading) {
+			t.Errorf("Expected the heading of %q to be kept, got %q", text, result)
+		}
+	}
+}
 
-	// Test deterministic behavior
-	checkDeterministicBehavior(t, d, data, columnTypes, columnNames, result)
+func TestParticleNames(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	for _, name := range []string{"Ludwig van Beethoven", "Vincent van Gogh", "Omar al-Farsi", "Maria de la Cruz", "Leonardo da Vinci", "Jean-Claude van Damme"} {
+		expected, err := d.Name(name)
+		if err != nil {
+			t.Fatalf("Name failed: %v", err)
+		}
+		result, err := d.Text("Notes on " + name + " and " + name + ".")
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if result != "Notes on "+expected+" and "+expected+"." {
+			t.Errorf("Expected %q to be replaced whole by %q, got %q", name, expected, result)
+		}
+	}
 
-	// Test with different column names (should produce different results)
-	checkDifferentColumnNames(t, data, columnTypes, result)
-}
+	// Street names with particles are left to address detection
+	for text, street := range map[string]string{"Meet at 5 Rue de la Paix, Paris": "Paix", "Office at 12 Jan van Galen Street": "Galen"} {
+		result, err := d.Text(text)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if strings.Contains(result, street) || !regexp.MustCompile(`at \d+ `).MatchString(result) {
+			t.Errorf("Expected the address in %q to be replaced as an address, got %q", text, result)
+		}
+	}
 
-func checkSlicesDimensions(t *testing.T, result, data [][]string) {
-	if len(result) != len(data) {
-		t.Errorf("Expected %d rows, got %d", len(data), len(result))
+	// Places with particles are not people
+	for _, place := range []string{"Notre Dame de Paris", "Museo del Prado", "Puerto de Valencia", "Palacio de Cristal", "Port de Soller"} {
+		text := "We visited " + place + " last spring."
+		if result, _ := d.Text(text); result != text {
+			t.Errorf("Expected %q to be kept, got %q", text, result)
+		}
 	}
 
-	for i, row := range result {
-		if len(row) != len(data[i]) {
-			t.Errorf("Row %d: expected %d columns, got %d", i, len(data[i]), len(row))
+	// Surnames that happen to be place words are still names
+	for _, name := range []string{"Maria da Costa", "Juan de la Torre", "Carlos del Rio", "Antonio del Castillo", "Sofia del Valle"} {
+		text := "Patient " + name + " called."
+		result, err := d.Text(text)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if strings.Contains(result, name) || strings.Contains(result, strings.Fields(name)[0]) {
+			t.Errorf("Expected %q to be replaced as a name, got %q", name, result)
 		}
 	}
 }
 
-func checkNonEmptyValuesDeidentified(t *testing.T, result, data [][]string) {
-	if result[0][0] == data[0][0] && data[0][0] != "" {
-		t.Error("Name should be deidentified")
+func TestNicknameNames(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	robert, _ := d.Name("Robert Smith")
+	william, _ := d.Name("William Jones")
+	elizabeth, _ := d.Name("Elizabeth Taylor")
+
+	testCases := []struct {
+		input    string
+		expected string
+	}{
+		{`Robert "Bob" Smith called`, robert + " called"},
+		{`Call Robert 'Bob' Smith today`, "Call " + robert + " today"},
+		{"William (Bill) Jones and William Jones", william + " and " + william},
+		{"Elizabeth “Liz” Taylor", elizabeth},
+		{`Dear Robert "Bob" Smith,`, "Dear " + robert + ","},
 	}
-	if result[0][1] == data[0][1] && data[0][1] != "" {
-		t.Error("Email should be deidentified")
+
+	for _, tc := range testCases {
+		result, err := d.Text(tc.input)
+		if err != nil {
+			t.Fatalf("Text failed: %v", err)
+		}
+		if result != tc.expected {
+			t.Errorf("Text(%q): expected %q, got %q", tc.input, tc.expected, result)
+		}
+	}
+
+	// Without a known given name in front, a parenthesized word is not a nickname
+	if result, _ := d.Text("Report on Project (Alpha) Status"); !strings.Contains(result, "(Alpha) Status") {
+		t.Errorf("Expected the parenthesized word to be kept, got %q", result)
 	}
 }
 
-func checkEmptyValuesRemainEmpty(t *testing.T, result [][]string) {
-	if result[3][0] != "" || result[3][1] != "" {
-		t.Error("Empty values should remain empty")
+func TestUppercaseNames(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	result, err := d.Text("PLAINTIFF: JOHN SMITH filed the claim.")
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if strings.Contains(result, "JOHN SMITH") {
+		t.Errorf("all-caps name should be replaced, got %q", result)
+	}
+
+	// The fake is all caps too and matches the mixed-case spelling's replacement
+	mixed, _ := d.Name("John Smith")
+	if !strings.Contains(result, "PLAINTIFF: "+strings.ToUpper(mixed)+" filed") {
+		t.Errorf("expected %q in all caps, got %q", mixed, result)
+	}
+
+	// A rescan from the second word finds names after other all-caps words
+	if result, _ := d.Text("SIGNED MARIA GARCIA"); strings.Contains(result, "MARIA GARCIA") {
+		t.Errorf("all-caps name after a heading word should be replaced, got %q", result)
+	}
+
+	for _, phrase := range []string{"TERMS AND CONDITIONS", "PLEASE READ CAREFULLY", "THE USA PATRIOT ACT"} {
+		if result, _ := d.Text(phrase); result != phrase {
+			t.Errorf("all-caps phrase %q should be kept, got %q", phrase, result)
+		}
+	}
+
+	// A repeat alias is written as it is rather than uppercased
+	aliased := NewDeidentifier("test-secret-key", WithRepeatAlias(TypeName, "[same person]"))
+	if result, _ := aliased.Text("JOHN SMITH met John Smith"); !strings.HasPrefix(result, "[same person] met ") {
+		t.Errorf("expected the alias in its own case, got %q", result)
+	}
+
+	// The Finding holds the all-caps replacement that was written
+	text, findings, err := d.TextWithFindings("Patient JOHN SMITH arrived")
+	if err != nil {
+		t.Fatalf("TextWithFindings() error = %v", err)
+	}
+	if len(findings) != 1 || !strings.Contains(text, findings[0].Replacement) || findings[0].Replacement != strings.ToUpper(mixed) {
+		t.Errorf("expected one finding for %q in %q, got %+v", strings.ToUpper(mixed), text, findings)
+	}
+}
+
+func TestNameSuffixes(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	base, err := d.Name("John Smith")
+	if err != nil {
+		t.Fatalf("Name failed: %v", err)
+	}
+	junior, _ := d.Name("John Smith Jr.")
+	senior, _ := d.Name("John Smith Sr.")
+	juniorBase := strings.TrimSuffix(junior, " Jr.")
+	if juniorBase == base || juniorBase == strings.TrimSuffix(senior, " Sr.") {
+		t.Errorf("Father and son should get different fakes, got %q, %q and %q", base, junior, senior)
+	}
+
+	tests := []struct {
+		input  string
+		suffix string
+	}{
+		{"John Smith Jr.", " Jr."},
+		{"John Smith Jr", " Jr"},
+		{"John Smith, Jr.", ", Jr."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := d.Text("Signed by " + tt.input + " today")
+			if err != nil {
+				t.Fatalf("Text() error = %v", err)
+			}
+			want := "Signed by " + juniorBase + tt.suffix + " today"
+			if result != want {
+				t.Errorf("Text(%q) = %q, want %q", tt.input, result, want)
+			}
+		})
+	}
+
+	for _, suffix := range []string{" Sr.", " II", " III", " IV"} {
+		result, _ := d.Text("Signed by John Smith" + suffix + " today")
+		if !strings.HasSuffix(result, suffix+" today") || strings.Contains(result, "John Smith") {
+			t.Errorf("Expected the name replaced with %q kept, got %q", suffix, result)
+		}
+	}
+
+	// A word that merely starts like a numeral is not a suffix
+	result, _ := d.Text("Signed by John Smith Irving")
+	if result != "Signed by "+base+" Irving" {
+		t.Errorf("Expected only the base name to be replaced, got %s", result)
+	}
+}
+
+func TestDeidentifyDispatch(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	email, _ := d.Email("frodo@shire.me")
+	if got, err := d.Deidentify("frodo@shire.me", TypeEmail, "email"); err != nil || got != email {
+		t.Errorf("Deidentify(TypeEmail) = %q, %v; want %q", got, err, email)
+	}
+
+	phone, _ := d.Phone("(555) 123-4567")
+	if got, err := d.Deidentify("(555) 123-4567", TypePhone, "phone"); err != nil || got != phone {
+		t.Errorf("Deidentify(TypePhone) = %q, %v; want %q", got, err, phone)
+	}
+
+	if got, _ := d.Deidentify("keep me", TypeGeneric, "notes"); got != "keep me" {
+		t.Errorf("TypeGeneric values should pass through, got %q", got)
+	}
+}
+
+func TestGivenNameDictionary(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithGivenNameDictionary())
+
+	tests := []struct {
+		input    string
+		redacted string
+		kept     string
+	}{
+		{"Dear Maria,", "Maria", "Dear "},
+		{"Hi Maria, thanks for the update", "Maria", "Hi "},
+		{"Maria, can you call me back?", "Maria", ", can you"},
+		{"Thanks, John", "John", "Thanks, "},
+		{"Hello John Smith, welcome", "Smith", "Hello "},
+	}
+	for _, tt := range tests {
+		result, err := d.Text(tt.input)
+		if err != nil {
+			t.Fatalf("Text() error = %v", err)
+		}
+		if strings.Contains(result, tt.redacted) || !strings.Contains(result, tt.kept) {
+			t.Errorf("Text(%q) = %q, want %q redacted and %q kept", tt.input, result, tt.redacted, tt.kept)
+		}
+	}
+
+	// Without greeting or comma context, dictionary names are left alone
+	if result, _ := d.Text("I saw Mark today"); result != "I saw Mark today" {
+		t.Errorf("Expected no change without context, got %q", result)
+	}
+
+	// Standalone replacements are consistent and a custom list replaces the built-in one
+	first, _ := d.Text("Dear Maria,")
+	second, _ := d.Text("Hi Maria, welcome")
+	if strings.TrimPrefix(first, "Dear ") != strings.Fields(strings.TrimPrefix(second, "Hi "))[0] {
+		t.Errorf("Expected the same replacement, got %q and %q", first, second)
+	}
+	custom := NewDeidentifier("test-secret-key", WithGivenNameDictionary("Zelda"))
+	if result, _ := custom.Text("Thanks, Zelda"); strings.Contains(result, "Zelda") {
+		t.Errorf("Custom dictionary name should be redacted, got %q", result)
+	}
+
+	// The default pipeline is unchanged without the option
+	plain := NewDeidentifier("test-secret-key")
+	if result, _ := plain.Text("Maria, can you call me back?"); result != "Maria, can you call me back?" {
+		t.Errorf("Standalone names should only be matched with the option, got %q", result)
+	}
+}
+
+func TestCryptoAddressDeidentification(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	tests := []struct {
+		name     string
+		original string
+		pattern  string
+	}{
+		{"bitcoin P2PKH", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", `^1[1-9A-HJ-NP-Za-km-z]{25,33}$`},
+		{"bitcoin P2SH", "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", `^3[1-9A-HJ-NP-Za-km-z]{25,33}$`},
+		{"bitcoin bech32", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", `^bc1q[ac-hj-np-z02-9]{38}$`},
+		{"ethereum", "0x742d35Cc6634C0532925a3b844Bc454e4438f44e", `^0x[0-9a-f]{40}$`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := d.CryptoAddress(tt.original)
+			if err != nil {
+				t.Fatalf("CryptoAddress failed: %v", err)
+			}
+			if result == tt.original || !regexp.MustCompile(tt.pattern).MatchString(result) {
+				t.Errorf("CryptoAddress(%s) = %s, want a different address matching %s", tt.original, result, tt.pattern)
+			}
+			if !d.isCryptoAddress(result) {
+				t.Errorf("Generated address %s should be recognized as a wallet address", result)
+			}
+
+			text, _ := d.Text("Send the refund to " + tt.original + " today")
+			if text != "Send the refund to "+result+" today" {
+				t.Errorf("Text() = %q, want the address replaced intact", text)
+			}
+		})
+	}
+
+	// Generated Base58 and bech32 addresses carry valid checksums
+	legacy, _ := d.CryptoAddress("1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa")
+	if raw, ok := d.decodeBase58(legacy); !ok || len(raw) != 25 {
+		t.Errorf("Expected a 25-byte Base58Check payload, got %s", legacy)
+	}
+	segwit, _ := d.CryptoAddress("bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq")
+	values := []byte{3, 3, 0, 2, 3}
+	for _, c := range segwit[3:] {
+		values = append(values, byte(strings.IndexRune(bech32Alphabet, c)))
+	}
+	if d.bech32Polymod(values) != bech32Const {
+		t.Errorf("Generated bech32 address %s has an invalid checksum", segwit)
+	}
+
+	// Long digit runs fit the Base58 alphabet but fail the checksum
+	if d.isCryptoAddress("1234567890123456789012345678") {
+		t.Error("A plain digit run should not be treated as a wallet address")
+	}
+}
+
+func TestMultilineAddressBlocks(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	input := "Frodo Baggins\n1600 Pennsylvania Avenue NW\nWashington, DC 20500\n\nThanks"
+
+	result, err := d.Text(input)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	for _, leaked := range []string{"1600", "Pennsylvania", "Washington", "20500"} {
+		if strings.Contains(result, leaked) {
+			t.Errorf("Address block leaked %q: %q", leaked, result)
+		}
+	}
+
+	lines := strings.Split(result, "\n")
+	if len(lines) != 5 || lines[4] != "Thanks" {
+		t.Fatalf("Expected the line layout to be kept, got %q", result)
+	}
+	if !regexp.MustCompile(`^\d+ \S.*$`).MatchString(lines[1]) {
+		t.Errorf("Expected a fake street line, got %q", lines[1])
+	}
+	if !regexp.MustCompile(`^[A-Z][a-z]+, DC \d{5}$`).MatchString(lines[2]) {
+		t.Errorf("Expected a fake locality line keeping the state, got %q", lines[2])
+	}
+
+	// Unit lines and ZIP+4 codes stay part of the block
+	withUnit, _ := d.Text("42 Elm Street\nApt 4B\nSpringfield, IL 62704-1234")
+	if !regexp.MustCompile(`^\d+ .+\nUnit \d+\n[A-Z][a-z]+, IL \d{5}-\d{4}$`).MatchString(withUnit) {
+		t.Errorf("Expected street, unit and locality lines, got %q", withUnit)
+	}
+}
+
+func TestFindAddressMatches(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	addrRegex := regexp.MustCompile(addressRegexPattern)
+
+	filler := strings.Repeat("the 42 quick brown foxes jumped over 7 lazy dogs ", 20)
+	texts := []string{
+		"He lives at 123 Main Street in New York.",
+		"Send it to 42 Rue de Rivoli, Paris, France please",
+		"Offices: 10 Downing St, London and 1600 Pennsylvania Avenue NW, Washington, US",
+		"Ship to 221B Baker Street Apt 4, London, GB",
+		"Visit 5 Via Roma, Milano or 12 Calle Mayor, Madrid",
+		"Straße: 7 Lange Straße Berlin",
+		"address 123 Main Street",
+		filler + "and then 99 Elm Road, Springfield" + filler,
+		filler + "no street words appear anywhere in here",
+		"12345678 " + filler + " Main Street",
+	}
+	for _, text := range texts {
+		got := d.findAddressMatches(text, addrRegex)
+		want := addrRegex.FindAllStringIndex(text, -1)
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("findAddressMatches(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestAgeGeneralization(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	tests := []struct {
+		age  string
+		want string
+	}{
+		{"0", "0-4"},
+		{"4", "0-4"},
+		{"37", "35-39"},
+		{" 42 ", " 40-44 "},
+		{"89", "85-89"},
+		{"90", "90+"},
+		{"104", "90+"},
+	}
+	for _, tt := range tests {
+		got, err := d.Age(tt.age)
+		if err != nil || got != tt.want {
+			t.Errorf("Age(%q) = %q, %v; want %q", tt.age, got, err, tt.want)
+		}
+	}
+
+	for _, invalid := range []string{"-3", "forty", "12.5"} {
+		if _, err := d.Age(invalid); err == nil {
+			t.Errorf("Age(%q) should fail", invalid)
+		}
+	}
+
+	table := &Table{Columns: []Column{{Name: "age", DataType: TypeAge, Values: []interface{}{93, 27, nil}}}}
+	result, err := d.Table(table)
+	if err != nil {
+		t.Fatalf("Table failed: %v", err)
+	}
+	if result.Columns[0].Values[0] != "90+" || result.Columns[0].Values[1] != "25-29" {
+		t.Errorf("Expected bucketed ages in tables, got %v", result.Columns[0].Values)
+	}
+}
+
+func TestAgeGeneralizationInText(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithAgeGeneralization())
+	cases := map[string]string{
+		"A 45-year-old male presented with chest pain.": "A 45-49-year-old male presented with chest pain.",
+		"Patient aged 45 was admitted.":                 "Patient aged 45-49 was admitted.",
+		"Age: 37, non-smoker.":                          "Age: 35-39, non-smoker.",
+		"She is 92 years old.":                          "She is 90+ years old.",
+		"Room 45 was cleaned.":                          "Room 45 was cleaned.",
+	}
+	for input, want := range cases {
+		if got, err := d.Text(input); err != nil || got != want {
+			t.Errorf("Text(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	// Without the option ages in prose are kept
+	input := "A 45-year-old male, aged 45."
+	if got, _ := NewDeidentifier("test-secret-key").Text(input); got != input {
+		t.Errorf("Expected ages to be kept by default, got %q", got)
+	}
+}
+
+func TestRepeatAlias(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithRepeatAlias(TypeName, "The Customer"))
+	input := "John Smith called about an order. John Smith then wrote to Mary Jones and john@example.com, then john@example.com again."
+
+	result, err := d.Text(input)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	fake, _ := d.Name("John Smith")
+	other, _ := d.Name("Mary Jones")
+	email, _ := d.Email("john@example.com")
+	if strings.Count(result, fake) != 1 || strings.Count(result, "The Customer") != 1 {
+		t.Errorf("Expected one full replacement and one alias for the repeated name, got %q", result)
+	}
+	if strings.Index(result, fake) > strings.Index(result, "The Customer") {
+		t.Errorf("Expected the first mention to get the full replacement, got %q", result)
+	}
+	if !strings.Contains(result, other) {
+		t.Errorf("Expected a single mention to be replaced in full, got %q", result)
+	}
+	if strings.Count(result, email) != 2 {
+		t.Errorf("Expected types without an alias to keep consistent full replacement, got %q", result)
+	}
+
+	// Every call starts over, and the default replaces all mentions in full
+	again, _ := d.Text("John Smith left.")
+	if again != fake+" left." {
+		t.Errorf("Expected the first mention of a new call to be replaced in full, got %q", again)
+	}
+	plain, _ := NewDeidentifier("test-secret-key").Text(input)
+	if strings.Count(plain, fake) != 2 {
+		t.Errorf("Expected every mention to be replaced without WithRepeatAlias, got %q", plain)
+	}
+}
+
+func TestTextMatchesConvenienceMethods(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	cases := []struct {
+		value  string
+		method func(string) (string, error)
+	}{
+		{"john@company.com", d.Email},
+		{"(555) 123-4567", d.Phone},
+		{"555-1234", d.Phone},
+		{"123-45-6789", d.SSN},
+		{"4111 1111 1111 1111", d.CreditCard},
+		{"AB 12 34 56 C", d.NINO},
+		{"@jsmith", d.Username},
+		{"0x52908400098527886E0F7030069857D2E4169EE7", d.CryptoAddress},
+		{"123 Main Street", d.Address},
+		{"221B Baker Street, London, UK", d.Address},
+		{"12 Oak Road Apt 4, Boston", d.Address},
+	}
+	templates := []string{"%s", "Contact: %s.", "Reach %s today", "Lives at %s, thanks"}
+
+	for _, tc := range cases {
+		want, err := tc.method(tc.value)
+		if err != nil {
+			t.Fatalf("convenience method failed for %q: %v", tc.value, err)
+		}
+		for _, template := range templates {
+			result, err := d.Text(strings.Replace(template, "%s", tc.value, 1))
+			if err != nil {
+				t.Fatalf("Text() error = %v", err)
+			}
+			if expected := strings.Replace(template, "%s", want, 1); result != expected {
+				t.Errorf("Text and the convenience method disagree on %q: got %q, want %q", tc.value, result, expected)
+			}
+		}
+	}
+}
+
+func TestPhoneURIs(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	cases := []struct {
+		input string
+		want  *regexp.Regexp
+	}{
+		{"Call tel:+15551234567 today", regexp.MustCompile(`^Call tel:\+1\d{10} today$`)},
+		{"Text sms:5551234567", regexp.MustCompile(`^Text sms:\d{10}$`)},
+		{"sms:+15551234567,+15557654321?body=hi", regexp.MustCompile(`^sms:\+1\d{10},\+1\d{10}\?body=hi$`)},
+		{"tel:+44-20-7123-4567", regexp.MustCompile(`^tel:\+\d{2}-\d{2}-\d{4}-\d{4}$`)},
+	}
+	for _, c := range cases {
+		result, err := d.Text(c.input)
+		if err != nil {
+			t.Fatalf("Text(%q) error = %v", c.input, err)
+		}
+		if !c.want.MatchString(result) || strings.Contains(result, "1234567") || strings.Contains(result, "7654321") {
+			t.Errorf("Text(%q) = %q, want a replaced number matching %s", c.input, result, c.want)
+		}
+	}
+
+	// The same number gets the same replacement in tel: and sms: links and in markup
+	tel, _ := d.Text("tel:+15551234567")
+	sms, _ := d.Text("sms:+15551234567")
+	if strings.TrimPrefix(tel, "tel:") != strings.TrimPrefix(sms, "sms:") {
+		t.Errorf("Expected one replacement for both schemes, got %q and %q", tel, sms)
+	}
+	html, _ := d.HTML(`<a href="tel:+15551234567">Call us</a>`)
+	if html != `<a href="`+tel+`">Call us</a>` {
+		t.Errorf("Expected the tel: link in markup to be replaced, got %q", html)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	doc := `<html><head><title>Note for John Smith</title>` +
+		`<script>var owner = "joe@company.com";</script></head>` +
+		`<body class="note"><p title="Call (555) 123-4567">Email <a href="mailto:joe@company.com">joe&#64;company.com</a>` +
+		` &amp; SSN 123-45-6789.</p><!-- owner: jane@company.com --><img alt="John Smith" src="photo.png">a &lt; b</body></html>`
+
+	result, err := d.HTML(doc)
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	for _, pii := range []string{"John Smith", "joe@company.com", "joe&#64;company.com", "123-4567", "123-45-6789", "jane@company.com"} {
+		if strings.Contains(result, pii) {
+			t.Errorf("%q should be redacted, got %s", pii, result)
+		}
+	}
+
+	// Markup and untouched text survive byte for byte
+	fakeEmail, _ := d.Email("joe@company.com")
+	for _, markup := range []string{`<body class="note">`, `<a href="mailto:` + fakeEmail + `">`, `src="photo.png">`, "a &lt; b</body></html>", "<!-- owner: "} {
+		if !strings.Contains(result, markup) {
+			t.Errorf("expected %q in output, got %s", markup, result)
+		}
+	}
+
+	skipping := NewDeidentifier("test-secret-key", WithHTMLSkipScripts())
+	result, err = skipping.HTML(doc)
+	if err != nil {
+		t.Fatalf("HTML() error = %v", err)
+	}
+	if !strings.Contains(result, `<script>var owner = "joe@company.com";</script>`) {
+		t.Errorf("script content should be kept with WithHTMLSkipScripts, got %s", result)
+	}
+
+	// A stray apostrophe or '<' in the markup must not swallow the rest of the document
+	for _, doc := range []string{
+		`<p class=it's>Email joe@x.com, SSN 123-45-6789</p>`,
+		`<p>if a<b then mail joe@x.com</p>`,
+		`<div data-x=a'b>Call (555) 123-4567</div><p>John Smith</p>`,
+	} {
+		result, err := d.HTML(doc)
+		if err != nil {
+			t.Fatalf("HTML(%q) error = %v", doc, err)
+		}
+		for _, pii := range []string{"joe@x.com", "123-45-6789", "123-4567", "John Smith"} {
+			if strings.Contains(result, pii) {
+				t.Errorf("HTML(%q) = %q, %q should be redacted", doc, result, pii)
+			}
+		}
+	}
+}
+
+func TestLabeledLatLong(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	coordinate := regexp.MustCompile(`-?\d+\.\d+`)
+
+	for _, text := range []string{
+		"Pickup at lat: 37.7749, lng: -122.4194 today",
+		"latitude=51.5074; longitude=-0.1278",
+		`{"lat": 40.71, "lon": -74.01}`,
+		"LAT: 48.8566 LONG: 2.3522",
+	} {
+		result, err := d.Text(text)
+		if err != nil {
+			t.Fatalf("Text() error = %v", err)
+		}
+		original := coordinate.FindAllString(text, -1)
+		fuzzed := coordinate.FindAllString(result, -1)
+		if len(fuzzed) != 2 {
+			t.Fatalf("expected labels and two coordinates kept in %q, got %q", text, result)
+		}
+		if coordinate.ReplaceAllString(result, "N") != coordinate.ReplaceAllString(text, "N") {
+			t.Errorf("labels and separators should be kept: %q -> %q", text, result)
+		}
+		for i := range original {
+			before, _ := strconv.ParseFloat(original[i], 64)
+			after, _ := strconv.ParseFloat(fuzzed[i], 64)
+			if before == after || math.Abs(before-after) > 0.011 {
+				t.Errorf("coordinate %s should move by up to 0.01 degrees, got %s", original[i], fuzzed[i])
+			}
+			if _, decimals, _ := strings.Cut(fuzzed[i], "."); len(decimals) != len(original[i])-strings.Index(original[i], ".")-1 {
+				t.Errorf("precision should be kept: %s -> %s", original[i], fuzzed[i])
+			}
+		}
+	}
+
+	// Separate lat and lng fields can be fuzzed through the type directly
+	lat, _ := d.Deidentify("89.9999", TypeLatLong, "lat")
+	if value, _ := strconv.ParseFloat(lat, 64); value > 90 || value == 89.9999 {
+		t.Errorf("latitude should stay valid and move, got %s", lat)
+	}
+	if again, _ := d.Text("lat: 37.7749, lng: -122.4194"); !strings.Contains(again, "lat: ") {
+		t.Errorf("expected the label to be kept, got %q", again)
+	}
+
+	// Integer coordinates keep their precision, so they move by exactly one degree
+	moved := map[float64]bool{}
+	for _, value := range []string{"37", "-122", "51", "2", "-33", "151"} {
+		fuzzed, _ := d.Deidentify(value, TypeLatLong, "coordinate")
+		before, _ := strconv.ParseFloat(value, 64)
+		after, err := strconv.Atoi(fuzzed)
+		if err != nil || math.Abs(float64(after)-before) != 1 {
+			t.Errorf("integer coordinate %s should move by one degree, got %s", value, fuzzed)
+		}
+		moved[float64(after)-before] = true
+	}
+	if len(moved) != 2 {
+		t.Errorf("integer coordinates should move in both directions, got %v", moved)
+	}
+}
+
+func TestIPAddresses(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	log := "accepted 192.0.2.5:443 from 203.0.113.77, route 198.51.100.0/24 via 10.1.2.3:8080 and 10.0.0.0/8"
+	result, err := d.Text(log)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+
+	fields := strings.Fields(result)
+	port, public, cidr, private, block := fields[1], strings.TrimSuffix(fields[3], ","), fields[5], fields[7], fields[9]
+	for _, original := range []string{"192.0.2.5", "203.0.113.77", "198.51.100.0", "10.1.2.3"} {
+		if strings.Contains(result, original) {
+			t.Errorf("Expected %s to be replaced, got %q", original, result)
+		}
+	}
+	host, p, _ := strings.Cut(port, ":")
+	if addr, err := netip.ParseAddr(host); err != nil || !addr.Is4() || p != "443" {
+		t.Errorf("Expected the port to be kept on a fake address, got %q", port)
+	}
+	if addr, err := netip.ParseAddr(public); err != nil || addr.IsPrivate() {
+		t.Errorf("Expected a fake public address, got %q", public)
+	}
+	if prefix, err := netip.ParsePrefix(cidr); err != nil || prefix.Bits() != 24 || prefix.Masked() != prefix {
+		t.Errorf("Expected a /24 network with its host bits cleared, got %q", cidr)
+	}
+	if !strings.HasPrefix(private, "10.") || !strings.HasSuffix(private, ":8080") {
+		t.Errorf("Expected a private address to stay private and keep its port, got %q", private)
+	}
+	if block != "10.0.0.0/8" {
+		t.Errorf("Expected a whole private block to stay as it is, got %q", block)
+	}
+
+	// One host keeps its fake across ports, and the type can be used on columns directly
+	bare, _ := d.IPAddress("192.0.2.5")
+	if !strings.HasPrefix(port, bare+":") {
+		t.Errorf("Expected %s with a port to use the fake %s, got %s", "192.0.2.5", bare, port)
+	}
+	if column, _ := d.Deidentify("192.0.2.5:22", TypeIPAddress, "client"); column != bare+":22" {
+		t.Errorf("Expected %s:22, got %s", bare, column)
+	}
+
+	// Auto and Slices infer the type from the values
+	auto, dataType, err := d.Auto("192.168.1.10")
+	if err != nil || dataType != TypeIPAddress || !strings.HasPrefix(auto, "192.168.") || auto == "192.168.1.10" {
+		t.Errorf("Auto: expected a fake private IP address, got %q as type %d (%v)", auto, dataType, err)
+	}
+	rows, err := d.Slices([][]string{{"192.0.2.5"}, {"10.1.2.3:8080"}, {"203.0.113.77"}})
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	if rows[0][0] != bare || !strings.HasPrefix(rows[1][0], "10.") || rows[2][0] == "203.0.113.77" {
+		t.Errorf("Slices: expected the column inferred as IP addresses, got %v", rows)
+	}
+
+	// Version strings, loopback and netmasks are left alone
+	for _, text := range []string{"upgraded to 1.2.3.4.5", "listening on 127.0.0.1:8080", "netmask 255.255.255.0", "bind 0.0.0.0:80", "not an ip 999.1.1.1"} {
+		if result, _ := d.Text(text); result != text {
+			t.Errorf("Expected %q to be left alone, got %q", text, result)
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	doc := "# Contacts\n\n" +
+		"Reach [Jane Smith](mailto:jane@company.com \"Send mail\") or [call](tel:+15551234567).\n" +
+		"![John Smith](https://example.com/photo.png) wrote from joe@company.com.\n" +
+		"Run `notify joe@company.com` to test.\n\n" +
+		"```go\nowner := \"joe@company.com\"\n```\n"
+
+	result, err := d.Markdown(doc)
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+
+	name, _ := d.Name("Jane Smith")
+	email, _ := d.Email("jane@company.com")
+	tel, _ := d.Text("tel:+15551234567")
+	photo, _ := d.Name("John Smith")
+	joe, _ := d.Email("joe@company.com")
+	for _, expected := range []string{
+		"# Contacts\n\n",
+		"[" + name + "](mailto:" + email + ` "Send mail")`,
+		"[call](" + tel + ")",
+		"![" + photo + "](https://example.com/photo.png) wrote from " + joe + ".",
+		"Run `notify joe@company.com` to test.",
+		"```go\nowner := \"joe@company.com\"\n```\n",
+	} {
+		if !strings.Contains(result, expected) {
+			t.Errorf("expected %q in output, got:\n%s", expected, result)
+		}
+	}
+
+	// With WithMarkdownCode, code is scrubbed too
+	result, err = NewDeidentifier("test-secret-key", WithMarkdownCode()).Markdown(doc)
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+	if strings.Contains(result, "joe@company.com") {
+		t.Errorf("expected code to be scrubbed with WithMarkdownCode, got:\n%s", result)
+	}
+	if !strings.Contains(result, "```go\nowner := \""+joe+"\"\n```\n") {
+		t.Errorf("expected the fence to be kept, got:\n%s", result)
+	}
+}
+
+func TestVCard(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	card := "BEGIN:VCARD\r\n" +
+		"VERSION:4.0\r\n" +
+		"FN:John Smith\r\n" +
+		"N:Smith;John;Quincy;Dr.;\r\n" +
+		"item1.TEL;TYPE=\"work,voice\":(555) 123-4567\r\n" +
+		"TEL;VALUE=uri:tel:+1-555-987-6543\r\n" +
+		"EMAIL;TYPE=work:john@company.com\r\n" +
+		"ADR;TYPE=home:;Apt 4;123 Main Street;Springfield;IL;62704;USA\r\n" +
+		"NOTE:Backup contact jane@company.com\r\n" +
+		"X-SOCIALPROFILE;TYPE=twitter:@jsmith\r\n" +
+		"ORG:Acme\r\n" +
+		"not a property\r\n" +
+		"END:VCARD\r\n"
+
+	result, err := d.VCard(card)
+	if err != nil {
+		t.Fatalf("VCard() error = %v", err)
+	}
+
+	name, _ := d.Name("John Smith")
+	first, last, _ := strings.Cut(name, " ")
+	phone, _ := d.Phone("(555) 123-4567")
+	email, _ := d.Email("john@company.com")
+	street, _ := d.Address("123 Main Street")
+	for _, line := range []string{
+		"FN:" + name + "\r\n",
+		"N:" + last + ";" + first + ";;Dr.;\r\n",
+		"item1.TEL;TYPE=\"work,voice\":" + phone + "\r\n",
+		"TEL;VALUE=uri:tel:+1-",
+		"EMAIL;TYPE=work:" + email + "\r\n",
+		"ADR;TYPE=home:;;" + street + ";Springfield;IL;",
+		"ORG:Acme\r\n",
+		"not a property\r\n",
+		"END:VCARD\r\n",
+	} {
+		if !strings.Contains(result, line) {
+			t.Errorf("expected %q in vCard output, got:\n%s", line, result)
+		}
+	}
+	for _, pii := range []string{"John", "Smith", "Quincy", "123-4567", "987-6543", "company.com", "Main Street", "62704", "@jsmith"} {
+		if strings.Contains(result, pii) {
+			t.Errorf("%q should be redacted, got:\n%s", pii, result)
+		}
+	}
+
+	folded := "NOTE:Reach me at jane@comp\r\n any.com\r\n"
+	if result, _ := d.VCard(folded); strings.Contains(result, "jane@company.com") {
+		t.Errorf("folded lines should be unfolded before detection, got %q", result)
+	}
+}
+
+func TestVCardNameParts(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithNameWordCount())
+	result, err := d.VCard("BEGIN:VCARD\r\nN:Watson;Mary Jane;;;\r\nEND:VCARD\r\n")
+	if err != nil {
+		t.Fatalf("VCard() error = %v", err)
+	}
+	name, _ := d.Name("Mary Jane Watson")
+	space := strings.LastIndex(name, " ")
+	if want := "N:" + name[space+1:] + ";" + name[:space] + ";;;\r\n"; !strings.Contains(result, want) {
+		t.Errorf("expected %q, with the middle name kept among the given names, got:\n%s", want, result)
+	}
+
+	redacted := NewDeidentifier("test-secret-key", WithRedactedTypes(TypeName))
+	result, err = redacted.VCard("BEGIN:VCARD\r\nN:Smith;John;;;\r\nEND:VCARD\r\n")
+	if err != nil {
+		t.Fatalf("VCard() error = %v", err)
+	}
+	if !strings.Contains(result, "N:[NAME];;;;\r\n") {
+		t.Errorf("expected the redaction token as the family name, got:\n%s", result)
+	}
+}
+
+func TestSupportedTypes(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	types := SupportedTypes()
+
+	// Every DataType constant is listed exactly once
+	seen := make(map[DataType]int)
+	for _, info := range types {
+		seen[info.Type]++
+		if info.Name == "" {
+			t.Errorf("Type %d has no name", info.Type)
+		}
+	}
+	constants := []DataType{
+		TypeName, TypeEmail, TypePhone, TypeSSN, TypeCreditCard, TypeAddress, TypeGeneric, TypeSIN,
+		TypeNINO, TypeUsername, TypeCryptoAddress, TypeAge, TypeFormattedID, TypeLatLong,
+		TypeNumericNoise, TypeSWIFT, TypeDateTime, TypeIPAddress, TypeCategorical,
+	}
+	for _, dataType := range constants {
+		if n := seen[dataType]; n != 1 {
+			t.Errorf("Expected type %d to be listed once, got %d", dataType, n)
+		}
+	}
+	if len(types) != len(constants) {
+		t.Errorf("Expected %d types, got %d", len(constants), len(types))
+	}
+
+	// Examples of text-detected types are actually found by Text
+	for _, info := range types {
+		if !info.DetectedInText {
+			continue
+		}
+		result, err := d.Text("Value: " + info.Example)
+		if err != nil {
+			t.Fatalf("Text() error = %v", err)
+		}
+		if strings.Contains(result, info.Example) {
+			t.Errorf("%s example %q should be detected in text, got %q", info.Name, info.Example, result)
+		}
+	}
+}
+
+func TestReversedNameVariants(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	fake, err := d.Name("John Smith")
+	if err != nil {
+		t.Fatalf("Name failed: %v", err)
+	}
+	fields := strings.Fields(fake)
+	reversedFake := fields[1] + ", " + fields[0]
+
+	result, err := d.Text("John Smith signed the form. Records list him as Smith, John.")
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	want := fake + " signed the form. Records list him as " + reversedFake + "."
+	if result != want {
+		t.Errorf("Text() = %q, want %q", result, want)
+	}
+
+	if got, _ := d.Name("Smith, John"); got != reversedFake {
+		t.Errorf("Name(\"Smith, John\") = %q, want %q", got, reversedFake)
+	}
+
+	// Without the forward form in the text, comma pairs are left alone
+	if got, _ := d.Text("We flew to Paris, France."); got != "We flew to Paris, France." {
+		t.Errorf("Expected no change, got %q", got)
+	}
+}
+
+func TestPlaceNamesNotRedacted(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	places := []string{
+		"New York", "Las Vegas", "New Orleans", "El Paso", "Fort Lauderdale",
+		"Mount Vernon", "North Carolina", "Tel Aviv", "Buenos Aires",
+	}
+
+	for _, place := range places {
+		if result, _ := d.Text(place); result != place {
+			t.Errorf("Text(%q) = %q, place names should not become person names", place, result)
+		}
+		sentence := "We met in " + place + " yesterday"
+		if result, _ := d.Text(sentence); result != sentence {
+			t.Errorf("Text(%q) = %q", sentence, result)
+		}
+	}
+
+	// Ordinary names are still redacted
+	if result, _ := d.Text("Contact Frodo Baggins"); strings.Contains(result, "Frodo") {
+		t.Errorf("Expected the name to be redacted, got %q", result)
+	}
+}
+
+func TestMinTokenLength(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithMinTokenLength(3))
+
+	if result, _ := d.Text("Signed: Al Baggins"); result != "Signed: Al Baggins" {
+		t.Errorf("Names with short words should be kept, got %q", result)
+	}
+	if result, _ := d.Text("Signed: Frodo Baggins"); strings.Contains(result, "Frodo") {
+		t.Errorf("Names with long enough words should be redacted, got %q", result)
+	}
+}
+
+func TestRegisterPattern(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	d.RegisterPattern("employee_id", regexp.MustCompile(`\bEMP-\d{6}\b`), TypeFormattedID)
+
+	result, err := d.Text("Ticket from EMP-000123 (call 555-123-4567), escalated by EMP-000456")
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	ids := regexp.MustCompile(`EMP-\d{6}`).FindAllString(result, -1)
+	if len(ids) != 2 || ids[0] == "EMP-000123" || ids[1] == "EMP-000456" || ids[0] == ids[1] {
+		t.Errorf("Expected two distinct fake employee IDs, got %q", result)
+	}
+	if strings.Contains(result, "555-123-4567") {
+		t.Errorf("Built-in detectors should still run, got %q", result)
+	}
+
+	// The same ID maps consistently and matches the table path
+	again, _ := d.Text("EMP-000123")
+	viaType, _ := d.Deidentify("EMP-000123", TypeFormattedID, "employee_id")
+	if again != ids[0] || viaType != ids[0] {
+		t.Errorf("Expected %s consistently, got %s and %s", ids[0], again, viaType)
+	}
+}
+
+func TestLoadRecognizers(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	config := `{"recognizers": [
+		{"name": "employee_id", "pattern": "\\bEMP-\\d{6}\\b", "type": "formatted_id", "score": 0.9},
+		{
+			"name": "Member Recognizer",
+			"supported_entity": "PERSON",
+			"patterns": [{"name": "member", "regex": "\\bMember [A-Z][a-z]+\\b", "score": 0.6}]
+		},
+		{"name": "ticket", "supported_entity": "TICKET", "patterns": [{"regex": "\\bTKT-\\d{4}\\b", "score": 0.3}]}
+	]}`
+	if err := d.LoadRecognizers([]byte(config)); err != nil {
+		t.Fatalf("LoadRecognizers failed: %v", err)
+	}
+
+	employee, _ := d.Deidentify("EMP-000123", TypeFormattedID, "employee_id")
+	member, _ := d.Deidentify("Member Frodo", TypeName, "member")
+	ticket, _ := d.Deidentify("TKT-1234", TypeFormattedID, "ticket")
+	result, err := d.Text("EMP-000123 and Member Frodo filed TKT-1234")
+	if err != nil {
+		t.Fatalf("Text failed: %v", err)
+	}
+	if expected := employee + " and " + member + " filed " + ticket; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	// A list works too, and higher scores are registered first
+	list := NewDeidentifier("test-secret-key")
+	if err := list.LoadRecognizers([]byte(`[
+		{"name": "weak", "pattern": "\\d{4}", "type": "formatted_id", "score": 0.1},
+		{"name": "strong", "pattern": "ID-\\d{4}", "type": "formatted_id", "score": 0.8}
+	]`)); err != nil {
+		t.Fatalf("LoadRecognizers failed: %v", err)
+	}
+	if names := []string{list.customPatterns.patterns[0].name, list.customPatterns.patterns[1].name}; names[0] != "strong" || names[1] != "weak" {
+		t.Errorf("Expected the stronger pattern first, got %v", names)
+	}
+
+	// Invalid configs register nothing
+	for _, invalid := range []string{
+		`{"recognizers": [`,
+		`[{"name": "a", "pattern": "(?=x)", "type": "formatted_id"}]`,
+		`[{"name": "a", "pattern": "x", "type": "no such type"}]`,
+		`[{"pattern": "x"}]`,
+		`[{"name": "ok", "pattern": "x"}, {"name": "bad", "pattern": "("}]`,
+	} {
+		fresh := NewDeidentifier("test-secret-key")
+		if err := fresh.LoadRecognizers([]byte(invalid)); err == nil || len(fresh.customPatterns.patterns) != 0 {
+			t.Errorf("Expected %s to be rejected without registering patterns", invalid)
+		}
+	}
+}
+
+func TestFormattedIDShape(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	tests := []struct {
+		original string
+		pattern  string
+	}{
+		{"EMP-000123", `^EMP-\d{6}$`},
+		{"CUST_ab12/XY", `^CUST_[a-z]{2}\d{2}/[A-Z]{2}$`},
+		{"1234-5678-9012-3456-7890-1234-5678-9012-3456", `^(\d{4}-){8}\d{4}$`},
+		{"ABCDEF", `^[A-Z]{6}$`},
+		{"ORDER", `^[A-Z]{5}$`},
+	}
+	for _, tt := range tests {
+		if result := d.generateFormattedID(tt.original); !regexp.MustCompile(tt.pattern).MatchString(result) || result == tt.original {
+			t.Errorf("generateFormattedID(%q) = %q, want a different value matching %s", tt.original, result, tt.pattern)
+		}
+	}
+}
+
+func TestFormattedIDNeverReturnsInput(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	for _, id := range []string{"A", "7", "AB-1", "ORDER", "ABCDEF", "--", "#/#", "EMP-000123"} {
+		result, err := d.Deidentify(id, TypeFormattedID, "id")
+		if err != nil {
+			t.Fatalf("Deidentify(%q) error = %v", id, err)
+		}
+		if result == id {
+			t.Errorf("Deidentify(%q, TypeFormattedID) returned its input", id)
+		}
+	}
+}
+
+func TestCSVTypeHints(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	input := "contact:email,id:ssn,notes,customer_name\n" +
+		"frodo@shire.me,123-45-6789,keep me,Frodo Baggins\n" +
+		"sam@shire.me,987-65-4321,and me,Samwise Gamgee\n"
+
+	var out bytes.Buffer
+	if err := d.CSV(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&out).ReadAll()
+	if err != nil {
+		t.Fatalf("Output is not valid CSV: %v", err)
+	}
+	if strings.Join(records[0], ",") != "contact,id,notes,customer_name" {
+		t.Errorf("Expected hints stripped from the header, got %v", records[0])
+	}
+
+	email, _ := d.Deidentify("frodo@shire.me", TypeEmail, "contact")
+	ssn, _ := d.Deidentify("123-45-6789", TypeSSN, "id")
+	if records[1][0] != email || records[1][1] != ssn {
+		t.Errorf("Hinted columns should use their declared types, got %v", records[1])
+	}
+	if records[1][2] != "keep me" || records[2][2] != "and me" {
+		t.Errorf("Free-text column should be inferred as generic, got %v", records[1:])
+	}
+	if records[1][3] == "Frodo Baggins" {
+		t.Errorf("Unhinted name column should be inferred and redacted, got %v", records[1])
+	}
+
+	// A colon that is not a known type stays part of the name
+	out.Reset()
+	if err := d.CSV(strings.NewReader("time:start\n09:00\n"), &out); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "time:start\n") {
+		t.Errorf("Expected the header to be kept, got %q", out.String())
+	}
+}
+
+func TestCSVCrosswalkWriter(t *testing.T) {
+	input := "contact:email,notes,id:ssn\n" +
+		"frodo@shire.me,keep me,123-45-6789\n" +
+		"sam@shire.me,and me,987-65-4321\n" +
+		"frodo@shire.me,again,123-45-6789\n"
+
+	var out, crosswalk bytes.Buffer
+	d := NewDeidentifier("test-secret-key", WithCrosswalkWriter(&crosswalk))
+	if err := d.CSV(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&crosswalk).ReadAll()
+	if err != nil {
+		t.Fatalf("Crosswalk is not valid CSV: %v", err)
+	}
+	frodo, _ := d.Deidentify("frodo@shire.me", TypeEmail, "contact")
+	ssn, _ := d.Deidentify("123-45-6789", TypeSSN, "id")
+	if len(records) != 5 {
+		t.Fatalf("Expected a header and one row per distinct replaced value, got %v", records)
+	}
+	if strings.Join(records[0], ",") != "column,original,replacement" {
+		t.Errorf("Unexpected crosswalk header %v", records[0])
+	}
+	if strings.Join(records[1], ",") != "contact,frodo@shire.me,"+frodo || strings.Join(records[2], ",") != "id,123-45-6789,"+ssn {
+		t.Errorf("Expected rows in order of first appearance, got %v", records[1:])
+	}
+
+	// A rerun over the same input gives the same crosswalk
+	var rerun bytes.Buffer
+	d = NewDeidentifier("test-secret-key", WithCrosswalkWriter(&rerun))
+	if err := d.CSV(strings.NewReader(input), io.Discard); err != nil {
+		t.Fatalf("CSV failed: %v", err)
+	}
+	first, _ := csv.NewReader(strings.NewReader(rerun.String())).ReadAll()
+	if fmt.Sprint(first) != fmt.Sprint(records) {
+		t.Errorf("Expected identical crosswalks across runs, got %v and %v", records, first)
+	}
+}
+
+func TestCategorical(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	for value, expected := range map[string]string{
+		"Married":   "Partnered",
+		"divorced":  "Not partnered",
+		" Widowed ": " Not partnered ",
+		"Female":    "*",
+		"":          "",
+	} {
+		if result, _ := d.Categorical(value, Generalize); result != expected {
+			t.Errorf("Categorical(%q, Generalize) = %q, want %q", value, result, expected)
+		}
+	}
+	if result, _ := d.Categorical("Married", Suppress); result != "*" {
+		t.Errorf("Expected suppression, got %q", result)
+	}
+
+	// Categorical columns are only inferred when asked for
+	rows := [][]string{{"Frodo Baggins", "Male", "Married"}, {"Rosie Cotton", "Female", "Single"}}
+	result, err := d.Slices(rows)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	if result[0][1] != "Male" || result[1][2] != "Single" {
+		t.Errorf("Expected categorical values kept without WithCategoricalInference, got %v", result)
+	}
+
+	// A custom hierarchy replaces the default, and inferred columns use the configured mode
+	custom := NewDeidentifier("test-secret-key", WithCategoricalInference(),
+		WithCategoryHierarchy(map[string]string{"Male": "Person", "Female": "Person"}))
+	result, err = custom.Slices(rows)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	if result[0][1] != "Person" || result[1][1] != "Person" || result[0][2] != "*" || result[0][0] == "Frodo Baggins" {
+		t.Errorf("Expected genders generalized and marital statuses suppressed, got %v", result)
+	}
+	suppressed := NewDeidentifier("test-secret-key", WithCategoricalMode(Suppress))
+	if values, _ := suppressed.Column("marital_status", []string{"Married", "Single"}, TypeCategorical); values[0] != "*" || values[1] != "*" {
+		t.Errorf("Expected a suppressed column, got %v", values)
+	}
+}
+
+func TestNumericNoise(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	first, err := d.NumericNoise("1000", 10)
+	if err != nil {
+		t.Fatalf("NumericNoise failed: %v", err)
+	}
+	again, _ := d.NumericNoise("1000", 10)
+	if first != again {
+		t.Errorf("Expected deterministic noise, got %q and %q", first, again)
+	}
+	if n, _ := strconv.Atoi(first); n < 900 || n > 1100 || n == 1000 {
+		t.Errorf("Expected an integer within ±10%% of 1000, got %q", first)
+	}
+
+	formats := map[string]*regexp.Regexp{
+		"$1,234,567.89": regexp.MustCompile(`^\$\d{1,3}(,\d{3})*\.\d{2}$`),
+		"-42.5":         regexp.MustCompile(`^-\d+\.\d$`),
+		" 12.75% ":      regexp.MustCompile(`^ \d+\.\d{2}% $`),
+	}
+	for input, want := range formats {
+		if got, err := d.NumericNoise(input, 5); err != nil || !want.MatchString(got) {
+			t.Errorf("NumericNoise(%q) = %q, %v; want format %s", input, got, err, want)
+		}
+	}
+	if _, err := d.NumericNoise("twelve", 5); err == nil {
+		t.Error("Expected an error for a non-numeric value")
+	}
+
+	// Individual values change while the mean stays close
+	column := make([][]string, 1000)
+	sum := 0.0
+	for i := range column {
+		value := 100 + float64(i)/10
+		column[i] = []string{strconv.FormatFloat(value, 'f', 2, 64)}
+		sum += value
+	}
+	result, err := NewDeidentifier("test-secret-key", WithNumericNoise(20)).Slices(column, []DataType{TypeNumericNoise})
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	noisySum, changed := 0.0, 0
+	for i, row := range result {
+		value, _ := strconv.ParseFloat(row[0], 64)
+		noisySum += value
+		if row[0] != column[i][0] {
+			changed++
+		}
+	}
+	if changed < 990 || math.Abs(noisySum-sum)/sum > 0.01 {
+		t.Errorf("Expected most values changed and the sum within 1%%, got %d changed and %.2f vs %.2f", changed, noisySum, sum)
+	}
+}
+
+func TestParseDataType(t *testing.T) {
+	for _, name := range []string{"credit_card", "Credit card", "CREDITCARD", "credit-card"} {
+		if got, err := ParseDataType(name); err != nil || got != TypeCreditCard {
+			t.Errorf("ParseDataType(%q) = %v, %v; want TypeCreditCard", name, got, err)
+		}
+	}
+	if _, err := ParseDataType("passport"); err == nil {
+		t.Error("Expected an error for an unknown type")
+	}
+}
+
+func TestWhitespacePreserved(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	core, err := d.Name("John Doe")
+	if err != nil {
+		t.Fatalf("Name failed: %v", err)
+	}
+
+	padded, err := d.Name("  John Doe  ")
+	if err != nil {
+		t.Fatalf("Name failed: %v", err)
+	}
+	if padded != "  "+core+"  " {
+		t.Errorf("Name(%q) = %q, want %q", "  John Doe  ", padded, "  "+core+"  ")
+	}
+
+	result, err := d.Slices([][]string{{"\tJohn Doe", "frodo@shire.me   "}}, []DataType{TypeName, TypeEmail})
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	if result[0][0] != "\t"+core || !strings.HasSuffix(result[0][1], "   ") || strings.TrimSpace(result[0][1]) == "frodo@shire.me" {
+		t.Errorf("Expected padding kept around replaced values, got %q", result[0])
+	}
+
+	// Whitespace-only values are returned unchanged
+	if blank, _ := d.Name("   "); blank != "   " {
+		t.Errorf("Expected whitespace-only value unchanged, got %q", blank)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	token := d.Tokenize("frodo@shire.me", "email")
+
+	if !regexp.MustCompile(`^[a-z2-7]{16}$`).MatchString(token) {
+		t.Errorf("Expected a 16-character base32 token, got %q", token)
+	}
+	if again := d.Tokenize("frodo@shire.me", "email"); again != token {
+		t.Errorf("Tokens should be deterministic, got %q and %q", token, again)
+	}
+	if other := d.Tokenize("frodo@shire.me", "backup_email"); other == token {
+		t.Error("Tokens should depend on the column")
+	}
+	if other := NewDeidentifier("another-key").Tokenize("frodo@shire.me", "email"); other == token {
+		t.Error("Tokens should depend on the key")
+	}
+
+	long := NewDeidentifier("test-secret-key", WithTokenLength(100))
+	if got := len(long.Tokenize("frodo@shire.me", "email")); got != 52 {
+		t.Errorf("Expected the token length clamped to 52, got %d", got)
+	}
+	short := NewDeidentifier("test-secret-key", WithTokenLength(8))
+	if got := short.Tokenize("frodo@shire.me", "email"); got != token[:8] {
+		t.Errorf("Expected a prefix of the default token, got %q", got)
+	}
+}
+
+func TestConvenienceMethods(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	// Test Email
+	email := "test@example.com"
+	emailResult, err := d.Email(email)
+	if err != nil {
+		t.Fatalf("Email failed: %v", err)
+	}
+	if emailResult == email {
+		t.Errorf("Email should produce different result, got: %s", emailResult)
+	}
+	if !strings.Contains(emailResult, "@") {
+		t.Errorf("Email result doesn't look like an email: %s", emailResult)
+	}
+
+	// Test Phone
+	phone := "(555) 123-4567"
+	phoneResult, err := d.Phone(phone)
+	if err != nil {
+		t.Fatalf("Phone failed: %v", err)
+	}
+	if phoneResult == phone {
+		t.Errorf("Phone should produce different result, got: %s", phoneResult)
+	}
+
+	// Test SSN
+	ssn := "123-45-6789"
+	ssnResult, err := d.SSN(ssn)
+	if err != nil {
+		t.Fatalf("SSN failed: %v", err)
+	}
+	if ssnResult == ssn {
+		t.Errorf("SSN should produce different result, got: %s", ssnResult)
+	}
+	if !regexp.MustCompile(`\d{3}-\d{2}-\d{4}`).MatchString(ssnResult) {
+		t.Errorf("SSN result doesn't match SSN format: %s", ssnResult)
+	}
+}
+
+func TestLocalPhoneNumbers(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	local, err := d.Phone("123-4567")
+	if err != nil {
+		t.Fatalf("Phone failed: %v", err)
+	}
+	if !regexp.MustCompile(`^\d{3}-\d{4}$`).MatchString(local) || local == "123-4567" {
+		t.Errorf("Expected a different 7-digit number, got %s", local)
+	}
+	if dotted, _ := d.Phone("123.4567"); !regexp.MustCompile(`^\d{3}\.\d{4}$`).MatchString(dotted) {
+		t.Errorf("Expected the separator kept, got %s", dotted)
+	}
+
+	result, err := d.Text("Call the front desk at 123-4567 or the office at (555) 987-6543.")
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if !strings.Contains(result, "at "+local+" or") {
+		t.Errorf("Expected the local number replaced, got %q", result)
+	}
+	if !regexp.MustCompile(`\(555\) \d{3}-\d{4}\.$`).MatchString(result) || strings.Contains(result, "987-6543") {
+		t.Errorf("Expected the full number handled as a whole, got %q", result)
+	}
+
+	// Hyphenated runs that are part of longer numbers are not local phones
+	for _, input := range []string{"Part 12-345-6789", "Ref 123-4567-89", "Ref 123-4567.5"} {
+		if got, _ := d.Text(input); got != input {
+			t.Errorf("Text(%q) = %q, should not treat it as a local number", input, got)
+		}
+	}
+}
+
+func TestSINDeidentification(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	testCases := []struct {
+		original string
+		pattern  string
+	}{
+		{"046 454 286", `^[1-7]\d{2} \d{3} \d{3}$`},
+		{"046-454-286", `^[1-7]\d{2}-\d{3}-\d{3}$`},
+		{"046454286", `^[1-7]\d{8}$`},
+	}
+
+	for _, tc := range testCases {
+		result, err := d.SIN(tc.original)
+		if err != nil {
+			t.Fatalf("SIN failed: %v", err)
+		}
+
+		if matched, _ := regexp.MatchString(tc.pattern, result); !matched {
+			t.Errorf("SIN %s doesn't match expected pattern %s, got %s", tc.original, tc.pattern, result)
+		}
+		if !d.isValidSIN(result) {
+			t.Errorf("Generated SIN %s has invalid Luhn checksum", result)
+		}
+		if result == tc.original {
+			t.Errorf("SIN should be anonymized, got same value: %s", result)
+		}
+	}
+}
+
+func TestSINDetectionInText(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	// Luhn-valid SIN with context is redacted as a SIN, US SSN still handled as SSN
+	input := "Employee SIN: 046 454 286, US SSN: 123-45-6789"
+	result, err := d.Text(input)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+
+	matched, _ := regexp.MatchString(`^Employee SIN: \d{3} \d{3} \d{3}, US SSN: \d{3}-\d{2}-\d{4}$`, result)
+	if !matched {
+		t.Fatalf("Unexpected result: %s", result)
+	}
+	if strings.Contains(result, "046 454 286") || strings.Contains(result, "123-45-6789") {
+		t.Errorf("Identifiers should be redacted, got %s", result)
+	}
+
+	// Without SIN context the number is left alone
+	noContext := "Reference 046 454 286 attached"
+	result, err = d.Text(noContext)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if result != noContext {
+		t.Errorf("Expected unchanged text without SIN context, got %s", result)
+	}
+
+	// Luhn-invalid numbers are not treated as a SIN
+	invalid := "SIN: 046 454 287"
+	result, err = d.Text(invalid)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if result != invalid {
+		t.Errorf("Expected Luhn-invalid SIN to be left unchanged, got %s", result)
+	}
+}
+
+func TestNINODeidentification(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	testCases := []struct {
+		original string
+		pattern  string
+	}{
+		{"AB123456C", `^[A-Z]{2}\d{6}[A-D]$`},
+		{"AB 12 34 56 C", `^[A-Z]{2} \d{2} \d{2} \d{2} [A-D]$`},
+	}
+
+	ninoRegex := regexp.MustCompile(ninoRegexPattern)
+	for _, tc := range testCases {
+		result, err := d.NINO(tc.original)
+		if err != nil {
+			t.Fatalf("NINO failed: %v", err)
+		}
+
+		if matched, _ := regexp.MatchString(tc.pattern, result); !matched {
+			t.Errorf("NINO %s doesn't match expected pattern %s, got %s", tc.original, tc.pattern, result)
+		}
+		if !ninoRegex.MatchString(result) || invalidNINOPrefixes[result[:2]] {
+			t.Errorf("Generated NINO %s is not a valid NINO", result)
+		}
+	}
+
+	result, err := d.Text("Her NI number is QQ123456C, his is AB 12 34 56 C.")
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	if !strings.Contains(result, "QQ123456C") {
+		t.Errorf("Invalid NINO prefix should not be redacted, got %s", result)
+	}
+	if strings.Contains(result, "AB 12 34 56 C") {
+		t.Errorf("NINO should be redacted, got %s", result)
+	}
+}
+
+// Helper function to validate Luhn checksum
+func isValidLuhn(cardNumber string) bool {
+	sum := 0
+	alternate := false
+
+	for i := len(cardNumber) - 1; i >= 0; i-- {
+		digit := int(cardNumber[i] - '0')
+
+		if alternate {
+			digit *= 2
+			if digit > 9 {
+				digit = digit/10 + digit%10
+			}
+		}
+
+		sum += digit
+		alternate = !alternate
+	}
+
+	return sum%10 == 0
+}
+
+func BenchmarkEmailGeneration(b *testing.B) {
+	d := NewDeidentifier("benchmark-key")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.generateEmail("test@example.com")
+	}
+}
+
+func BenchmarkTableDeidentification(b *testing.B) {
+	d := NewDeidentifier("benchmark-key")
+
+	table := &Table{
+		Columns: []Column{
+			{Name: "name", DataType: TypeName, Values: make([]interface{}, 1000)},
+			{Name: "email", DataType: TypeEmail, Values: make([]interface{}, 1000)},
+		},
+	}
+
+	// Fill with test data
+	for i := 0; i < 1000; i++ {
+		table.Columns[0].Values[i] = "John Doe"
+		table.Columns[1].Values[i] = "john@company.com"
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := d.Table(table)
+		if err != nil {
+			b.Fatalf("Table failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkTextDeidentification(b *testing.B) {
+	d := NewDeidentifier("benchmark-key")
+
+	text := `Contact John Smith at john.smith@example.com or (555) 123-4567.
+His SSN is 123-45-6789 and he lives at 123 Main Street in New York.
+Please process his payment using credit card 4111-1111-1111-1111.`
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := d.Text(text)
+		if err != nil {
+			b.Fatalf("Text failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkStandardAddressesNonMatching(b *testing.B) {
+	d := NewDeidentifier("benchmark-key")
+	addrRegex := regexp.MustCompile(addressRegexPattern)
+	text := strings.Repeat("12 alpha beta gamma delta, 34 epsilon zeta eta theta, ", 2000)
+
+	b.Run("windowed", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			d.findAddressMatches(text, addrRegex)
+		}
+	})
+	b.Run("full pattern", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			addrRegex.FindAllStringIndex(text, -1)
+		}
+	})
+}
+
+func TestProfile(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	data := [][]string{
+		{"Jane Smith", "jane.smith@example.com", "see notes", ""},
+		{"Bob Jones", "bob@example.org", "call 555-123-4567", ""},
+		{"Mary Major", "mary@example.net", "n/a", ""},
+		{"Ann Lee", "not an email", "", ""},
+	}
+
+	report := d.Profile(data)
+	if report.Rows != 4 || len(report.Columns) != 4 {
+		t.Fatalf("Expected 4 rows and 4 columns, got %d and %d", report.Rows, len(report.Columns))
+	}
+
+	names := report.Columns[0]
+	if names.InferredType != TypeName || names.Confidence != 1 || names.TypeCounts[TypeName] != 4 {
+		t.Errorf("Unexpected name column profile: %+v", names)
+	}
+
+	emails := report.Columns[1]
+	if emails.InferredType != TypeEmail || emails.Confidence != 0.75 || emails.TypeCounts[TypeEmail] != 3 {
+		t.Errorf("Unexpected email column profile: %+v", emails)
+	}
+	if emails.Unmatched != 1 || len(emails.UnmatchedSamples) != 1 || emails.UnmatchedSamples[0] != "not an email" {
+		t.Errorf("Expected the non-email cell as the unmatched sample, got %+v", emails)
+	}
+
+	notes := report.Columns[2]
+	if notes.InferredType != TypeGeneric || notes.Cells != 3 || notes.TypeCounts[TypePhone] != 1 || notes.Unmatched != 2 {
+		t.Errorf("Unexpected free-text column profile: %+v", notes)
+	}
+	if empty := report.Columns[3]; empty.Cells != 0 || empty.Confidence != 0 {
+		t.Errorf("Expected an empty column profile, got %+v", empty)
+	}
+
+	// Profiling leaves the mappings alone
+	if forward, _ := d.LastRunMappings(); len(forward) != 0 {
+		t.Errorf("Expected no mappings after Profile, got %v", forward)
+	}
+}
+
+func TestSlices(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	// Test data as [][]string
+	data := [][]string{
+		{"John Doe", "john.doe@example.com", "555-123-4567", "123-45-6789"},
+		{"Jane Smith", "jane.smith@company.org", "(555) 987-6543", "987-65-4321"},
+		{"Bob Johnson", "bob@test.co.uk", "555.111.2222", "456-78-9012"},
+		{"", "", "", ""}, // Test empty values
+	}
+
+	columnTypes := []DataType{TypeName, TypeEmail, TypePhone, TypeSSN}
+	columnNames := []string{"name", "email", "phone", "ssn"}
+
+	// Test successful deidentification
+	result, err := d.Slices(data, columnTypes, columnNames)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+
+	// Check result dimensions
+	checkSlicesDimensions(t, result, data)
+
+	// Test that non-empty values are deidentified
+	checkNonEmptyValuesDeidentified(t, result, data)
+
+	// Test that empty values remain empty
+	checkEmptyValuesRemainEmpty(t, result)
+
+	// Test deterministic behavior
+	checkDeterministicBehavior(t, d, data, columnTypes, columnNames, result)
+
+	// Test with different column names (should produce different results)
+	checkDifferentColumnNames(t, data, columnTypes, result)
+}
+
+func checkSlicesDimensions(t *testing.T, result, data [][]string) {
+	if len(result) != len(data) {
+		t.Errorf("Expected %d rows, got %d", len(data), len(result))
+	}
+
+	for i, row := range result {
+		if len(row) != len(data[i]) {
+			t.Errorf("Row %d: expected %d columns, got %d", i, len(data[i]), len(row))
+		}
+	}
+}
+
+func checkNonEmptyValuesDeidentified(t *testing.T, result, data [][]string) {
+	if result[0][0] == data[0][0] && data[0][0] != "" {
+		t.Error("Name should be deidentified")
+	}
+	if result[0][1] == data[0][1] && data[0][1] != "" {
+		t.Error("Email should be deidentified")
+	}
+}
+
+func checkEmptyValuesRemainEmpty(t *testing.T, result [][]string) {
+	if result[3][0] != "" || result[3][1] != "" {
+		t.Error("Empty values should remain empty")
+	}
+}
+
+func checkDeterministicBehavior(t *testing.T, d *Deidentifier, data [][]string, columnTypes []DataType, columnNames []string, result [][]string) {
+	result2, err := d.Slices(data, columnTypes, columnNames)
+	if err != nil {
+		t.Fatalf("Second Slices failed: %v", err)
+	}
+
+	if result[0][0] != result2[0][0] {
+		t.Error("Deidentification should be deterministic")
+	}
+}
+
+func checkDifferentColumnNames(t *testing.T, data [][]string, columnTypes []DataType, result [][]string) {
+	// Create a fresh deidentifier to ensure clean mapping table
+	d2 := NewDeidentifier("test-secret-key-3")
+	differentColumnNames := []string{"customer_name", "customer_email", "customer_phone", "customer_ssn"}
+	result3, err := d2.Slices(data, columnTypes, differentColumnNames)
+	if err != nil {
+		t.Fatalf("Third Slices failed: %v", err)
+	}
+
+	if result[0][0] == result3[0][0] && data[0][0] != "" {
+		t.Errorf("Different column names should produce different deidentified values: %s == %s", result[0][0], result3[0][0])
+	}
+}
+
+func TestSlicesInPlace(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	data := [][]string{
+		{"John Doe", "john.doe@example.com", "555-123-4567"},
+		{"Jane Smith", "jane.smith@company.org", ""},
+	}
+	columnTypes := []DataType{TypeName, TypeEmail, TypePhone}
+	columnNames := []string{"name", "email", "phone"}
+
+	expected, err := d.Slices(data, columnTypes, columnNames)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+
+	firstRow := data[0]
+	if err := d.SlicesInPlace(data, columnTypes, columnNames); err != nil {
+		t.Fatalf("SlicesInPlace failed: %v", err)
+	}
+
+	for i, row := range expected {
+		for j, val := range row {
+			if data[i][j] != val {
+				t.Errorf("row %d col %d: expected %q, got %q", i, j, val, data[i][j])
+			}
+		}
+	}
+
+	// The caller's row slices are reused rather than replaced
+	if &firstRow[0] != &data[0][0] {
+		t.Error("SlicesInPlace should write into the existing row slices")
+	}
+
+	if err := d.SlicesInPlace(data, []DataType{TypeName}); err == nil {
+		t.Error("Should error when column types don't match data columns")
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	data := [][]string{{"John Doe", "john@example.com"}}
+	if _, err := d.SlicesContext(ctx, data, []DataType{TypeName, TypeEmail}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SlicesContext: expected context.Canceled, got %v", err)
+	}
+
+	table := &Table{
+		Columns: []Column{
+			{Name: "name", DataType: TypeName, Values: []interface{}{"John Doe"}},
+		},
+	}
+	if _, err := d.TableContext(ctx, table); !errors.Is(err, context.Canceled) {
+		t.Errorf("TableContext: expected context.Canceled, got %v", err)
+	}
+
+	// A live context behaves exactly like the non-context variants
+	result, err := d.TableContext(context.Background(), table)
+	if err != nil {
+		t.Fatalf("TableContext failed: %v", err)
+	}
+	expected, _ := d.Table(table)
+	if result.Columns[0].Values[0] != expected.Columns[0].Values[0] {
+		t.Errorf("TableContext and Table should agree, got %v and %v",
+			result.Columns[0].Values[0], expected.Columns[0].Values[0])
+	}
+}
+
+func TestSlicesWithSchema(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	data := [][]string{
+		{"1", "John Doe", "john@example.com"},
+		{"2", "Jane Smith", "jane@example.com"},
+	}
+	schema := []ColumnSpec{
+		{Name: "id", Type: TypeGeneric},
+		{Name: "name", Type: TypeName},
+		{Name: "email", Type: TypeEmail},
+	}
+
+	result, err := d.SlicesWithSchema(data, schema)
+	if err != nil {
+		t.Fatalf("SlicesWithSchema failed: %v", err)
+	}
+
+	expected, err := d.Slices(data, []DataType{TypeGeneric, TypeName, TypeEmail}, []string{"id", "name", "email"})
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	for i, row := range expected {
+		for j, val := range row {
+			if result[i][j] != val {
+				t.Errorf("row %d col %d: expected %q, got %q", i, j, val, result[i][j])
+			}
+		}
+	}
+
+	if _, err := d.SlicesWithSchema(data, schema[:2]); err == nil {
+		t.Error("Should error when schema doesn't match data columns")
+	}
+}
+
+func TestSlicesInference(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	// Test data with clear patterns
+	data := [][]string{
+		{"john.doe@example.com", "John Doe", "(555) 123-4567", "123-45-6789", "123 Main Street"},
+		{"jane.smith@company.org", "Jane Smith", "555-987-6543", "987-65-4321", "456 Oak Avenue"},
+		{"bob@test.co.uk", "Bob Johnson", "555.111.2222", "456-78-9012", "789 Pine Drive"},
+	}
+
+	// Test with no parameters (should infer)
+	result, err := d.Slices(data)
+	if err != nil {
+		t.Fatalf("Slices with inference failed: %v", err)
+	}
+
+	if len(result) != len(data) {
+		t.Errorf("Expected %d rows, got %d", len(data), len(result))
+	}
+
+	// Test that emails are deidentified (column 0)
+	if result[0][0] == data[0][0] {
+		t.Error("Email should be deidentified")
+	}
+
+	// Test that phone numbers are deidentified (column 2)
+	if result[0][2] == data[0][2] {
+		t.Error("Phone should be deidentified")
+	}
+
+	// Test with empty types slice (should infer)
+	result2, err := d.Slices(data, []DataType{})
+	if err != nil {
+		t.Fatalf("Slices with empty slices failed: %v", err)
+	}
+
+	if len(result2) != len(data) {
+		t.Errorf("Expected %d rows, got %d", len(data), len(result2))
+	}
+}
+
+func TestColumnTypeOverride(t *testing.T) {
+	data := [][]string{
+		{"john@example.com", "ACME-1234", "John Doe"},
+		{"jane@company.org", "ACME-5678", "Jane Smith"},
+	}
+
+	d := NewDeidentifier("test-secret-key", WithColumnTypeOverride(map[int]DataType{1: TypeFormattedID}))
+	result, err := d.Slices(data)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	if id, _ := d.Deidentify("ACME-1234", TypeFormattedID, "column_1"); result[0][1] != id {
+		t.Errorf("overridden column should use TypeFormattedID, got %q want %q", result[0][1], id)
+	}
+	if email, _ := d.Deidentify("john@example.com", TypeEmail, "column_0"); result[0][0] != email {
+		t.Errorf("other columns should keep their inferred type, got %q want %q", result[0][0], email)
+	}
+
+	outOfRange := NewDeidentifier("test-secret-key", WithColumnTypeOverride(map[int]DataType{3: TypeName}))
+	if _, err := outOfRange.Slices(data); err == nil {
+		t.Error("expected an error for an override index outside the data")
+	}
+	if _, err := outOfRange.Slices(data, []DataType{TypeEmail, TypeGeneric, TypeName}); err != nil {
+		t.Errorf("overrides should not apply to explicit types, got %v", err)
+	}
+}
+
+func TestInferColumnTypes(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	testCases := []struct {
+		name     string
+		data     [][]string
+		expected []DataType
+	}{
+		{
+			name: "Clear patterns",
+			data: [][]string{
+				{"john@example.com", "John Doe", "(555) 123-4567"},
+				{"jane@company.org", "Jane Smith", "555-987-6543"},
+			},
+			expected: []DataType{TypeEmail, TypeName, TypePhone},
+		},
+		{
+			name: "Mixed with some empty",
+			data: [][]string{
+				{"test@example.com", "", "123-45-6789"},
+				{"", "Bob Smith", "987-65-4321"},
+				{"admin@test.org", "Alice Brown", ""},
+			},
+			expected: []DataType{TypeEmail, TypeName, TypeSSN},
+		},
+		{
+			name: "International addresses",
+			data: [][]string{
+				{"Hauptstraße 5, 10115 Berlin, Germany", "Jane Smith"},
+				{"Kungsgatan 12, 111 43 Stockholm, Sweden", "Bob Johnson"},
+				{"1-2-3 Shibuya, Tokyo, Japan", "Alice Brown"},
+				{"Piazza Navona 3, Rome, Italy", "John Doe"},
+			},
+			expected: []DataType{TypeAddress, TypeName},
+		},
+		{
+			name: "Generic fallback",
+			data: [][]string{
+				{"random text", "123ABC", "unknown format"},
+				{"more text", "XYZ789", "another format"},
+			},
+			expected: []DataType{TypeGeneric, TypeGeneric, TypeGeneric},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := d.inferColumnTypes(tc.data)
+			if err != nil {
+				t.Fatalf("inferColumnTypes failed: %v", err)
+			}
+
+			if len(result) != len(tc.expected) {
+				t.Fatalf("Expected %d types, got %d", len(tc.expected), len(result))
+			}
+
+			for i, expected := range tc.expected {
+				if result[i] != expected {
+					t.Errorf("Column %d: expected %v, got %v", i, expected, result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestColumnSampler(t *testing.T) {
+	// The first rows of a non-uniform column are placeholders; real emails follow
+	data := make([][]string, 100)
+	values := make([]interface{}, 100)
+	for i := range data {
+		value := "pending"
+		if i >= 20 {
+			value = fmt.Sprintf("user%d@example.com", i)
+		}
+		data[i] = []string{value}
+		values[i] = value
+	}
+	values[50] = nil
+
+	types, err := NewDeidentifier("test-secret-key").inferColumnTypes(data)
+	if err != nil || types[0] != TypeGeneric {
+		t.Fatalf("Expected head sampling to see only placeholders, got %v (%v)", types, err)
+	}
+
+	var sampled []int
+	everyTenth := func(rows int) []int {
+		sampled = sampled[:0]
+		for i := 5; i < rows; i += 10 {
+			sampled = append(sampled, i)
+		}
+		return append(sampled, -1, rows) // out of range, ignored
+	}
+	d := NewDeidentifier("test-secret-key", WithColumnSampler(everyTenth))
+	if types, _ := d.inferColumnTypes(data); types[0] != TypeEmail {
+		t.Errorf("Expected the sampled rows to reveal emails, got %v", types)
+	}
+
+	table, err := d.Table(&Table{Columns: []Column{{Name: "contact", Values: values, DataType: TypeInfer}}})
+	if err != nil {
+		t.Fatalf("Table failed: %v", err)
+	}
+	if table.Columns[0].DataType != TypeEmail || table.Columns[0].Values[25] == values[25] {
+		t.Errorf("Expected the Table column to be inferred as emails, got %v", table.Columns[0].DataType)
+	}
+}
+
+func TestReplacementLengthCap(t *testing.T) {
+	d := NewDeidentifier("test-secret-key",
+		WithReplacementLengthCap(TypeAddress, 10),
+		WithReplacementLengthCap(TypeName, 12))
+	data := [][]string{
+		{"123 Mountain View Avenue", "Alexander Montgomery"},
+		{"9876 Long Boulevard Road", "John Smith"},
+		{"1 Elm St", "Maria Gonzalez"},
+	}
+	result, err := d.Slices(data, []DataType{TypeAddress, TypeName}, []string{"address", "name"})
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	for i, row := range result {
+		if len(row[0]) > 10 || !regexp.MustCompile(`^\d+ \S+`).MatchString(row[0]) {
+			t.Errorf("Row %d: expected a whole address of at most 10 characters, got %q", i, row[0])
+		}
+		if len(row[1]) > 12 || len(strings.Fields(row[1])) != 2 {
+			t.Errorf("Row %d: expected a full name of at most 12 characters, got %q", i, row[1])
+		}
+	}
+
+	// Uncapped types and instances are unaffected
+	email, _ := d.Email("john@example.com")
+	uncapped, _ := NewDeidentifier("test-secret-key").Email("john@example.com")
+	if email != uncapped {
+		t.Errorf("Expected uncapped emails to be unchanged, got %q and %q", email, uncapped)
+	}
+
+	// Caps on types without pools are ignored, so values keep a valid shape
+	capped := NewDeidentifier("test-secret-key", WithReplacementLengthCap(TypeEmail, 12), WithReplacementLengthCap(TypeSSN, 5))
+	if email, _ := capped.Email("john@example.com"); email != uncapped {
+		t.Errorf("Expected the email cap to be ignored, got %q", email)
+	}
+	if ssn, _ := capped.SSN("123-45-6789"); len(ssn) != 11 {
+		t.Errorf("Expected the SSN cap to be ignored, got %q", ssn)
+	}
+
+	// Without a fitting pool entry, values are cut at a word boundary
+	if got := d.capLength("12 Mountain View Ave", TypeAddress); got != "12" {
+		t.Errorf("Expected a cut at the word boundary, got %q", got)
+	}
+}
+
+func TestDateTime(t *testing.T) {
+	events := []string{"2024-03-15T09:30:00Z", "2024-03-15T09:45:10Z", "2024-03-16T08:00:00Z", "2024-03-20 17:05:00"}
+
+	d := NewDeidentifier("test-secret-key", WithConstantDateShift())
+	shift := time.Duration(d.dateShiftDays()) * 24 * time.Hour
+	var previous time.Time
+	for i, event := range events {
+		result, err := d.DateTime(event)
+		if err != nil {
+			t.Fatalf("DateTime(%q) failed: %v", event, err)
+		}
+		layout := time.RFC3339
+		if i == 3 {
+			layout = "2006-01-02 15:04:05"
+		}
+		original, _ := time.Parse(layout, event)
+		shifted, err := time.Parse(layout, result)
+		if err != nil {
+			t.Fatalf("Expected %q to keep its layout, got %q", event, result)
+		}
+		if shifted.Sub(original) != shift {
+			t.Errorf("Expected %q to move by %v, got %q", event, shift, result)
+		}
+		if !shifted.After(previous) {
+			t.Errorf("Expected ordering to be kept at %q", result)
+		}
+		previous = shifted
+	}
+
+	// Unix times shift by the same number of days
+	if result, _ := d.DateTime("1710495000"); result != fmt.Sprintf("%d", 1710495000+int64(shift.Seconds())) {
+		t.Errorf("Expected a shifted Unix time, got %q", result)
+	}
+	if result, _ := d.DateTime("1710495000123"); result != fmt.Sprintf("%d", 1710495000123+shift.Milliseconds()) {
+		t.Errorf("Expected a shifted Unix time in milliseconds, got %q", result)
+	}
+
+	// Per-value shifts are deterministic, within 30 days and not all equal
+	perValue := NewDeidentifier("test-secret-key")
+	offsets := make(map[time.Duration]bool)
+	for _, event := range events[:3] {
+		result, _ := perValue.DateTime(event)
+		again, _ := NewDeidentifier("test-secret-key").DateTime(event)
+		original, _ := time.Parse(time.RFC3339, event)
+		shifted, _ := time.Parse(time.RFC3339, result)
+		offset := shifted.Sub(original)
+		if result != again || offset == 0 || offset.Abs() > 30*24*time.Hour {
+			t.Errorf("Expected a stable shift of up to 30 days for %q, got %q and %q", event, result, again)
+		}
+		offsets[offset] = true
+	}
+	if len(offsets) < 2 {
+		t.Errorf("Expected per-value shifts to differ, got %v", offsets)
+	}
+
+	if _, err := perValue.DateTime("next week"); err == nil {
+		t.Error("Expected an error for a value that is not a date")
+	}
+	result, err := perValue.Slices([][]string{{"2024-03-15"}}, []DataType{TypeDateTime})
+	if err != nil || result[0][0] == "2024-03-15" || len(result[0][0]) != len("2024-03-15") {
+		t.Errorf("Expected a shifted date column, got %v (%v)", result, err)
+	}
+}
+
+func TestFormatMismatchHandler(t *testing.T) {
+	data := [][]string{
+		{"Frodo Baggins", "555-123-4567"},
+		{"12345", "call me"},
+		{"Samwise Gamgee", ""},
+	}
+	types := []DataType{TypeName, TypePhone}
+	names := []string{"name", "phone"}
+
+	var quarantined []string
+	d := NewDeidentifier("test-secret-key", WithFormatMismatchHandler(func(value string, dataType DataType, column string) string {
+		quarantined = append(quarantined, column+"="+value)
+		return "[INVALID]"
+	}))
+	result, err := d.Slices(data, types, names)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	if result[1][0] != "[INVALID]" || result[1][1] != "[INVALID]" {
+		t.Errorf("Expected mismatched values to be quarantined, got %v", result[1])
+	}
+	if strings.Join(quarantined, ",") != "name=12345,phone=call me" {
+		t.Errorf("Expected the handler to see the mismatched values, got %v", quarantined)
+	}
+	frodo, _ := d.Name("Frodo Baggins")
+	if result[0][0] != frodo || result[2][1] != "" {
+		t.Errorf("Expected conforming and empty values to be processed as usual, got %v", result)
+	}
+
+	// A nil handler leaves mismatched values as they are
+	kept := NewDeidentifier("test-secret-key", WithFormatMismatchHandler(nil))
+	if result, _ := kept.Deidentify("12345", TypeName, "name"); result != "12345" {
+		t.Errorf("Expected the numeric name to be kept, got %q", result)
+	}
+	if result, _ := kept.Deidentify("frodo@shire", TypeEmail, "email"); result != "frodo@shire" {
+		t.Errorf("Expected the malformed email to be kept, got %q", result)
+	}
+
+	// Without the option a fake is still generated, and Text is never affected
+	if result, _ := NewDeidentifier("test-secret-key").Deidentify("12345", TypeName, "name"); result == "12345" {
+		t.Error("Expected a fake name by default")
+	}
+	if result, _ := kept.Text("Call Frodo Baggins"); result == "Call Frodo Baggins" {
+		t.Error("Expected Text to replace names as usual")
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	d := NewDeidentifier("test-secret-key",
+		WithChecksum("account", ChecksumMod10),
+		WithChecksum("isbn", ChecksumMod11))
+
+	// Mod-10 (Luhn): doubling every second digit from the right gives a multiple of 10
+	luhnValid := func(id string) bool {
+		sum := 0
+		digits := strings.Map(func(r rune) rune {
+			if r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, id)
+		for i := range digits {
+			digit := int(digits[len(digits)-1-i] - '0')
+			if i%2 == 1 {
+				if digit *= 2; digit > 9 {
+					digit -= 9
+				}
+			}
+			sum += digit
+		}
+		return sum%10 == 0
+	}
+	for _, account := range []string{"ACC-4539-1488-0343-6467", "ACC-0000-0000-0000-0018", "79927398713"} {
+		result, err := d.Deidentify(account, TypeFormattedID, "account")
+		if err != nil {
+			t.Fatalf("Deidentify failed: %v", err)
+		}
+		if result == account || len(result) != len(account) || !luhnValid(result) {
+			t.Errorf("Expected a Luhn-valid fake with the shape of %s, got %s", account, result)
+		}
+	}
+
+	// Mod-11: weights 10..1 from the left sum to a multiple of 11, with X standing for 10
+	mod11Valid := func(id string) bool {
+		chars := strings.ReplaceAll(id, "-", "")
+		sum := 0
+		for i, c := range chars {
+			value := int(c - '0')
+			if c == 'X' {
+				value = 10
+			}
+			sum += value * (len(chars) - i)
+		}
+		return sum%11 == 0
+	}
+	sawX := false
+	for i := 0; i < 50; i++ {
+		isbn := fmt.Sprintf("0-306-%05d-%d", 40615+i, i%10)
+		result, err := d.Deidentify(isbn, TypeFormattedID, "isbn")
+		if err != nil {
+			t.Fatalf("Deidentify failed: %v", err)
+		}
+		if result[:len(result)-1] == isbn[:len(isbn)-1] || !mod11Valid(result) {
+			t.Errorf("Expected a mod-11-valid fake for %s, got %s", isbn, result)
+		}
+		sawX = sawX || strings.HasSuffix(result, "X")
+	}
+	if !sawX {
+		t.Error("Expected some mod-11 check values of 10 to be written as X")
+	}
+	// The check digit is applied before the fake is compared with the original, so it
+	// cannot turn a short ID back into itself
+	short := NewDeidentifier("k", WithChecksum("id", ChecksumMod10))
+	for _, id := range []string{"A-26", "A-5199", "A-7955"} {
+		if result, _ := short.Column("id", []string{id}, TypeFormattedID); result[0] == id {
+			t.Errorf("Expected %s to be replaced, got it back", id)
+		}
+	}
+
+	first, _ := d.Deidentify("0-306-40615-0", TypeFormattedID, "isbn")
+	fresh := NewDeidentifier("test-secret-key", WithChecksum("isbn", ChecksumMod11))
+	if again, _ := fresh.Deidentify("0-306-40615-0", TypeFormattedID, "isbn"); again != first {
+		t.Errorf("Expected deterministic results, got %s and %s", first, again)
+	}
+
+	// Other columns keep the plain shape-preserving fake
+	plain, _ := NewDeidentifier("test-secret-key").Deidentify("0-306-40615-0", TypeFormattedID, "other")
+	if other, _ := d.Deidentify("0-306-40615-0", TypeFormattedID, "other"); other != plain {
+		t.Errorf("Expected no checksum outside configured columns, got %s want %s", other, plain)
 	}
 }
 
-func checkDeterministicBehavior(t *testing.T, d *Deidentifier, data [][]string, columnTypes []DataType, columnNames []string, result [][]string) {
-	result2, err := d.Slices(data, columnTypes, columnNames)
-	if err != nil {
-		t.Fatalf("Second Slices failed: %v", err)
+func TestVersionTag(t *testing.T) {
+	plain := NewDeidentifier("test-secret-key")
+	d := NewDeidentifier("test-secret-key", WithVersionTag())
+
+	email, _ := plain.Email("frodo@shire.me")
+	tagged, _ := d.Email("frodo@shire.me")
+	at := strings.Index(email, "@")
+	if tagged != email[:at]+"."+GeneratorVersion+email[at:] {
+		t.Errorf("Expected %s tagged with %s, got %s", email, GeneratorVersion, tagged)
 	}
 
-	if result[0][0] != result2[0][0] {
-		t.Error("Deidentification should be deterministic")
+	handle, _ := plain.Username("@frodo_b")
+	if result, _ := d.Username("@frodo_b"); result != handle+"_"+GeneratorVersion {
+		t.Errorf("Expected %s tagged with %s, got %s", handle, GeneratorVersion, result)
+	}
+	if result := d.generateGeneric("x"); result != plain.generateGeneric("x")+"_"+GeneratorVersion {
+		t.Errorf("Expected a tagged generic token, got %s", result)
 	}
-}
 
-func checkDifferentColumnNames(t *testing.T, data [][]string, columnTypes []DataType, result [][]string) {
-	// Create a fresh deidentifier to ensure clean mapping table
-	d2 := NewDeidentifier("test-secret-key-3")
-	differentColumnNames := []string{"customer_name", "customer_email", "customer_phone", "customer_ssn"}
-	result3, err := d2.Slices(data, columnTypes, differentColumnNames)
-	if err != nil {
-		t.Fatalf("Third Slices failed: %v", err)
+	// Formats without room for a tag are unchanged
+	name, _ := plain.Name("Frodo Baggins")
+	if result, _ := d.Name("Frodo Baggins"); result != name {
+		t.Errorf("Expected names to be untagged, got %s", result)
 	}
 
-	if result[0][0] == result3[0][0] && data[0][0] != "" {
-		t.Errorf("Different column names should produce different deidentified values: %s == %s", result[0][0], result3[0][0])
+	// Tagged emails are still recognized as fakes
+	skip := NewDeidentifier("test-secret-key", WithVersionTag(), WithSkipAlreadyFake())
+	if result, _ := skip.Email(tagged); result != tagged {
+		t.Errorf("Expected a tagged fake to be skipped, got %s", result)
 	}
 }
 
-func TestSlicesInference(t *testing.T) {
-	d := NewDeidentifier("test-secret-key")
-
-	// Test data with clear patterns
-	data := [][]string{
-		{"john.doe@example.com", "John Doe", "(555) 123-4567", "123-45-6789", "123 Main Street"},
-		{"jane.smith@company.org", "Jane Smith", "555-987-6543", "987-65-4321", "456 Oak Avenue"},
-		{"bob@test.co.uk", "Bob Johnson", "555.111.2222", "456-78-9012", "789 Pine Drive"},
+func TestControlSampleRate(t *testing.T) {
+	data := make([][]string, 200)
+	for i := range data {
+		data[i] = []string{fmt.Sprintf("%d", 1000+i), fmt.Sprintf("user%d@example.com", i)}
 	}
+	names := []string{"patient_id", "email"}
+	types := []DataType{TypeGeneric, TypeEmail}
 
-	// Test with no parameters (should infer)
-	result, err := d.Slices(data)
+	d := NewDeidentifier("test-secret-key", WithControlSampleRate(0.25, "patient_id"))
+	result, err := d.Slices(data, types, names)
 	if err != nil {
-		t.Fatalf("Slices with inference failed: %v", err)
+		t.Fatalf("Slices failed: %v", err)
 	}
-
-	if len(result) != len(data) {
-		t.Errorf("Expected %d rows, got %d", len(data), len(result))
+	var control []string
+	for i, row := range result {
+		if row[1] == data[i][1] {
+			control = append(control, row[0])
+		}
 	}
-
-	// Test that emails are deidentified (column 0)
-	if result[0][0] == data[0][0] {
-		t.Error("Email should be deidentified")
+	if len(control) < 25 || len(control) > 75 {
+		t.Errorf("Expected about a quarter of 200 rows in the control group, got %d", len(control))
 	}
 
-	// Test that phone numbers are deidentified (column 2)
-	if result[0][2] == data[0][2] {
-		t.Error("Phone should be deidentified")
+	// The same keys form the control group on every run, with a fresh instance and
+	// with the rows shuffled
+	reversed := make([][]string, len(data))
+	for i, row := range data {
+		reversed[len(data)-1-i] = row
+	}
+	again, err := NewDeidentifier("test-secret-key", WithControlSampleRate(0.25, "patient_id")).Slices(reversed, types, names)
+	if err != nil {
+		t.Fatalf("Slices failed: %v", err)
+	}
+	var controlAgain []string
+	for i := len(again) - 1; i >= 0; i-- {
+		if again[i][1] == reversed[i][1] {
+			controlAgain = append(controlAgain, again[i][0])
+		}
+	}
+	if strings.Join(control, ",") != strings.Join(controlAgain, ",") {
+		t.Errorf("Expected a stable control group, got %v and %v", control, controlAgain)
 	}
 
-	// Test with empty types slice (should infer)
-	result2, err := d.Slices(data, []DataType{})
+	// Table picks the same rows
+	ids := make([]interface{}, len(data))
+	emails := make([]interface{}, len(data))
+	for i, row := range data {
+		ids[i], emails[i] = row[0], row[1]
+	}
+	table, err := d.Table(&Table{Columns: []Column{
+		{Name: "patient_id", DataType: TypeGeneric, Values: ids},
+		{Name: "email", DataType: TypeEmail, Values: emails},
+	}})
 	if err != nil {
-		t.Fatalf("Slices with empty slices failed: %v", err)
+		t.Fatalf("Table failed: %v", err)
+	}
+	for i, value := range table.Columns[1].Values {
+		if (value == emails[i]) != (result[i][1] == data[i][1]) {
+			t.Errorf("Row %d: expected Table and Slices to agree on the control group", i)
+		}
 	}
 
-	if len(result2) != len(data) {
-		t.Errorf("Expected %d rows, got %d", len(data), len(result2))
+	if _, err := NewDeidentifier("test-secret-key", WithControlSampleRate(0.25, "missing")).Slices(data, types, names); err == nil {
+		t.Error("Expected an error for an unknown control key column")
+	}
+	if result, _ := NewDeidentifier("test-secret-key", WithControlSampleRate(0)).Slices(data, types, names); result[0][1] == data[0][1] {
+		t.Error("Expected a zero rate to leave no rows unchanged")
 	}
 }
 
-func TestInferColumnTypes(t *testing.T) {
-	d := NewDeidentifier("test-secret-key")
+// fakeSQLDriver serves one fixed result set to every query, standing in for a database
+type fakeSQLDriver struct {
+	columns []string
+	rows    [][]driver.Value
+}
 
-	testCases := []struct {
-		name     string
-		data     [][]string
-		expected []DataType
-	}{
-		{
-			name: "Clear patterns",
-			data: [][]string{
-				{"john@example.com", "John Doe", "(555) 123-4567"},
-				{"jane@company.org", "Jane Smith", "555-987-6543"},
-			},
-			expected: []DataType{TypeEmail, TypeName, TypePhone},
-		},
-		{
-			name: "Mixed with some empty",
-			data: [][]string{
-				{"test@example.com", "", "123-45-6789"},
-				{"", "Bob Smith", "987-65-4321"},
-				{"admin@test.org", "Alice Brown", ""},
-			},
-			expected: []DataType{TypeEmail, TypeName, TypeSSN},
-		},
-		{
-			name: "Generic fallback",
-			data: [][]string{
-				{"random text", "123ABC", "unknown format"},
-				{"more text", "XYZ789", "another format"},
-			},
-			expected: []DataType{TypeGeneric, TypeGeneric, TypeGeneric},
-		},
+type fakeSQLConn struct{ driver *fakeSQLDriver }
+
+type fakeSQLStmt struct{ driver *fakeSQLDriver }
+
+type fakeSQLRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (f *fakeSQLDriver) Open(string) (driver.Conn, error) { return &fakeSQLConn{f}, nil }
+
+func (c *fakeSQLConn) Prepare(string) (driver.Stmt, error) { return &fakeSQLStmt{c.driver}, nil }
+func (c *fakeSQLConn) Close() error                        { return nil }
+func (c *fakeSQLConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+func (s *fakeSQLStmt) Close() error  { return nil }
+func (s *fakeSQLStmt) NumInput() int { return -1 }
+func (s *fakeSQLStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s *fakeSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeSQLRows{columns: s.driver.columns, rows: s.driver.rows}, nil
+}
+
+func (r *fakeSQLRows) Columns() []string { return r.columns }
+func (r *fakeSQLRows) Close() error      { return nil }
+func (r *fakeSQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
 	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			result, err := d.inferColumnTypes(tc.data)
-			if err != nil {
-				t.Fatalf("inferColumnTypes failed: %v", err)
-			}
+func TestRows(t *testing.T) {
+	sql.Register("deidentify-fake", &fakeSQLDriver{
+		columns: []string{"id", "name", "contact", "note"},
+		rows: [][]driver.Value{
+			{int64(1), "Frodo Baggins", "frodo@shire.me", nil},
+			{int64(2), "Samwise Gamgee", "sam@shire.me", []byte("likes potatoes")},
+			{int64(3), "Meriadoc Brandybuck", "merry@buckland.me", "second breakfast"},
+		},
+	})
+	db, err := sql.Open("deidentify-fake", "")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer db.Close()
 
-			if len(result) != len(tc.expected) {
-				t.Fatalf("Expected %d types, got %d", len(tc.expected), len(result))
-			}
+	rows, err := db.Query("SELECT id, name, contact, note FROM hobbits")
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	d := NewDeidentifier("test-secret-key")
+	result, err := d.Rows(rows, map[string]DataType{"name": TypeName})
+	if err != nil {
+		t.Fatalf("Rows failed: %v", err)
+	}
+	if len(result) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(result))
+	}
 
-			for i, expected := range tc.expected {
-				if result[i] != expected {
-					t.Errorf("Column %d: expected %v, got %v", i, expected, result[i])
-				}
-			}
-		})
+	frodo, _ := d.Deidentify("Frodo Baggins", TypeName, "name")
+	sam, _ := d.Deidentify("sam@shire.me", TypeEmail, "contact")
+	expected := [][]string{
+		{"1", frodo, result[0][2], ""},
+		{"2", result[1][1], sam, "likes potatoes"},
+	}
+	for i, row := range expected {
+		if strings.Join(result[i], "|") != strings.Join(row, "|") {
+			t.Errorf("Row %d: expected %v, got %v", i, row, result[i])
+		}
+	}
+	if !strings.Contains(result[2][2], "@") || result[2][2] == "merry@buckland.me" {
+		t.Errorf("Expected the inferred email column to be replaced, got %q", result[2][2])
 	}
 }
 
@@ -806,6 +4578,306 @@ func TestTableGenericTypePreservesValues(t *testing.T) {
 	}
 }
 
+func TestSlicesChan(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	schema := []ColumnSpec{{Name: "name", Type: TypeName}, {Name: "email", Type: TypeEmail}}
+	rows := [][]string{
+		{"John Doe", "john@company.com"},
+		{"Jane Smith", ""},
+		{"John Doe", "john@company.com"},
+	}
+
+	in := make(chan []string)
+	out := make(chan []string)
+	errc := make(chan error, 1)
+	go func() { errc <- d.SlicesChan(in, out, schema) }()
+	go func() {
+		for _, row := range rows {
+			in <- row
+		}
+		close(in)
+	}()
+
+	var results [][]string
+	for row := range out {
+		results = append(results, row)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("SlicesChan failed: %v", err)
+	}
+
+	expected, err := d.SlicesWithSchema(rows, schema)
+	if err != nil {
+		t.Fatalf("SlicesWithSchema failed: %v", err)
+	}
+	if fmt.Sprint(results) != fmt.Sprint(expected) {
+		t.Errorf("streamed rows %v should match batch rows %v", results, expected)
+	}
+
+	// A row that does not fit the schema stops processing and closes out
+	in = make(chan []string, 1)
+	out = make(chan []string, 1)
+	in <- []string{"only one column"}
+	if err := d.SlicesChan(in, out, schema); err == nil {
+		t.Error("expected an error for a row that does not match the schema")
+	}
+	if _, open := <-out; open {
+		t.Error("out should be closed after an error")
+	}
+
+	// Cancellation stops a processor waiting for rows
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := d.SlicesChanContext(ctx, make(chan []string), make(chan []string), schema); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestTableEmptyAndNilHandling(t *testing.T) {
+	table := &Table{
+		Columns: []Column{
+			{Name: "email", DataType: TypeEmail, Values: []interface{}{"john@company.com", "", nil}},
+			{Name: "status", DataType: TypeGeneric, Values: []interface{}{"active", "", nil}},
+		},
+	}
+
+	for _, tc := range []struct {
+		name          string
+		opts          []Option
+		empty, absent interface{}
+	}{
+		{"default", nil, "", nil},
+		{"empty as nil", []Option{WithEmptyAsNil()}, nil, nil},
+		{"nil as empty", []Option{WithNilAsEmpty()}, "", ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := NewDeidentifier("test-secret-key", tc.opts...).Table(table)
+			if err != nil {
+				t.Fatalf("Table failed: %v", err)
+			}
+			for _, col := range result.Columns {
+				if col.Values[0] == nil || col.Values[0] == "" {
+					t.Errorf("column %s: non-empty value lost, got %v", col.Name, col.Values[0])
+				}
+				if col.Values[1] != tc.empty {
+					t.Errorf("column %s: empty string became %#v, want %#v", col.Name, col.Values[1], tc.empty)
+				}
+				if col.Values[2] != tc.absent {
+					t.Errorf("column %s: nil became %#v, want %#v", col.Name, col.Values[2], tc.absent)
+				}
+			}
+		})
+	}
+}
+
+func TestColumn(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+
+	emails := []string{"john@company.com", "", "jane@company.com", "john@company.com"}
+	inferred, err := d.Column("contact", emails)
+	if err != nil {
+		t.Fatalf("Column failed: %v", err)
+	}
+	want, _ := d.Deidentify("john@company.com", TypeEmail, "contact")
+	if inferred[0] != want || inferred[3] != want {
+		t.Errorf("inferred email column should share the column mapping, got %v want %q", inferred, want)
+	}
+	if inferred[1] != "" {
+		t.Errorf("empty values should stay empty, got %q", inferred[1])
+	}
+
+	explicit, err := d.Column("customer", []string{"John Doe"}, TypeName)
+	if err != nil {
+		t.Fatalf("Column failed: %v", err)
+	}
+	if name, _ := d.Deidentify("John Doe", TypeName, "customer"); explicit[0] != name {
+		t.Errorf("explicit type should be used, got %q want %q", explicit[0], name)
+	}
+
+	if result, err := d.Column("empty", nil); err != nil || result != nil {
+		t.Errorf("nil values should give nil, got %v, %v", result, err)
+	}
+	if _, err := d.Column("bad", emails, TypeEmail, TypeName); err == nil {
+		t.Error("expected an error for more than one data type")
+	}
+}
+
+func TestUncertainRedaction(t *testing.T) {
+	input := "Ref AB12345X for John Smith, mail john@localhost, order 12345, email frodo@shire.me."
+
+	// Off by default: unclassified tokens are kept
+	if got, _ := NewDeidentifier("test-secret-key").Text(input); !strings.Contains(got, "AB12345X") || !strings.Contains(got, "john@localhost") {
+		t.Errorf("Expected uncertain tokens to be kept by default, got %q", got)
+	}
+
+	d := NewDeidentifier("test-secret-key", WithUncertainRedaction(5))
+	result, err := d.Text(input)
+	if err != nil {
+		t.Fatalf("Text() error = %v", err)
+	}
+	for _, leaked := range []string{"AB12345X", "john@localhost", "John Smith", "frodo@shire.me"} {
+		if strings.Contains(result, leaked) {
+			t.Errorf("Expected %q to be redacted, got %q", leaked, result)
+		}
+	}
+	if !regexp.MustCompile(`^Ref DATA_[0-9a-f]{16} for `).MatchString(result) || !strings.Contains(result, ", order 12345,") {
+		t.Errorf("Expected a generic token for the ID and short numbers kept, got %q", result)
+	}
+	email, _ := d.Email("frodo@shire.me")
+	if !strings.Contains(result, email) {
+		t.Errorf("Expected detected values to keep their realistic replacement, got %q", result)
+	}
+
+	// A lower threshold trades precision for recall
+	strict, _ := NewDeidentifier("test-secret-key", WithUncertainRedaction(4)).Text(input)
+	if strings.Contains(strict, "12345") {
+		t.Errorf("Expected a threshold of 4 to also redact 5-digit numbers, got %q", strict)
+	}
+}
+
+func TestDiffMappings(t *testing.T) {
+	values := map[string][]string{
+		"email":   {"frodo@shire.me", "sam@shire.me", "pippin@shire.me"},
+		"address": {"123 Main Street", "42 Elm Road", "7 Oak Avenue"},
+	}
+	d := NewDeidentifier("test-secret-key")
+
+	same := d.DiffMappings(NewDeidentifier("test-secret-key"), values)
+	if same["email"] != 0 || same["address"] != 0 {
+		t.Errorf("Expected no drift between identical configurations, got %v", same)
+	}
+
+	rekeyed := d.DiffMappings(NewDeidentifier("other-secret-key"), values)
+	if rekeyed["email"] != 3 || rekeyed["address"] != 3 {
+		t.Errorf("Expected every value to drift under a new key, got %v", rekeyed)
+	}
+
+	// An option only moves the types it affects
+	capped := d.DiffMappings(NewDeidentifier("test-secret-key", WithMaxStreetNumber(99)), values)
+	if capped["email"] != 0 || capped["address"] == 0 {
+		t.Errorf("Expected only addresses to drift with a smaller street number range, got %v", capped)
+	}
+
+	if d.getMapping("email", "frodo@shire.me") != "" {
+		t.Error("DiffMappings should not store mappings")
+	}
+}
+
+func TestFindingsOriginalHash(t *testing.T) {
+	d := NewDeidentifier("test-secret-key", WithOriginalHash())
+	input := "Email frodo@shire.me or call (555) 123-4567. Frodo Baggins wrote from frodo@shire.me."
+
+	result, findings, err := d.TextWithFindings(input)
+	if err != nil {
+		t.Fatalf("TextWithFindings failed: %v", err)
+	}
+	if plain, _ := d.Text(input); plain != result {
+		t.Errorf("Expected the same result as Text, got %q and %q", result, plain)
+	}
+	hashes := map[DataType][]string{}
+	for _, f := range findings {
+		if !strings.Contains(result, f.Replacement) {
+			t.Errorf("Finding replacement %q not in result %q", f.Replacement, result)
+		}
+		if !regexp.MustCompile(`^[0-9a-f]{64}$`).MatchString(f.OriginalHash) || strings.Contains(f.OriginalHash, "frodo") {
+			t.Errorf("Expected a hex HMAC as OriginalHash, got %q", f.OriginalHash)
+		}
+		hashes[f.Type] = append(hashes[f.Type], f.OriginalHash)
+	}
+	if len(hashes[TypeEmail]) != 2 || hashes[TypeEmail][0] != hashes[TypeEmail][1] {
+		t.Errorf("Expected both email mentions to share a hash, got %v", hashes[TypeEmail])
+	}
+	if len(hashes[TypePhone]) != 1 || len(hashes[TypeName]) != 1 {
+		t.Errorf("Expected one phone and one name finding, got %v", findings)
+	}
+
+	// Columns give one finding per value, with hashes that match across columns and calls
+	values := []string{"frodo@shire.me", "", "sam@shire.me", "frodo@shire.me"}
+	replaced, columnFindings, err := d.ColumnWithFindings("contact", values, TypeEmail)
+	if err != nil || len(columnFindings) != len(values) {
+		t.Fatalf("ColumnWithFindings = %v, %v; want %d findings", columnFindings, err, len(values))
+	}
+	if columnFindings[0].OriginalHash != hashes[TypeEmail][0] || columnFindings[3].OriginalHash != columnFindings[0].OriginalHash {
+		t.Errorf("Expected equal originals to share a hash, got %v", columnFindings)
+	}
+	if columnFindings[2].OriginalHash == columnFindings[0].OriginalHash || columnFindings[1] != (Finding{}) {
+		t.Errorf("Expected distinct hashes and an empty finding for the empty value, got %v", columnFindings)
+	}
+	if columnFindings[2].Replacement != replaced[2] || columnFindings[2].Type != TypeEmail {
+		t.Errorf("Expected the finding to describe the replacement, got %v", columnFindings[2])
+	}
+
+	// Without the option no hash is emitted
+	_, findings, _ = NewDeidentifier("test-secret-key").TextWithFindings(input)
+	if len(findings) == 0 || findings[0].OriginalHash != "" {
+		t.Errorf("Expected findings without hashes by default, got %v", findings)
+	}
+}
+
+func TestTableTypeInfer(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	table := &Table{
+		Columns: []Column{
+			{Name: "customer", DataType: TypeName, Values: []interface{}{"John Doe", "Jane Smith"}},
+			{Name: "contact", DataType: TypeInfer, Values: []interface{}{"john@company.com", nil, "jane@company.com"}},
+		},
+	}
+
+	result, err := d.Table(table)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Columns[0].DataType != TypeName {
+		t.Errorf("explicit column type should be kept, got %d", result.Columns[0].DataType)
+	}
+	if result.Columns[1].DataType != TypeEmail {
+		t.Fatalf("inferred column type should be TypeEmail, got %d", result.Columns[1].DataType)
+	}
+
+	// Inferred columns share mappings with explicitly typed ones
+	expected, _ := d.Deidentify("john@company.com", TypeEmail, "contact")
+	if got := result.Columns[1].Values[0]; got != expected {
+		t.Errorf("expected %q, got %v", expected, got)
+	}
+	if result.Columns[1].Values[1] != nil {
+		t.Errorf("nil values should stay nil, got %v", result.Columns[1].Values[1])
+	}
+}
+
+func TestTypeInferOutsideTable(t *testing.T) {
+	d := NewDeidentifier("test-secret-key")
+	email, _ := d.Deidentify("john@company.com", TypeEmail, "contact")
+
+	if got, err := d.Deidentify("john@company.com", TypeInfer, "contact"); err != nil || got != email {
+		t.Errorf("Deidentify with TypeInfer = %q, %v, want %q", got, err, email)
+	}
+
+	data := [][]string{{"John Doe", "john@company.com"}, {"Jane Smith", "jane@company.com"}}
+	columnTypes := []DataType{TypeName, TypeInfer}
+	result, err := d.Slices(data, columnTypes, []string{"customer", "contact"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result[0][1] != email {
+		t.Errorf("a TypeInfer column should be inferred from its values, got %q, want %q", result[0][1], email)
+	}
+	if columnTypes[1] != TypeInfer {
+		t.Errorf("the caller's column types should not change, got %d", columnTypes[1])
+	}
+
+	in, out := make(chan []string, 1), make(chan []string, 1)
+	in <- data[0]
+	close(in)
+	err = d.SlicesChan(in, out, []ColumnSpec{{Name: "customer", Type: TypeName}, {Name: "contact", Type: TypeInfer}})
+	if err == nil || !strings.Contains(err.Error(), "TypeInfer") {
+		t.Errorf("expected SlicesChan to reject a TypeInfer column, got %v", err)
+	}
+	if _, ok := <-out; ok {
+		t.Error("expected no rows and out closed")
+	}
+}
+
 func BenchmarkSlicesDeidentification(b *testing.B) {
 	d := NewDeidentifier("benchmark-key")
 
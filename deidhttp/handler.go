@@ -0,0 +1,187 @@
+// Package deidhttp provides net/http glue for scrubbing request and response
+// bodies with a deidentify.Deidentifier.
+package deidhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aliengiraffe/deidentify"
+)
+
+// MaxBodyBytes limits how much of a request body Handler reads
+const MaxBodyBytes = 10 << 20
+
+// errUnsupportedMediaType is returned for bodies that are neither JSON nor text
+var errUnsupportedMediaType = errors.New("unsupported content type")
+
+// Handler returns an http.Handler that deidentifies the request body and writes the
+// scrubbed result back with the same Content-Type. JSON bodies have every string value
+// passed through Text, keeping keys, numbers and structure intact; text/html bodies go
+// through HTML so markup is preserved, and other text/* bodies through Text as a whole.
+// Malformed bodies produce 400, oversized ones 413, other content types 415, and
+// deidentification failures 500.
+func Handler(d *deidentify.Deidentifier) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "failed to read request body", http.StatusBadRequest)
+			return
+		}
+
+		contentType := r.Header.Get("Content-Type")
+		scrubbed, err := Scrub(d, contentType, body)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		_, _ = w.Write(scrubbed)
+	})
+}
+
+// Scrub deidentifies body according to contentType, as Handler does
+func Scrub(d *deidentify.Deidentifier, contentType string, body []byte) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", errUnsupportedMediaType, contentType)
+	}
+
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return scrubJSON(d, body)
+	case mediaType == "text/html":
+		result, err := d.HTML(string(body))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(result), nil
+	case strings.HasPrefix(mediaType, "text/"):
+		result, err := d.Text(string(body))
+		if err != nil {
+			return nil, err
+		}
+		return []byte(result), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", errUnsupportedMediaType, mediaType)
+	}
+}
+
+// invalidJSONError marks a body that could not be decoded as JSON
+type invalidJSONError struct {
+	err error
+}
+
+func (e *invalidJSONError) Error() string {
+	return "invalid JSON body: " + e.err.Error()
+}
+
+func (e *invalidJSONError) Unwrap() error {
+	return e.err
+}
+
+// scrubJSON re-encodes body token by token with its string values deidentified, so
+// object keys keep their order and numbers their exact text
+func scrubJSON(d *deidentify.Deidentifier, body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var out bytes.Buffer
+	if err := scrubJSONValue(d, decoder, &out); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, &invalidJSONError{err: errors.New("unexpected data after top-level value")}
+	}
+	return out.Bytes(), nil
+}
+
+// scrubJSONValue copies the next JSON value from decoder to out, deidentifying every
+// string value in it
+func scrubJSONValue(d *deidentify.Deidentifier, decoder *json.Decoder, out *bytes.Buffer) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return &invalidJSONError{err: err}
+	}
+
+	switch v := token.(type) {
+	case json.Delim:
+		return scrubJSONContainer(d, decoder, out, v)
+	case string:
+		scrubbed, err := d.Text(v)
+		if err != nil {
+			return err
+		}
+		writeJSONString(out, scrubbed)
+	case json.Number:
+		out.WriteString(v.String())
+	case bool:
+		out.WriteString(strconv.FormatBool(v))
+	default:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+// scrubJSONContainer copies the elements of the array or object opened by delim,
+// leaving object keys unchanged
+func scrubJSONContainer(d *deidentify.Deidentifier, decoder *json.Decoder, out *bytes.Buffer, delim json.Delim) error {
+	out.WriteRune(rune(delim))
+	for i := 0; decoder.More(); i++ {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		if delim == '{' {
+			key, err := decoder.Token()
+			if err != nil {
+				return &invalidJSONError{err: err}
+			}
+			writeJSONString(out, key.(string))
+			out.WriteByte(':')
+		}
+		if err := scrubJSONValue(d, decoder, out); err != nil {
+			return err
+		}
+	}
+
+	end, err := decoder.Token()
+	if err != nil {
+		return &invalidJSONError{err: err}
+	}
+	out.WriteRune(rune(end.(json.Delim)))
+	return nil
+}
+
+// writeJSONString writes s to out as a JSON string without escaping HTML characters
+func writeJSONString(out *bytes.Buffer, s string) {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(s)
+	out.Truncate(out.Len() - 1)
+}
+
+// writeError maps a Scrub error to an HTTP status
+func writeError(w http.ResponseWriter, err error) {
+	var invalid *invalidJSONError
+	switch {
+	case errors.As(err, &invalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errUnsupportedMediaType):
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+	default:
+		http.Error(w, "failed to deidentify body", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,108 @@
+package deidhttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aliengiraffe/deidentify"
+)
+
+func TestHandlerJSON(t *testing.T) {
+	d := deidentify.NewDeidentifier("test-secret-key")
+	body := `{"note":"Email frodo@shire.me","count":3,"tags":["call 555-123-4567"],"ok":true}`
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec := httptest.NewRecorder()
+	Handler(d).ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("Content-Type should be preserved, got %q", got)
+	}
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Response is not valid JSON: %v", err)
+	}
+	if strings.Contains(rec.Body.String(), "frodo@shire.me") || strings.Contains(rec.Body.String(), "555-123-4567") {
+		t.Errorf("PII should be scrubbed, got %s", rec.Body.String())
+	}
+	if doc["count"] != float64(3) || doc["ok"] != true {
+		t.Errorf("Non-string values should be unchanged, got %v", doc)
+	}
+}
+
+func TestHandlerJSONKeepsLayout(t *testing.T) {
+	d := deidentify.NewDeidentifier("test-secret-key")
+	body := `{"zeta":"<b>R&D</b>","alpha":1.50,"nested":{"y":null,"x":[false]}}`
+
+	scrubbed, err := Scrub(d, "application/json", []byte(body))
+	if err != nil {
+		t.Fatalf("Scrub failed: %v", err)
+	}
+	if string(scrubbed) != body {
+		t.Errorf("Keys, numbers and markup should be kept as written, got %s", scrubbed)
+	}
+}
+
+func TestHandlerText(t *testing.T) {
+	d := deidentify.NewDeidentifier("test-secret-key")
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("SSN 123-45-6789"))
+	req.Header.Set("Content-Type", "text/plain")
+	rec := httptest.NewRecorder()
+	Handler(d).ServeHTTP(rec, req)
+
+	want, _ := d.Text("SSN 123-45-6789")
+	if rec.Code != http.StatusOK || rec.Body.String() != want {
+		t.Errorf("Expected 200 with %q, got %d with %q", want, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerHTML(t *testing.T) {
+	d := deidentify.NewDeidentifier("test-secret-key")
+	body := `<p class="ssn">SSN 123-45-6789</p>`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/html; charset=utf-8")
+	rec := httptest.NewRecorder()
+	Handler(d).ServeHTTP(rec, req)
+
+	want, _ := d.HTML(body)
+	if rec.Code != http.StatusOK || rec.Body.String() != want || !strings.HasPrefix(want, `<p class="ssn">`) {
+		t.Errorf("Expected 200 with %q, got %d with %q", want, rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandlerErrors(t *testing.T) {
+	d := deidentify.NewDeidentifier("test-secret-key")
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"malformed JSON", "application/json", `{"note":`, http.StatusBadRequest},
+		{"trailing data", "application/json", `{} {}`, http.StatusBadRequest},
+		{"object key not a string", "application/json", `{1:2}`, http.StatusBadRequest},
+		{"oversized body", "text/plain", strings.Repeat("a", MaxBodyBytes+1), http.StatusRequestEntityTooLarge},
+		{"unsupported type", "image/png", "\x89PNG", http.StatusUnsupportedMediaType},
+		{"missing type", "", "hello", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			Handler(d).ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, rec.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package deidentify
+
+import (
+	"encoding/hex"
+	"strings"
+)
+
+// Finding describes one value that was deidentified without holding the original: its
+// type, what it was replaced with and, with WithOriginalHash, a keyed hash of the
+// original that lets downstream systems spot duplicate source records
+type Finding struct {
+	Type         DataType
+	Replacement  string
+	OriginalHash string
+}
+
+// ColumnWithFindings is like Column but also returns one Finding per value, in the same
+// order. Empty values give a Finding with empty fields.
+func (d *Deidentifier) ColumnWithFindings(name string, values []string, t ...DataType) ([]string, []Finding, error) {
+	dataType, err := d.columnDataType(values, t)
+	if err != nil {
+		return nil, nil, err
+	}
+	result, err := d.Column(name, values, dataType)
+	if err != nil || result == nil {
+		return result, nil, err
+	}
+
+	findings := make([]Finding, len(values))
+	for i, value := range values {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		findings[i] = Finding{Type: dataType, Replacement: result[i], OriginalHash: d.originalHash(value, dataType)}
+	}
+	return result, findings, nil
+}
+
+// TextWithFindings is like Text but also returns a Finding for every value replaced,
+// in the order the detection steps replaced them
+func (d *Deidentifier) TextWithFindings(text string) (string, []Finding, error) {
+	run := &textRun{collect: true}
+	result, err := d.runText(run, text)
+	if err != nil {
+		return "", nil, err
+	}
+	return result, run.findings, nil
+}
+
+// originalHash returns the hex HMAC of a normalized original when WithOriginalHash is
+// set, or "". The input is prefixed so the hash differs from the one seeding replacements.
+func (d *Deidentifier) originalHash(original string, dataType DataType) string {
+	if !d.hashOriginals {
+		return ""
+	}
+	normalized := d.normalizeValue(strings.TrimSpace(original), dataType)
+	return hex.EncodeToString(d.deterministicHash("original\x00" + normalized))
+}
+
+// recordFinding adds a Finding for a replaced value to run when findings are collected,
+// and remembers the replacement for processUncertainTokens
+func (d *Deidentifier) recordFinding(run *textRun, dataType DataType, original, replacement string) {
+	if d.uncertainThreshold > 0 {
+		run.replacements = append(run.replacements, replacement)
+	}
+	if !run.collect {
+		return
+	}
+	run.findings = append(run.findings, Finding{
+		Type:         dataType,
+		Replacement:  replacement,
+		OriginalHash: d.originalHash(original, dataType),
+	})
+}
@@ -0,0 +1,64 @@
+package deidentify
+
+import (
+	"encoding/binary"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// latLongJitter is the largest shift, in degrees, applied to a coordinate with at least
+// two decimal places (about 1 km)
+const latLongJitter = 0.01
+
+// fuzzCoordinate shifts one coordinate by a deterministic offset of up to latLongJitter
+// degrees, keeping its number of decimal places and a non-zero shift at that precision.
+// Coarser values move by one unit of their last place in either direction, so an integer
+// coordinate moves by 1 degree (about 111 km). Values within ±90 stay valid latitudes
+// and larger ones valid longitudes.
+func (d *Deidentifier) fuzzCoordinate(coordinate string, hash []byte) string {
+	value, err := strconv.ParseFloat(coordinate, 64)
+	if err != nil {
+		return coordinate
+	}
+
+	decimals := 0
+	if dot := strings.Index(coordinate, "."); dot >= 0 {
+		decimals = len(coordinate) - dot - 1
+	}
+	unit := math.Pow10(-decimals)
+
+	fraction := float64(binary.BigEndian.Uint64(hash[:8])) / math.MaxUint64
+	steps := math.Round((fraction*2 - 1) * latLongJitter / unit)
+	if steps == 0 {
+		steps = 1
+		if fraction < 0.5 {
+			steps = -1
+		}
+	}
+
+	limit := 90.0
+	if math.Abs(value) > 90 {
+		limit = 180
+	}
+	fuzzed := math.Max(-limit, math.Min(limit, value+steps*unit))
+	if fuzzed == value {
+		fuzzed = value - steps*unit
+	}
+	return strconv.FormatFloat(fuzzed, 'f', decimals, 64)
+}
+
+// generateLatLong creates a deterministic nearby location by shifting every coordinate
+// in original, keeping separators, signs and precision
+func (d *Deidentifier) generateLatLong(original string) string {
+	hash := d.deterministicHash(original)
+	coordinateRegex := regexp.MustCompile(coordinateRegexPattern)
+
+	i := 0
+	return coordinateRegex.ReplaceAllStringFunc(original, func(coordinate string) string {
+		offset := (i % 4) * 8
+		i++
+		return d.fuzzCoordinate(coordinate, hash[offset:offset+8])
+	})
+}
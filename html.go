@@ -0,0 +1,212 @@
+package deidentify
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// htmlScrubbedAttributes lists the attributes whose values are run through Text. Other
+// attributes such as class or id are structural and kept as they are.
+var htmlScrubbedAttributes = map[string]bool{
+	"alt": true, "aria-label": true, "content": true, "href": true, "label": true,
+	"placeholder": true, "src": true, "title": true, "value": true,
+}
+
+// HTML deidentifies an HTML document while keeping its markup intact. Text nodes,
+// comments and the values of attributes that carry content (href including mailto: and
+// tel: links, title, alt, value, ...) go through Text; tags, attribute names and
+// structure are copied unchanged. Entities are decoded before detection, so
+// "joe&#64;x.com" is found too, and changed text is re-escaped. Script and style
+// contents are processed as raw text unless WithHTMLSkipScripts is set.
+func (d *Deidentifier) HTML(doc string) (string, error) {
+	var b strings.Builder
+	pos := 0
+	for pos < len(doc) {
+		start := d.nextHTMLTag(doc, pos)
+		if err := d.writeHTMLText(&b, doc[pos:start]); err != nil {
+			return "", err
+		}
+		if start == len(doc) {
+			break
+		}
+
+		end, err := d.writeHTMLMarkup(&b, doc, start)
+		if err != nil {
+			return "", err
+		}
+		pos = end
+	}
+	return b.String(), nil
+}
+
+// htmlTagEnd returns the index just past the '>' closing the tag at start, skipping '>'
+// inside quoted attribute values, or -1 when another '<' or the end of doc comes first.
+// A quote only opens a value directly after '=', so the apostrophe in class=it's is
+// part of the value rather than the start of one.
+func (d *Deidentifier) htmlTagEnd(doc string, start int) int {
+	var quote byte
+	afterEquals := false
+	for i := start + 1; i < len(doc); i++ {
+		c := doc[i]
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case afterEquals && (c == '"' || c == '\''):
+			quote = c
+		case c == '>':
+			return i + 1
+		case c == '<':
+			return -1
+		}
+		afterEquals = c == '=' || afterEquals && (c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f')
+	}
+	return -1
+}
+
+// nextHTMLTag returns the index of the next '<' at or after pos that opens markup,
+// or len(doc). A '<' followed by anything else, as in "a < b", or one that never
+// reaches its closing '>', as in "if a<b then", is text.
+func (d *Deidentifier) nextHTMLTag(doc string, pos int) int {
+	for i := pos; i < len(doc); i++ {
+		if doc[i] != '<' || i+1 == len(doc) {
+			continue
+		}
+		if strings.HasPrefix(doc[i:], "<!--") {
+			return i
+		}
+		c := doc[i+1]
+		isTag := c == '/' || c == '!' || c == '?' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if isTag && d.htmlTagEnd(doc, i) >= 0 {
+			return i
+		}
+	}
+	return len(doc)
+}
+
+// scrubHTMLTag runs the content-carrying attribute values of a tag through Text
+func (d *Deidentifier) scrubHTMLTag(tag string) (string, error) {
+	attrRegex := regexp.MustCompile(htmlAttributeRegexPattern)
+	name := regexp.MustCompile(htmlTagNameRegexPattern).FindString(tag)
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range attrRegex.FindAllStringSubmatchIndex(tag[len(name):], -1) {
+		attr := strings.ToLower(tag[len(name)+loc[2] : len(name)+loc[3]])
+		if loc[4] < 0 || !htmlScrubbedAttributes[attr] {
+			continue
+		}
+
+		valueStart, valueEnd := len(name)+loc[4], len(name)+loc[5]
+		value := tag[valueStart:valueEnd]
+		quote := ""
+		if value[0] == '"' || value[0] == '\'' {
+			quote = value[:1]
+			value = value[1 : len(value)-1]
+		}
+		scrubbed, err := d.scrubHTMLValue(value)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(tag[last:valueStart])
+		b.WriteString(quote + scrubbed + quote)
+		last = valueEnd
+	}
+	b.WriteString(tag[last:])
+	return b.String(), nil
+}
+
+// scrubHTMLValue runs escaped HTML text through Text, returning it unchanged when
+// nothing is found so the original entity spelling survives
+func (d *Deidentifier) scrubHTMLValue(escaped string) (string, error) {
+	text := html.UnescapeString(escaped)
+	result, err := d.Text(text)
+	if err != nil {
+		return "", err
+	}
+	if result == text {
+		return escaped, nil
+	}
+	return html.EscapeString(result), nil
+}
+
+// writeHTMLComment scrubs the comment starting at start and returns the index past it
+func (d *Deidentifier) writeHTMLComment(b *strings.Builder, doc string, start int) (int, error) {
+	bodyStart := start + len("<!--")
+	bodyEnd, end := len(doc), len(doc)
+	if i := strings.Index(doc[bodyStart:], "-->"); i >= 0 {
+		bodyEnd = bodyStart + i
+		end = bodyEnd + len("-->")
+	}
+
+	comment, err := d.Text(doc[bodyStart:bodyEnd])
+	if err != nil {
+		return 0, err
+	}
+	b.WriteString("<!--" + comment + doc[bodyEnd:end])
+	return end, nil
+}
+
+// writeHTMLMarkup copies the comment, declaration or tag starting at start, scrubbing
+// comment text, attribute values and script or style contents, and returns the index
+// where text resumes
+func (d *Deidentifier) writeHTMLMarkup(b *strings.Builder, doc string, start int) (int, error) {
+	if strings.HasPrefix(doc[start:], "<!--") {
+		return d.writeHTMLComment(b, doc, start)
+	}
+
+	end := d.htmlTagEnd(doc, start)
+	tag := doc[start:end]
+	if tag[1] == '!' || tag[1] == '?' || tag[1] == '/' {
+		b.WriteString(tag)
+		return end, nil
+	}
+
+	scrubbed, err := d.scrubHTMLTag(tag)
+	if err != nil {
+		return 0, err
+	}
+	b.WriteString(scrubbed)
+
+	name := strings.ToLower(regexp.MustCompile(htmlTagNameRegexPattern).FindString(tag)[1:])
+	if (name != "script" && name != "style") || strings.HasSuffix(tag, "/>") {
+		return end, nil
+	}
+	return d.writeHTMLRawText(b, doc, end, name)
+}
+
+// writeHTMLRawText copies the contents of a script or style element up to its closing
+// tag, running them through Text as raw text unless WithHTMLSkipScripts is set
+func (d *Deidentifier) writeHTMLRawText(b *strings.Builder, doc string, start int, name string) (int, error) {
+	end := len(doc) - start
+	if loc := regexp.MustCompile(`(?i)</` + name).FindStringIndex(doc[start:]); loc != nil {
+		end = loc[0]
+	}
+	content := doc[start : start+end]
+	if !d.skipHTMLScripts {
+		scrubbed, err := d.Text(content)
+		if err != nil {
+			return 0, err
+		}
+		content = scrubbed
+	}
+	b.WriteString(content)
+	return start + end, nil
+}
+
+// writeHTMLText scrubs a text node and appends it to b
+func (d *Deidentifier) writeHTMLText(b *strings.Builder, text string) error {
+	if text == "" {
+		return nil
+	}
+	scrubbed, err := d.scrubHTMLValue(text)
+	if err != nil {
+		return err
+	}
+	b.WriteString(scrubbed)
+	return nil
+}
@@ -0,0 +1,109 @@
+package deidentify
+
+import "sync"
+
+// MappingStore persists the original→replacement mappings that keep deidentification
+// consistent. Mappings are namespaced by column name. Implementations must be safe
+// for concurrent use.
+//
+// The default store keeps everything in process memory. Supplying a shared store
+// (Redis, a database, ...) through WithMappingStore lets several workers hand out
+// the same replacements.
+type MappingStore interface {
+	// Get returns the replacement recorded for original in column, if any
+	Get(column, original string) (string, bool)
+	// Set records the replacement for original in column
+	Set(column, original, replacement string)
+}
+
+// memoryStore is the default in-process MappingStore
+type memoryStore struct {
+	tables map[string]map[string]string
+	mutex  sync.RWMutex
+}
+
+// newMemoryStore creates an empty in-memory mapping store
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		tables: make(map[string]map[string]string),
+	}
+}
+
+// Clear removes all stored mappings
+func (s *memoryStore) Clear() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.tables = make(map[string]map[string]string)
+}
+
+// snapshot returns a copy of all stored mappings keyed by column
+func (s *memoryStore) snapshot() map[string]map[string]string {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tables := make(map[string]map[string]string, len(s.tables))
+	for column, table := range s.tables {
+		copied := make(map[string]string, len(table))
+		for original, replacement := range table {
+			copied[original] = replacement
+		}
+		tables[column] = copied
+	}
+	return tables
+}
+
+// Get retrieves an existing mapping
+func (s *memoryStore) Get(column, original string) (string, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	replacement, exists := s.tables[column][original]
+	return replacement, exists
+}
+
+// Set stores a mapping
+func (s *memoryStore) Set(column, original, replacement string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.tables[column] == nil {
+		s.tables[column] = make(map[string]string)
+	}
+	s.tables[column][original] = replacement
+}
+
+// columnTypeIndex remembers which DataType was written to each mapping namespace
+type columnTypeIndex struct {
+	types map[string]DataType
+	mutex sync.RWMutex
+}
+
+// newColumnTypeIndex creates an empty column type index
+func newColumnTypeIndex() *columnTypeIndex {
+	return &columnTypeIndex{
+		types: make(map[string]DataType),
+	}
+}
+
+// get returns the type recorded for column
+func (i *columnTypeIndex) get(column string) (DataType, bool) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	dataType, exists := i.types[column]
+	return dataType, exists
+}
+
+// record notes that column holds values of dataType
+func (i *columnTypeIndex) record(column string, dataType DataType) {
+	i.mutex.RLock()
+	current, exists := i.types[column]
+	i.mutex.RUnlock()
+	if exists && current == dataType {
+		return
+	}
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.types[column] = dataType
+}
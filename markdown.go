@@ -0,0 +1,105 @@
+package deidentify
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Markdown deidentifies a Markdown document while keeping its syntax intact. Prose goes
+// through Text, and the text, target and title of links and images are scrubbed
+// separately, so [contact](mailto:joe@x.com) keeps its brackets and parentheses while
+// the email is replaced. Fenced code blocks and inline code are copied unchanged unless
+// WithMarkdownCode is set.
+func (d *Deidentifier) Markdown(doc string) (string, error) {
+	run := &textRun{}
+	var b, prose strings.Builder
+	fence := ""
+	for _, line := range strings.SplitAfter(doc, "\n") {
+		marker := d.markdownFence(line)
+		switch {
+		case fence != "":
+			b.WriteString(line)
+			if marker != "" && marker[0] == fence[0] && len(marker) >= len(fence) {
+				fence = ""
+			}
+		case marker != "":
+			if err := d.writeMarkdownProse(run, &b, prose.String()); err != nil {
+				return "", err
+			}
+			prose.Reset()
+			b.WriteString(line)
+			fence = marker
+		default:
+			prose.WriteString(line)
+		}
+	}
+	if err := d.writeMarkdownProse(run, &b, prose.String()); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// markdownFence returns the backtick or tilde run opening or closing a fenced code
+// block on line, or "" when line is not a fence or WithMarkdownCode is set
+func (d *Deidentifier) markdownFence(line string) string {
+	if d.scrubMarkdownCode {
+		return ""
+	}
+	return regexp.MustCompile(markdownFenceRegexPattern).FindString(strings.TrimLeft(line, " "))
+}
+
+// scrubMarkdownLink scrubs the text, target and title of a link or image separately
+func (d *Deidentifier) scrubMarkdownLink(run *textRun, link string) (string, error) {
+	loc := regexp.MustCompile(markdownLinkRegexPattern).FindStringSubmatchIndex(link)
+	var b strings.Builder
+	last := 0
+	for group := 1; group <= 3; group++ {
+		start, end := loc[2*group], loc[2*group+1]
+		if start < 0 {
+			continue
+		}
+		scrubbed, err := d.runText(run, link[start:end])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(link[last:start])
+		b.WriteString(scrubbed)
+		last = end
+	}
+	b.WriteString(link[last:])
+	return b.String(), nil
+}
+
+// writeMarkdownProse scrubs Markdown outside code blocks and appends it to b. Links and
+// inline code are replaced by protected placeholders first, so Text neither breaks
+// their syntax nor sees code.
+func (d *Deidentifier) writeMarkdownProse(run *textRun, b *strings.Builder, prose string) error {
+	if prose == "" {
+		return nil
+	}
+
+	var err error
+	masked := regexp.MustCompile(markdownInlineRegexPattern).ReplaceAllStringFunc(prose, func(match string) string {
+		if strings.HasPrefix(match, "`") {
+			if d.scrubMarkdownCode {
+				return match
+			}
+			return d.protect(run, match)
+		}
+		scrubbed, linkErr := d.scrubMarkdownLink(run, match)
+		if linkErr != nil && err == nil {
+			err = linkErr
+		}
+		return d.protect(run, scrubbed)
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := d.runText(run, masked)
+	if err != nil {
+		return err
+	}
+	b.WriteString(d.restoreProtected(run, result))
+	return nil
+}
@@ -0,0 +1,99 @@
+package deidentify
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"strconv"
+	"strings"
+)
+
+// ipv4Blocks lists the special-purpose IPv4 ranges a fake address stays within, so
+// private, shared, link-local and loopback addresses keep their meaning in logs
+var ipv4Blocks = []netip.Prefix{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+}
+
+// generateIPAddress creates a deterministic fake IPv4 address, keeping a ":port" or
+// "/mask" suffix as written. Addresses in a special-purpose block stay in that block and
+// public ones become other public addresses; a CIDR network gets its host bits cleared.
+// The fake depends only on the address, so one host keeps its fake across ports.
+func (d *Deidentifier) generateIPAddress(original string) string {
+	addr, suffix, bits, ok := d.parseIPAddress(original)
+	if !ok {
+		return d.generateFormattedID(original)
+	}
+
+	hash := d.deterministicHash("ip:" + addr.String())
+	fake := d.publicIPv4(hash)
+	for _, block := range ipv4Blocks {
+		if block.Contains(addr) {
+			mask := ^uint32(0) << (32 - block.Bits())
+			value := binary.BigEndian.Uint32(addr.AsSlice())&mask | binary.BigEndian.Uint32(hash[:4])&^mask
+			fake = netip.AddrFrom4([4]byte(binary.BigEndian.AppendUint32(nil, value)))
+			break
+		}
+	}
+	if bits < 32 {
+		fake = netip.PrefixFrom(fake, bits).Masked().Addr()
+	}
+	return fake.String() + suffix
+}
+
+// isPublicIPv4 reports whether addr is a unicast address outside ipv4Blocks and the
+// reserved ranges, so it can stand in for a public address
+func (d *Deidentifier) isPublicIPv4(addr netip.Addr) bool {
+	first := addr.As4()[0]
+	if first == 0 || first >= 224 || !addr.IsGlobalUnicast() {
+		return false
+	}
+	for _, block := range ipv4Blocks {
+		if block.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseIPAddress splits value into an IPv4 address and a ":port" or "/mask" suffix,
+// returning the prefix length the suffix sets (32 without a mask)
+func (d *Deidentifier) parseIPAddress(value string) (netip.Addr, string, int, bool) {
+	end := strings.IndexAny(value, ":/")
+	if end < 0 {
+		end = len(value)
+	}
+	addr, err := netip.ParseAddr(value[:end])
+	if err != nil || !addr.Is4() {
+		return netip.Addr{}, "", 0, false
+	}
+
+	suffix := value[end:]
+	if suffix == "" {
+		return addr, "", 32, true
+	}
+	n, err := strconv.Atoi(suffix[1:])
+	switch {
+	case err != nil || n < 0:
+		return netip.Addr{}, "", 0, false
+	case suffix[0] == '/' && n <= 32:
+		return addr, suffix, n, true
+	case suffix[0] == ':' && n <= 65535:
+		return addr, suffix, 32, true
+	}
+	return netip.Addr{}, "", 0, false
+}
+
+// publicIPv4 picks a public IPv4 address from the first 4-byte window of hash that gives
+// one, falling back to the 1.0.0.0/8 to 9.0.0.0/8 range
+func (d *Deidentifier) publicIPv4(hash []byte) netip.Addr {
+	for i := 0; i+4 <= len(hash); i += 4 {
+		if addr := netip.AddrFrom4([4]byte(hash[i : i+4])); d.isPublicIPv4(addr) {
+			return addr
+		}
+	}
+	return netip.AddrFrom4([4]byte{1 + hash[0]%9, hash[1], hash[2], hash[3]})
+}
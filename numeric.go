@@ -0,0 +1,81 @@
+package deidentify
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultNumericNoisePercent is the largest relative change TypeNumericNoise applies
+// unless WithNumericNoise sets another bound
+const defaultNumericNoisePercent = 5.0
+
+// NumericNoise perturbs a number by deterministic noise of up to ±pct percent, drawn
+// uniformly from a hash of the value and the secret key. The same input always gives
+// the same output, and because the noise is centered on zero, sums and means over many
+// values stay approximately intact. Decimal places, thousands separators and a
+// surrounding currency symbol or unit are kept; small integers may come back unchanged
+// once rounded.
+func (d *Deidentifier) NumericNoise(s string, pct float64) (string, error) {
+	core := strings.TrimSpace(s)
+	if core == "" {
+		return s, nil
+	}
+
+	perturbed, err := d.perturbNumber(core, pct)
+	if err != nil {
+		return "", err
+	}
+	start := strings.Index(s, core)
+	return s[:start] + perturbed + s[start+len(core):], nil
+}
+
+// formatNumberLike formats value with the given decimal places, adding thousands
+// separators when grouped is set
+func (d *Deidentifier) formatNumberLike(value float64, decimals int, grouped bool) string {
+	formatted := strconv.FormatFloat(value, 'f', decimals, 64)
+	if !grouped {
+		return formatted
+	}
+
+	sign := ""
+	if strings.HasPrefix(formatted, "-") {
+		sign, formatted = "-", formatted[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(formatted, ".")
+	var b strings.Builder
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFraction {
+		b.WriteString("." + fraction)
+	}
+	return sign + b.String()
+}
+
+// perturbNumber applies NumericNoise to a trimmed value
+func (d *Deidentifier) perturbNumber(value string, pct float64) (string, error) {
+	parts := regexp.MustCompile(numericValueRegexPattern).FindStringSubmatch(value)
+	if parts == nil {
+		return "", fmt.Errorf("invalid number %q", value)
+	}
+	number, err := strconv.ParseFloat(strings.ReplaceAll(parts[2], ",", ""), 64)
+	if err != nil {
+		return "", fmt.Errorf("invalid number %q: %w", value, err)
+	}
+
+	decimals := 0
+	if dot := strings.Index(parts[2], "."); dot >= 0 {
+		decimals = len(parts[2]) - dot - 1
+	}
+	hash := d.deterministicHash(value)
+	fraction := float64(binary.BigEndian.Uint64(hash[:8])) / math.MaxUint64
+	noisy := number * (1 + (fraction*2-1)*math.Abs(pct)/100)
+	return parts[1] + d.formatNumberLike(noisy, decimals, strings.Contains(parts[2], ",")) + parts[3], nil
+}
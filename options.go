@@ -0,0 +1,648 @@
+package deidentify
+
+import (
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ErrorPolicy controls what Text does when replacing a detected value fails
+type ErrorPolicy int
+
+const (
+	// InlineToken replaces the value with a marker such as [EMAIL REDACTION ERROR]
+	InlineToken ErrorPolicy = iota
+	// KeepOriginal leaves the detected value unchanged
+	KeepOriginal
+	// FailFast makes Text return the first error instead of a result
+	FailFast
+)
+
+// CategoricalMode controls how TypeCategorical values are anonymized
+type CategoricalMode int
+
+const (
+	// Generalize replaces a value with its parent category from WithCategoryHierarchy,
+	// and a value without one with "*"
+	Generalize CategoricalMode = iota
+	// Suppress replaces every value with "*"
+	Suppress
+)
+
+// Checksum selects the check digit algorithm WithChecksum recomputes on formatted IDs
+type Checksum int
+
+const (
+	// ChecksumMod10 is the Luhn algorithm used by credit cards and many account numbers
+	ChecksumMod10 Checksum = iota
+	// ChecksumMod11 weights digits 2, 3, 4, ... from the right, as ISBN-10 does; a check
+	// value of 10 is written as X
+	ChecksumMod11
+)
+
+// OutputCase controls the letter case of generated emails and usernames
+type OutputCase int
+
+const (
+	// Lower writes generated emails and usernames in lowercase
+	Lower OutputCase = iota
+	// Upper writes generated emails and usernames in uppercase
+	Upper
+	// Preserve follows the input: all-caps input gives an all-caps replacement, input
+	// starting with a capital a capitalized one, and anything else lowercase
+	Preserve
+)
+
+// defaultMaxStreetNumber is the largest street number generated addresses use by default
+const defaultMaxStreetNumber = 9999
+
+// Token length bounds for Tokenize; a base32 HMAC-SHA256 has 52 characters
+const (
+	defaultTokenLength = 16
+	maxTokenLength     = 52
+)
+
+// Option configures optional behavior of a Deidentifier
+type Option func(*Deidentifier)
+
+// options holds the optional settings applied by Option functions
+type options struct {
+	requireCardContext    bool
+	requirePhoneContext   bool
+	redistributeAreaCodes bool
+	columnGroups          map[string]string
+	invalidSSNRange       bool
+	givenNames            map[string]bool
+	errorPolicy           ErrorPolicy
+	caseInsensitiveTypes  map[DataType]bool
+	maxStreetNumber       int
+	minTokenLength        int
+	tokenLength           int
+	emailDomainAllowlist  []string
+	skipAlreadyFake       bool
+	phoneLayout           string
+	metrics               func(DataType, time.Duration)
+	emptyAsNil            bool
+	nilAsEmpty            bool
+	skipHTMLScripts       bool
+	columnTypeOverrides   map[int]DataType
+	repeatAliases         map[DataType]string
+	generalizeTextAges    bool
+	crosswalk             io.Writer
+	numericNoisePercent   float64
+	hashOriginals         bool
+	uncertainThreshold    int
+	preserveNameWords     bool
+	outputCase            OutputCase
+	redactedTypes         map[DataType]bool
+	scrubMarkdownCode     bool
+	nonDeterministic      bool
+	relativeDates         bool
+	dateReference         time.Time
+	columnSampler         func(rows int) []int
+	preserveTLD           bool
+	controlSampleRate     float64
+	controlKeyColumn      string
+	lengthCaps            map[DataType]int
+	constantDateShift     bool
+	checkTypeFormats      bool
+	formatMismatchHandler func(value string, dataType DataType, column string) string
+	tagVersion            bool
+	checksums             map[string]Checksum
+	normalizeSeparators   bool
+	separator             string
+	categoricalMode       CategoricalMode
+	categoryHierarchy     map[string]string
+	preservePlusTag       bool
+	inferCategorical      bool
+}
+
+// WithAgeGeneralization makes Text generalize ages written in prose, such as "aged 45",
+// "age: 45" or "45-year-old", into the 5-year buckets TypeAge uses ("aged 45-49",
+// "45-49-year-old"; 90 and over become "90+"). Ages are quasi-identifiers rather than
+// direct PII, so by default they are left alone in text.
+func WithAgeGeneralization() Option {
+	return func(d *Deidentifier) {
+		d.generalizeTextAges = true
+	}
+}
+
+// WithBarePhoneContext makes Text replace phone numbers written as bare digits, such as
+// "5551234567" or "15551234567", only when they have a leading "+" or follow a cue such
+// as "phone", "call" or "mobile" within a few words. By default every such run is
+// replaced, which also catches order numbers and other IDs of the same length.
+func WithBarePhoneContext() Option {
+	return func(d *Deidentifier) {
+		d.requirePhoneContext = true
+	}
+}
+
+// WithCanonicalPhoneFormat makes generated 10-digit phone numbers use layout instead of
+// the input's formatting. Each X in layout is replaced by the next digit of the area
+// code, exchange and line number, so "+1XXXXXXXXXX" gives E.164 and "(XXX) XXX-XXXX"
+// the US style. Layouts without exactly ten X characters are ignored and formatting
+// stays preserved, which is the default. Seven-digit local numbers keep their format.
+func WithCanonicalPhoneFormat(layout string) Option {
+	return func(d *Deidentifier) {
+		if strings.Count(layout, "X") == 10 {
+			d.phoneLayout = layout
+		}
+	}
+}
+
+// WithCaseInsensitiveMapping lowercases values of the given types before the mapping
+// lookup and hashing, so case variants such as test@X.com and test@x.com receive the
+// same replacement. With no types it applies to TypeEmail.
+func WithCaseInsensitiveMapping(types ...DataType) Option {
+	return func(d *Deidentifier) {
+		if len(types) == 0 {
+			types = []DataType{TypeEmail}
+		}
+		if d.caseInsensitiveTypes == nil {
+			d.caseInsensitiveTypes = make(map[DataType]bool, len(types))
+		}
+		for _, dataType := range types {
+			d.caseInsensitiveTypes[dataType] = true
+		}
+	}
+}
+
+// WithCategoricalInference lets column type inference choose TypeCategorical for
+// columns of genders and marital statuses. It is off by default because inferred
+// categorical columns are generalized or suppressed rather than kept, which destroys
+// values callers may rely on; declare the type explicitly to anonymize a single column.
+func WithCategoricalInference() Option {
+	return func(d *Deidentifier) {
+		d.inferCategorical = true
+	}
+}
+
+// WithCategoricalMode sets how TypeCategorical columns are anonymized. The default,
+// Generalize, maps values through the category hierarchy; Suppress replaces every value
+// with "*".
+func WithCategoricalMode(mode CategoricalMode) Option {
+	return func(d *Deidentifier) {
+		d.categoricalMode = mode
+	}
+}
+
+// WithCategoryHierarchy sets the parent category Generalize replaces each
+// TypeCategorical value with, matched ignoring case, as in {"Married": "Partnered",
+// "Divorced": "Not partnered"}; values missing from it become "*". It replaces the
+// default hierarchy, which only generalizes marital statuses, so genders are suppressed
+// unless mapped here.
+func WithCategoryHierarchy(hierarchy map[string]string) Option {
+	return func(d *Deidentifier) {
+		d.categoryHierarchy = make(map[string]string, len(hierarchy))
+		for category, parent := range hierarchy {
+			d.categoryHierarchy[category] = parent
+		}
+	}
+}
+
+// WithChecksum makes formatted IDs generated for column end in a valid check digit under
+// algo, so downstream validators accept the fakes. The last letter or digit of the
+// generated ID is replaced by the check digit computed over the digits before it.
+// Column is the column name for table data and the pattern name for custom patterns.
+func WithChecksum(column string, algo Checksum) Option {
+	return func(d *Deidentifier) {
+		if d.checksums == nil {
+			d.checksums = make(map[string]Checksum)
+		}
+		d.checksums[column] = algo
+	}
+}
+
+// WithColumnGroups makes the named columns of each group share one mapping namespace,
+// so a value seen in any column of a group always maps to the same replacement. Use it
+// for semantically linked columns such as email and manager_email to keep joins between
+// them intact. Columns outside every group keep their own namespace.
+func WithColumnGroups(groups map[string][]string) Option {
+	return func(d *Deidentifier) {
+		if d.columnGroups == nil {
+			d.columnGroups = make(map[string]string)
+		}
+		for group, columns := range groups {
+			for _, column := range columns {
+				d.columnGroups[column] = "group:" + group
+			}
+		}
+	}
+}
+
+// WithColumnSampler sets how column type inference picks the rows it scores. sampler
+// receives the number of rows and returns the indices to score, for example a random or
+// stratified selection for large tables whose first rows are not representative;
+// indices out of range are ignored. By default the first 10 rows are scored.
+func WithColumnSampler(sampler func(rows int) []int) Option {
+	return func(d *Deidentifier) {
+		d.columnSampler = sampler
+	}
+}
+
+// WithColumnTypeOverride replaces the inferred types of the given column indices when
+// Slices infers column types, so one misclassified column can be corrected without
+// listing every type. It has no effect when column types are passed explicitly. An
+// index outside the data's columns makes Slices return an error.
+func WithColumnTypeOverride(overrides map[int]DataType) Option {
+	return func(d *Deidentifier) {
+		if d.columnTypeOverrides == nil {
+			d.columnTypeOverrides = make(map[int]DataType, len(overrides))
+		}
+		for col, dataType := range overrides {
+			d.columnTypeOverrides[col] = dataType
+		}
+	}
+}
+
+// WithConstantDateShift makes TypeDateTime move every date and timestamp by one
+// offset derived from the secret key, the shift WithRelativeDates also uses, instead of
+// a separate offset per value. Ordering and the intervals between events survive, which
+// time-series data needs, but anyone who learns one true date can recover them all.
+func WithConstantDateShift() Option {
+	return func(d *Deidentifier) {
+		d.constantDateShift = true
+	}
+}
+
+// WithControlSampleRate leaves a fraction p of rows of Slices, SlicesChan, CSV and
+// Table completely unchanged as a control group for evaluating the downstream impact of
+// deidentification. Rows are chosen by a keyed hash of the value in keyColumn, or in the
+// first column when no name is given, so the same rows are always in the control group.
+// This intentionally leaks the control rows: their PII passes through as is, and anyone
+// holding the output sees those records in the clear. p is clamped to [0, 1].
+func WithControlSampleRate(p float64, keyColumn ...string) Option {
+	return func(d *Deidentifier) {
+		d.controlSampleRate = math.Max(0, math.Min(1, p))
+		if len(keyColumn) > 0 {
+			d.controlKeyColumn = keyColumn[0]
+		}
+	}
+}
+
+// WithCreditCardContext makes Text redact card-shaped numbers only when a payment
+// word (card, visa, payment, ...) appears shortly before them and the number is not
+// directly labelled as something else (order, ISBN, SKU, ...). This trades some
+// recall for fewer false positives in inventory or logistics text.
+func WithCreditCardContext() Option {
+	return func(d *Deidentifier) {
+		d.requireCardContext = true
+	}
+}
+
+// WithCrosswalkWriter makes CSV stream a sidecar crosswalk to w while it deidentifies:
+// a column,original,replacement header, then one row per distinct replaced value in the
+// order values first appear, so reruns over the same input produce identical files. The
+// crosswalk is flushed when CSV returns. Rows are written as they are processed, but
+// CSV remembers every distinct column and original it has recorded in order to write
+// each once, so memory grows with the number of distinct values, as the mapping table
+// already does. The crosswalk links fakes back to real values, so store it as securely
+// as the input. Use one CSV call at a time per writer.
+func WithCrosswalkWriter(w io.Writer) Option {
+	return func(d *Deidentifier) {
+		d.crosswalk = w
+	}
+}
+
+// WithEmailDomainAllowlist leaves emails under the given domains, or any of their
+// subdomains, unchanged: with "example.com" allowlisted, both jane@example.com and
+// ops@mail.example.com pass through while other addresses are replaced. Matching
+// ignores case.
+func WithEmailDomainAllowlist(domains []string) Option {
+	return func(d *Deidentifier) {
+		for _, domain := range domains {
+			domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+			if domain != "" {
+				d.emailDomainAllowlist = append(d.emailDomainAllowlist, domain)
+			}
+		}
+	}
+}
+
+// WithEmptyAsNil makes Table return nil for values that are empty strings, for targets
+// with strict NULL semantics. By default empty strings stay empty.
+func WithEmptyAsNil() Option {
+	return func(d *Deidentifier) {
+		d.emptyAsNil = true
+	}
+}
+
+// WithErrorPolicy sets how Text handles a value it detected but failed to replace.
+// The default, InlineToken, inlines an error marker in place of the value.
+func WithErrorPolicy(policy ErrorPolicy) Option {
+	return func(d *Deidentifier) {
+		d.errorPolicy = policy
+	}
+}
+
+// WithFormatMismatchHandler stops the library from fabricating replacements for values
+// that do not have the basic shape of their declared type, such as a number in a name
+// column or a name in a phone column, so dirty data surfaces instead of being masked.
+// Such values are passed to handler with their type and column, and its result is used
+// in their place, for example after logging them or to write a quarantine marker. With
+// a nil handler they are left unchanged. Types without a checkable shape, such as
+// TypeGeneric or TypeFormattedID, are never routed.
+func WithFormatMismatchHandler(handler func(value string, dataType DataType, column string) string) Option {
+	return func(d *Deidentifier) {
+		d.checkTypeFormats = true
+		d.formatMismatchHandler = handler
+	}
+}
+
+// WithGivenNameDictionary makes Text also redact standalone given names, such as the
+// "Maria" in "Dear Maria," that the first-and-last-name pattern cannot see. Only names
+// in the dictionary are considered, and only when a greeting precedes them or a comma
+// follows them. Greetings are then no longer taken as the first half of a full name,
+// so "Dear Maria Garcia" keeps "Dear". With no names supplied a built-in list of common
+// given names is used.
+func WithGivenNameDictionary(names ...string) Option {
+	return func(d *Deidentifier) {
+		if len(names) == 0 {
+			names = commonGivenNameOptions
+		}
+		if d.givenNames == nil {
+			d.givenNames = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			d.givenNames[strings.ToLower(name)] = true
+		}
+	}
+}
+
+// WithHTMLSkipScripts makes HTML copy the contents of script and style elements
+// unchanged instead of running them through Text, so code and CSS are never altered
+func WithHTMLSkipScripts() Option {
+	return func(d *Deidentifier) {
+		d.skipHTMLScripts = true
+	}
+}
+
+// WithInvalidSSNRange makes generated SSNs use the 900-999 area, which the SSA never
+// issues, instead of the default valid-looking areas, with a group from 01-49 so the
+// value is not an IRS ITIN either. The output keeps the XXX-XX-XXXX format but can
+// never belong to a real person, which suits test fixtures that must be obviously
+// fake. Downstream validators that reject invalid areas will reject these values.
+func WithInvalidSSNRange() Option {
+	return func(d *Deidentifier) {
+		d.invalidSSNRange = true
+	}
+}
+
+// WithMarkdownCode makes Markdown also run fenced code blocks and inline code through
+// Text instead of copying them unchanged, for documents whose code samples hold PII
+func WithMarkdownCode() Option {
+	return func(d *Deidentifier) {
+		d.scrubMarkdownCode = true
+	}
+}
+
+// WithMaxStreetNumber limits generated street numbers to the range 1 to max, for
+// downstream address validators that only accept small numbers. Values below 1 are
+// ignored and keep the default of 9999.
+func WithMaxStreetNumber(max int) Option {
+	return func(d *Deidentifier) {
+		if max >= 1 {
+			d.maxStreetNumber = max
+		}
+	}
+}
+
+// WithMappingStore replaces the default in-memory mapping table with store. Workers
+// sharing one store produce identical replacements for the same values.
+func WithMappingStore(store MappingStore) Option {
+	return func(d *Deidentifier) {
+		if store != nil {
+			d.mappings = store
+		}
+	}
+}
+
+// WithMetrics calls hook with the type and duration of every replacement generated for a
+// value not yet in the mapping store, to show which types dominate processing time.
+// Values answered from the store are not reported. The hook runs on the calling
+// goroutine and must be safe for concurrent use when the Deidentifier is shared.
+func WithMetrics(hook func(t DataType, dur time.Duration)) Option {
+	return func(d *Deidentifier) {
+		d.metrics = hook
+	}
+}
+
+// WithMinTokenLength makes Text leave name candidates alone when any of their words is
+// shorter than n characters, so short capitalized pairs such as "Al Ed" or "Mo Li" are
+// not redacted. The default of 0 applies no limit.
+func WithMinTokenLength(n int) Option {
+	return func(d *Deidentifier) {
+		if n > 0 {
+			d.minTokenLength = n
+		}
+	}
+}
+
+// WithNameWordCount makes generated names keep the word count of the original: a
+// mononym such as "Madonna" gets a single given name, and "Mary Jane Watson" a first,
+// middle and last name. By default every name is replaced with a first and last name.
+func WithNameWordCount() Option {
+	return func(d *Deidentifier) {
+		d.preserveNameWords = true
+	}
+}
+
+// WithNilAsEmpty makes Table return empty strings for nil values, for targets that do
+// not accept NULL. By default nil values stay nil.
+func WithNilAsEmpty() Option {
+	return func(d *Deidentifier) {
+		d.nilAsEmpty = true
+	}
+}
+
+// WithNonDeterministicMode gives every occurrence of a value its own fake, so frequency
+// analysis cannot link occurrences, for testing re-identification resistance. This
+// breaks referential integrity by design: repeated values no longer share a fake, so
+// joins across rows and columns are lost, and nothing is stored in the mapping table
+// for LastRunMappings or a crosswalk. Fakes come from a per-instance call counter, so a
+// fresh instance making the same calls in the same order reproduces them.
+func WithNonDeterministicMode() Option {
+	return func(d *Deidentifier) {
+		d.nonDeterministic = true
+	}
+}
+
+// WithNumericNoise sets the largest relative change, in percent, that TypeNumericNoise
+// columns get; the default is 5. Larger bounds hide individual values better while
+// leaving aggregates noisier.
+func WithNumericNoise(pct float64) Option {
+	return func(d *Deidentifier) {
+		d.numericNoisePercent = math.Abs(pct)
+	}
+}
+
+// WithOriginalHash makes the Findings from TextWithFindings and ColumnWithFindings carry
+// OriginalHash, the hex HMAC-SHA256 of the original value under the secret key. Equal
+// originals give equal hashes, so ETL jobs can deduplicate source records without
+// keeping the PII; without the key the hash cannot be reversed or recomputed.
+func WithOriginalHash() Option {
+	return func(d *Deidentifier) {
+		d.hashOriginals = true
+	}
+}
+
+// WithOutputCase sets the letter case of generated emails and usernames for systems
+// that store them uppercase or compare them case-sensitively. Mappings keep the
+// lowercase replacement; the case is applied on output. The default is Lower.
+func WithOutputCase(c OutputCase) Option {
+	return func(d *Deidentifier) {
+		d.outputCase = c
+	}
+}
+
+// WithPreservePlusTag makes generated emails keep the "+tag" of the original's local
+// part, so joe+newsletter@x.com becomes something like user42+newsletter@anon.org and
+// routing analytics on tags still work. The rest of the local part is generated from
+// the untagged address, so every tag of one mailbox shares its fake. Combine it with
+// WithPreserveTLD to keep more of the domain. Tags can be as identifying as the mailbox
+// name, so only use this when they are known to be generic.
+func WithPreservePlusTag() Option {
+	return func(d *Deidentifier) {
+		d.preservePlusTag = true
+	}
+}
+
+// WithPreserveTLD makes generated emails keep the TLD of the original, including
+// multi-label ones such as co.uk, with the rest of the domain still generated, so
+// joe@harvard.edu becomes something like user42@anon.edu. The TLD allows coarse
+// segmentation (.edu, .gov, country) but narrows down the original domain.
+func WithPreserveTLD() Option {
+	return func(d *Deidentifier) {
+		d.preserveTLD = true
+	}
+}
+
+// WithRedactedTypes replaces values of the given types with a fixed token naming the
+// type, such as [ADDRESS], instead of a realistic fake. Reviewers can then tell at a
+// glance that a value was removed, and a fake cannot be mistaken for a real one. The
+// token is the same for every value, so joins on these types are lost. With no types it
+// applies to TypeAddress.
+func WithRedactedTypes(types ...DataType) Option {
+	return func(d *Deidentifier) {
+		if len(types) == 0 {
+			types = []DataType{TypeAddress}
+		}
+		if d.redactedTypes == nil {
+			d.redactedTypes = make(map[DataType]bool, len(types))
+		}
+		for _, t := range types {
+			d.redactedTypes[t] = true
+		}
+	}
+}
+
+// WithRedistributedAreaCodes makes generated phone numbers use an area code derived
+// from the hash instead of keeping the original one. Fake numbers then spread evenly
+// over all valid NANP area codes rather than clustering around the source data's
+// regions, at the cost of losing the coarse geographic signal the area code carries.
+// Formatting is still preserved.
+func WithRedistributedAreaCodes() Option {
+	return func(d *Deidentifier) {
+		d.redistributeAreaCodes = true
+	}
+}
+
+// WithRelativeDates makes Text detect relative dates such as "3 days ago", "in two
+// weeks", "last Tuesday" or "yesterday", which identify an event once the date of the
+// note is known. With a zero reference they become [DATE]; otherwise each resolves to an
+// absolute date against reference, moved by a shift of up to 30 days derived from the
+// key, so every date shifts alike and intervals are kept. Off by default, since phrases
+// such as "in a week" are not always dates.
+func WithRelativeDates(reference time.Time) Option {
+	return func(d *Deidentifier) {
+		d.relativeDates = true
+		d.dateReference = reference
+	}
+}
+
+// WithRepeatAlias makes Text write alias instead of the replacement for the second and
+// later mentions of the same dataType value within one call, as in "Mary Major called;
+// the customer asked ...". The first mention still gets the full deterministic
+// replacement. Call it once per type; by default every mention is replaced in full.
+func WithRepeatAlias(dataType DataType, alias string) Option {
+	return func(d *Deidentifier) {
+		if d.repeatAliases == nil {
+			d.repeatAliases = make(map[DataType]string)
+		}
+		d.repeatAliases[dataType] = alias
+	}
+}
+
+// WithReplacementLengthCap keeps replacements of dataType within n characters, for
+// fixed-width targets that would otherwise truncate them mid-word. It applies to
+// TypeName and TypeAddress, whose fakes are drawn from the pool entries that fit; those
+// with no fitting entry are cut at the last word boundary within n, or at n when a
+// single word is longer. Other types are ignored, since cutting an email, SSN, card or
+// IP address would leave an invalid value, as are caps of 0 or less.
+func WithReplacementLengthCap(dataType DataType, n int) Option {
+	return func(d *Deidentifier) {
+		if n <= 0 || dataType != TypeName && dataType != TypeAddress {
+			return
+		}
+		if d.lengthCaps == nil {
+			d.lengthCaps = make(map[DataType]int)
+		}
+		d.lengthCaps[dataType] = n
+	}
+}
+
+// WithSeparatorNormalization makes generated SSNs, phone numbers and credit card numbers
+// use separator between their digit groups whatever the input looked like, so "-" gives
+// 123-45-6789 and 555-123-4567 and "" gives bare digits. Parentheses around an area code
+// are dropped and a leading "+" is kept. It applies after WithCanonicalPhoneFormat; by
+// default the generated formats follow the input.
+func WithSeparatorNormalization(separator string) Option {
+	return func(d *Deidentifier) {
+		d.normalizeSeparators = true
+		d.separator = separator
+	}
+}
+
+// WithSkipAlreadyFake leaves values that already have the library's own output format
+// unchanged: emails on the generated placeholder domains and, together with
+// WithInvalidSSNRange, SSNs in the never-issued 900-999 area outside the ITIN groups.
+// Data that passes through a pipeline twice then keeps its first replacement instead of
+// being deidentified again.
+func WithSkipAlreadyFake() Option {
+	return func(d *Deidentifier) {
+		d.skipAlreadyFake = true
+	}
+}
+
+// WithTokenLength sets how many base32 characters Tokenize returns. Longer tokens make
+// accidental collisions rarer; n is clamped to the range 1 to 52.
+func WithTokenLength(n int) Option {
+	return func(d *Deidentifier) {
+		d.tokenLength = min(max(n, 1), maxTokenLength)
+	}
+}
+
+// WithUncertainRedaction turns on a "when in doubt, redact" step at the end of Text:
+// tokens no detector classified but that still look like PII, scoring at least
+// threshold on a 0-10 scale, are replaced with a generic DATA_ token. Partial numbers
+// of 9+ digits score 8, emails with an inner @ 8, 7-8 digits 6, IDs mixing letters
+// and 4+ digits 5, and 5-6 digits 4. Lower thresholds catch more leaks but also
+// replace dates, amounts and order numbers. Off by default; a threshold of 0 or less
+// disables it.
+func WithUncertainRedaction(threshold int) Option {
+	return func(d *Deidentifier) {
+		d.uncertainThreshold = threshold
+	}
+}
+
+// WithVersionTag appends GeneratorVersion to generated values whose format has room for
+// it, so audits of long-lived datasets can tell which generation algorithms produced a
+// fake: emails get it at the end of the local part (user123456.v1@example.org),
+// usernames and generic DATA_ tokens after an underscore (taylor_4921_v1). Other types
+// keep their exact formats and are not tagged.
+func WithVersionTag() Option {
+	return func(d *Deidentifier) {
+		d.tagVersion = true
+	}
+}
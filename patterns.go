@@ -1,25 +1,132 @@
 package deidentify
 
+// Private-use runes delimiting protected replacements in intermediate Text results
+const (
+	protectedStart = '\uE000'
+	protectedEnd   = '\uE001'
+)
+
 // Regular expression patterns for finding PII
 var (
-	// Email pattern
-	emailRegexPattern = `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`
+	// Email pattern. Dots may only separate local-part atoms and domain labels, so
+	// surrounding punctuation such as a sentence-final period is never captured.
+	emailRegexPattern = `[a-zA-Z0-9_%+-]+(?:\.[a-zA-Z0-9_%+-]+)*@(?:[a-zA-Z0-9](?:[a-zA-Z0-9-]*[a-zA-Z0-9])?\.)+[a-zA-Z]{2,}\b`
+
+	// Six-digit number ending a generated email username
+	emailNumberRegexPattern = `^\d{6}$`
+
+	// Social handle pattern (@user). The leading group keeps the @ of an email
+	// address from matching, since that is always preceded by a word character.
+	usernameRegexPattern = `(^|[^\w@.])(@\w{1,39})\b`
+
+	// Cryptocurrency wallet pattern: Ethereum hex, Bitcoin bech32 and Bitcoin Base58
+	cryptoAddressRegexPattern = `\b(?:0x[0-9a-fA-F]{40}|bc1[ac-hj-np-z02-9]{11,71}|BC1[AC-HJ-NP-Z02-9]{11,71}|[13][a-km-zA-HJ-NP-Z1-9]{25,34})\b`
+
+	// Placeholder for a replacement protected from later Text steps
+	protectedRegexPattern = "\uE000[a-z]+\uE001"
 
 	// Phone patterns
-	phoneRegexPattern       = `(\+\d{1,2}\s)?\(?\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}`
-	phoneFormatRegexPattern = `^(\+?1?\s?)?(\(?)(\d{3})(\)?[\s.-]?)(\d{3})([\s.-]?)(\d{4})`
+	phoneRegexPattern            = `(\+\d{1,2}\s)?\(?\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}`
+	localPhoneRegexPattern       = `\b\d{3}-\d{4}\b`
+	localPhoneFormatRegexPattern = `^\d{3}([\s.-])\d{4}$`
+	localPhoneBeforeRegexPattern = `(?:\d|\))[\s.-]?$`
+	localPhoneAfterRegexPattern  = `^[.-]\d`
+	barePhoneRegexPattern        = `(?:\+|\b)1?\d{10}\b`
+	phoneContextRegexPattern     = `(?i)\b(?:phone|tel|telephone|mobile|cell|call|fax|sms|text|whatsapp|contact)\b`
+	vanityPhoneRegexPattern      = `\b(1[-. ])?(?:(\d{3})[-. ])?(\d{3})[-. ]([A-Z0-9]*[A-Z][A-Z0-9]*)\b`
+	phoneFormatRegexPattern      = `^(\+?1?[\s.-]?)?(\(?)(\d{3})(\)?[\s.-]?)(\d{3})([\s.-]?)(\d{4})`
 
-	// SSN patterns
+	// Country code of an international number, written after "+" or "00" and ended by a
+	// separator, as in "+44 20 7946 0958" or "0049-30-1234567"
+	phoneCountryCodeRegexPattern = `^(?:\+|00)\d{1,3}[\s.-]`
+
+	// tel:, sms: and callto: URIs, whose number part may list several numbers separated
+	// by commas, and one number within it
+	phoneURIRegexPattern       = `(?i)\b(tel|sms|callto):([+\d().,-]*\d)`
+	phoneURINumberRegexPattern = `\+?[\d().-]*\d`
+
+	// SSN patterns. Candidates in text may touch letters, as in "ID123-45-6789", but not
+	// more digits, which processSSNs checks since RE2 has no lookaround; a column value
+	// holds an SSN when one is not part of a longer digit run
 	ssnRegexPattern        = `\d{3}[- ]?\d{2}[- ]?\d{4}`
+	ssnValueRegexPattern   = `(?:^|\D)\d{3}[- ]?\d{2}[- ]?\d{4}(?:\D|$)`
+	ssnPartsRegexPattern   = `^(\d{3})[- ]?(\d{2})[- ]?(\d{4})$`
 	ssnSpaceRegexPattern   = `[ ]`
 	ssnHyphenRegexPattern  = `[-]`
 	ssnContextRegexPattern = `(?i)SSN|social security`
 
+	// SWIFT/BIC code after a "SWIFT" or "BIC" label: 4-letter bank, 2-letter country,
+	// 2-character location and optional 3-character branch
+	swiftLabeledRegexPattern = `(?i)\b((?:SWIFT|BIC)(?:\s*/\s*BIC)?(?:\s+code)?\s*[:#]?\s*)((?-i:[A-Z]{6}[A-Z0-9]{2}(?:[A-Z0-9]{3})?))\b`
+	swiftRegexPattern        = `^[A-Za-z]{6}[A-Za-z0-9]{2}(?:[A-Za-z0-9]{3})?$`
+
+	// SSN after a label and optional adornment, as in "SSN #123456789", "SS# 123-45-6789"
+	// or "social security no. 123 45 6789"
+	ssnLabeledRegexPattern = `(?i)\b(SSN|SS|social security(?: number)?)(\s*(?:#|no\.?|num(?:ber)?\.?|:)?[\s#:]*)(\d{3}[- ]?\d{2}[- ]?\d{4})\b`
+
+	// Canadian Social Insurance Number patterns
+	sinRegexPattern        = `\b\d{3}[- ]?\d{3}[- ]?\d{3}\b`
+	sinContextRegexPattern = `(?i)\bSIN\b|social insurance`
+
+	// UK National Insurance Number pattern (e.g. AB123456C or AB 12 34 56 C)
+	ninoRegexPattern = `\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z] ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`
+
 	// Credit card pattern
-	creditCardRegexPattern = `\d{4}[\s-]?\d{4}[\s-]?\d{4}[\s-]?\d{4}`
+	creditCardRegexPattern = `\d{4}[\s.-]?\d{4}[\s.-]?\d{4}[\s.-]?\d{4}`
+
+	// Credit card context patterns, used when payment context is required
+	creditCardContextRegexPattern    = `(?i)\b(card|credit|debit|visa|mastercard|amex|american express|discover|payment|paid|pay|charged?|billing|cc)\b`
+	creditCardNonPaymentRegexPattern = `(?i)\b(order|isbn|sku|tracking|invoice|serial|part|item|ref|reference|account|acct|po|qty|quantity)\b\W*$`
 
 	// Name pattern
-	nameRegexPattern = `\b[A-Z][a-z]+ [A-Z][a-z]+\b`
+	nameRegexPattern = `\b[A-Z][a-z]+ [A-Z][a-z]+\b(?:,? (?:(?:Jr|Sr)\b\.?|(?:III|II|IV)\b))?`
+
+	// All-caps word pair, as names are printed in legal and government documents
+	uppercaseNameRegexPattern = `\b([A-Z]{2,})[ \t]+([A-Z]{2,}(?:-[A-Z]{2,})?)\b`
+
+	// Capitalized word checked against the given-name dictionary
+	givenNameRegexPattern = `\b[A-Z][a-z]+\b`
+
+	// Greeting at the start of a name match, which is not part of the name
+	greetingWordRegexPattern = `^(?i:hi|hello|hey|dear|thanks|cheers|morning|afternoon|evening) `
+
+	// Capitalized word directly before a candidate, meaning it ends a longer name
+	nameWordBeforeRegexPattern = `\b[A-Z][a-z]+ $`
+
+	// Name after a correspondence cue such as "Dear", "Attn:" or "Attention:", with an
+	// optional title; the cue and title are kept
+	salutationNameRegexPattern = `\b((?:(?i:dear)|(?i:attn)\.?:?|(?i:attention):)[ \t]+(?:(?:Mr|Mrs|Ms|Miss|Mx|Dr|Prof)\.?[ \t]+)?)([A-Z][a-z]+(?:[ '-][A-Z][a-z]+){0,2})\b`
+
+	// Name line of an email signature: one to four capitalized words or initials, and the
+	// sign-off line that may precede it, such as "Best regards," or "Thanks!"
+	signatureNameRegexPattern = `^[ \t]*([A-Z][a-z]+(?:[ '-](?:[A-Z]\.|[A-Z][a-z]+)){0,3})[ \t\r]*$`
+	signOffRegexPattern       = `^(?i:(?:best|kind|warm|warmest)?\s*regards|best(?: wishes)?|(?:many )?thanks|thank you|cheers|sincerely|yours(?: truly| sincerely)?|all the best|cordially|respectfully|take care)\s*[,.!]?$`
+
+	// Name with a quoted or parenthesized nickname between given name and surname, as in
+	// Robert "Bob" Smith or William (Bill) Jones
+	nicknameNameRegexPattern = `\b([A-Z][a-z]+)[ \t]+(?:"[A-Z][a-z]+"|“[A-Z][a-z]+”|'[A-Z][a-z]+'|\([A-Z][a-z]+\))[ \t]+([A-Z][a-z]+)\b`
+
+	// Name with a lowercase surname particle, as in Ludwig van Beethoven, Maria de la Cruz
+	// or Omar al-Farsi, with an optional street type after it that marks a street name
+	particleNameRegexPattern = `\b[A-Z][a-z]+(?:-[A-Z][a-z]+)?[ \t]+(?:(?:(?:van|von)[ \t]+(?:der|den|de)|de[ \t]+(?:la|las|los)|van|von|de|del|della|di|da|du|dos|das|ter|ten|bin|ibn)[ \t]+[A-Z][a-z]+|(?:[Aa]l|[Ee]l)-[A-Z][a-z]+)\b([ \t]+(?i:` + streetTypeRegexPattern + `)\b)?`
+
+	// Email header line with mailboxes, and one mailbox in it: a display name, bare or
+	// quoted, before an address in angle brackets
+	emailHeaderRegexPattern   = `(?im)^((?:from|to|cc|bcc|reply-to|sender):[ \t]*)(.+)$`
+	headerMailboxRegexPattern = `(^|[,;])(\s*)(?:"([^"\n]+)"|([^,;<>"\n]+?))(\s*<[^<>\n]*>)`
+
+	// Display name, quoted or as capitalized words, before an email address in angle
+	// brackets anywhere in text, as in "write to Jane Doe <jane@x.com>"
+	inlineMailboxRegexPattern = `(?:"([^"\n<>@]+)"|\b(\p{Lu}[\p{L}'.-]*(?:[ \t]+\p{Lu}[\p{L}'.-]*){0,3}))([ \t]*<[^<>\s@]+@[^<>\s]+>)`
+
+	// Greeting directly before a standalone given name
+	greetingRegexPattern = `(?i)\b(hi|hello|hey|dear|thanks|thank you|cheers|morning|afternoon|evening)\W*$`
+
+	// Name written surname first, as in "Smith, John"
+	reversedNameRegexPattern = `\b([A-Z][a-z]+), ([A-Z][a-z]+)\b`
+
+	// Generational suffix at the end of a name, kept as-is when the name is replaced
+	nameSuffixRegexPattern = `,? (?:(?:Jr|Sr)\b\.?|(?:III|II|IV)\b)$`
 
 	// Address patterns
 	addressWordRegexPattern = `(?i)\b(Street|Avenue|Road|Lane|Drive|Boulevard|Blvd|Way|Plaza|Square|Court|Terrace|Place|Circle|Alley|Row|Highway|Hwy|Parkway|Path|Trail|Crescent|Rue|Strasse|Straße|Calle|Via|Viale|Avenida|Carrer|Straat|Gasse|Weg|Camino|Ulica|Utca|Prospekt|Dori|Jalan|Marg|Dao|Jie|Lu)\b`
@@ -30,7 +137,10 @@ var (
 	// Country and location patterns
 	countryNameRegexPattern = `(?i)(Afghanistan|Albania|Algeria|Andorra|Angola|Argentina|Armenia|Australia|Austria|Azerbaijan|Bahamas|Bahrain|Bangladesh|Barbados|Belarus|Belgium|Belize|Benin|Bhutan|Bolivia|Bosnia|Brazil|Brunei|Bulgaria|Burkina\s+Faso|Burundi|Cambodia|Cameroon|Canada|Chad|Chile|China|Colombia|Comoros|Congo|Costa\s+Rica|Croatia|Cuba|Cyprus|Czech|Denmark|Djibouti|Dominica|Dominican\s+Republic|Ecuador|Egypt|El\s+Salvador|Eritrea|Estonia|Eswatini|Ethiopia|Fiji|Finland|France|Gabon|Gambia|Georgia|Germany|Ghana|Greece|Grenada|Guatemala|Guinea|Guyana|Haiti|Honduras|Hungary|Iceland|India|Indonesia|Iran|Iraq|Ireland|Israel|Italy|Jamaica|Japan|Jordan|Kazakhstan|Kenya|Kiribati|Korea|Kuwait|Kyrgyzstan|Laos|Latvia|Lebanon|Lesotho|Liberia|Libya|Liechtenstein|Lithuania|Luxembourg|Madagascar|Malawi|Malaysia|Maldives|Mali|Malta|Mauritania|Mauritius|Mexico|Micronesia|Moldova|Monaco|Mongolia|Montenegro|Morocco|Mozambique|Myanmar|Namibia|Nauru|Nepal|Netherlands|New\s+Zealand|Nicaragua|Niger|Nigeria|Norway|Oman|Pakistan|Palau|Panama|Papua\s+New\s+Guinea|Paraguay|Peru|Philippines|Poland|Portugal|Qatar|Romania|Russia|Rwanda|Samoa|San\s+Marino|Saudi\s+Arabia|Senegal|Serbia|Seychelles|Sierra\s+Leone|Singapore|Slovakia|Slovenia|Solomon\s+Islands|Somalia|South\s+Africa|South\s+Sudan|Spain|Sri\s+Lanka|Sudan|Suriname|Sweden|Switzerland|Syria|Taiwan|Tajikistan|Tanzania|Thailand|Togo|Tonga|Trinidad\s+and\s+Tobago|Tunisia|Turkey|Turkmenistan|Tuvalu|Uganda|Ukraine|United\s+Arab\s+Emirates|UAE|United\s+Kingdom|UK|Great\s+Britain|Britain|England|Scotland|Wales|United\s+States|USA|U\.S\.A\.|U\.S\.|US|America|Uruguay|Uzbekistan|Vanuatu|Vatican|Venezuela|Vietnam|Yemen|Zambia|Zimbabwe)`
 
-	cityRegexPattern = `(?i)(New\s+York|Los\s+Angeles|Chicago|Houston|Phoenix|Philadelphia|San\s+Antonio|San\s+Diego|Dallas|San\s+Jose|Austin|Jacksonville|Fort\s+Worth|Columbus|Charlotte|Indianapolis|San\s+Francisco|Seattle|Denver|Washington|Boston|London|Manchester|Birmingham|Liverpool|Glasgow|Edinburgh|Paris|Marseille|Lyon|Berlin|Munich|Hamburg|Frankfurt|Tokyo|Osaka|Kyoto|Seoul|Mumbai|Delhi|Hyderabad|Bangkok|Beijing|Shanghai|Hong\s+Kong|Singapore|Toronto|Vancouver|Montreal|Sydney|Melbourne|Brisbane|Madrid|Barcelona|Rome|Milan|Amsterdam|Brussels|Vienna|Prague|Moscow|St\.\s+Petersburg|Dubai|Abu\s+Dhabi|Riyadh|Cairo|Nairobi|Lagos|Johannesburg|Cape\s+Town|Casablanca|Istanbul|Ankara|Tehran|Baghdad|Karachi|Lahore|Dhaka|Jakarta|Manila|Auckland)`
+	cityRegexPattern = `(?i)(New\s+York|Los\s+Angeles|Chicago|Houston|Phoenix|Philadelphia|San\s+Antonio|San\s+Diego|Dallas|San\s+Jose|Austin|Jacksonville|Fort\s+Worth|Columbus|Charlotte|Indianapolis|San\s+Francisco|Seattle|Denver|Washington|Boston|London|Manchester|Birmingham|Liverpool|Glasgow|Edinburgh|Paris|Marseille|Lyon|Berlin|Munich|Hamburg|Frankfurt|Tokyo|Osaka|Kyoto|Seoul|Mumbai|Delhi|Hyderabad|Bangkok|Beijing|Shanghai|Hong\s+Kong|Singapore|Toronto|Vancouver|Montreal|Sydney|Melbourne|Brisbane|Madrid|Barcelona|Rome|Milan|Amsterdam|Brussels|Vienna|Prague|Moscow|St\.\s+Petersburg|Dubai|Abu\s+Dhabi|Riyadh|Cairo|Nairobi|Lagos|Johannesburg|Cape\s+Town|Casablanca|Istanbul|Ankara|Tehran|Baghdad|Karachi|Lahore|Dhaka|Jakarta|Manila|Auckland|Las\s+Vegas|New\s+Orleans|El\s+Paso|Santa\s+Monica|Salt\s+Lake|Kansas\s+City|Oklahoma\s+City|Baton\s+Rouge|Long\s+Beach|Virginia\s+Beach|Palo\s+Alto|Buenos\s+Aires|Rio\s+de\s+Janeiro|Tel\s+Aviv|Kuala\s+Lumpur|New\s+Delhi|Rhode\s+Island|New\s+Jersey|New\s+Hampshire|New\s+Mexico|North\s+Carolina|South\s+Carolina|North\s+Dakota|South\s+Dakota|West\s+Virginia)`
+
+	// Leading words that mark a capitalized pair as a place, as in "Fort Lauderdale"
+	placePrefixRegexPattern = `^(?:New|San|Santa|Los|Las|El|Fort|Port|Saint|Mount|Lake|North|South|East|West)\s`
 
 	// ISO country code pattern
 	isoCountryCodeRegexPattern = `(?i)\b(AF|AX|AL|DZ|AS|AD|AO|AI|AQ|AG|AR|AM|AW|AU|AT|AZ|BS|BH|BD|BB|BY|BE|BZ|BJ|BM|BT|BO|BQ|BA|BW|BV|BR|IO|BN|BG|BF|BI|KH|CM|CA|CV|KY|CF|TD|CL|CN|CX|CC|CO|KM|CG|CD|CK|CR|CI|HR|CU|CW|CY|CZ|DK|DJ|DM|DO|EC|EG|SV|GQ|ER|EE|ET|FK|FO|FJ|FI|FR|GF|PF|TF|GA|GM|GE|DE|GH|GI|GR|GL|GD|GP|GU|GT|GG|GN|GW|GY|HT|HM|VA|HN|HK|HU|IS|IN|ID|IR|IQ|IE|IM|IL|IT|JM|JP|JE|JO|KZ|KE|KI|KP|KR|KW|KG|LA|LV|LB|LS|LR|LY|LI|LT|LU|MO|MK|MG|MW|MY|MV|ML|MT|MH|MQ|MR|MU|YT|MX|FM|MD|MC|MN|ME|MS|MA|MZ|MM|NA|NR|NP|NL|NC|NZ|NI|NE|NG|NU|NF|MP|NO|OM|PK|PW|PS|PA|PG|PY|PE|PH|PN|PL|PT|PR|QA|RE|RO|RU|RW|BL|SH|KN|LC|MF|PM|VC|WS|SM|ST|SA|SN|RS|SC|SL|SG|SX|SK|SI|SB|SO|ZA|GS|SS|ES|LK|SD|SR|SJ|SZ|SE|CH|SY|TW|TJ|TZ|TH|TL|TG|TK|TO|TT|TN|TR|TM|TC|TV|UG|UA|AE|GB|US|USA|UM|UY|UZ|VU|VE|VN|VG|VI|WF|EH|YE|ZM|ZW)\b`
@@ -41,8 +151,64 @@ var (
 	specialAddressPattern2 = `(?i)(\d+)[\s,]+([A-Za-z\p{L}]+([\s'-][A-Za-z\p{L}]+)*[\s,]+)+(Rue|Via|Road|Street|Avenue)[\s,]+([A-Za-z\p{L}]+([\s'-][A-Za-z\p{L}]+)*)[\s,]+` + cityRegexPattern + `[\s,]+` + countryNameRegexPattern
 
 	// For addresses in text that might have a label before them (like "European HQ: 15 Rue de Rivoli")
-	specialAddressPattern3 = `(?i)(:\s+|at\s+|@\s+)(\d+[-\s]?\w*|\d+-\d+-\d+)[\s,]+([A-Za-z\p{L}]+([\s'-][A-Za-z\p{L}]+)*[\s,]+)+(Road|Rd|Street|St|Avenue|Ave|Boulevard|Blvd|Drive|Dr|Lane|Ln|Place|Pl|Rue|Via|Viale|Strasse|Straße|Calle|Avenida)`
+	specialAddressPattern3 = `(?i)(:\s+|at\s+|@\s+)(\d+[-\s]?\w*|\d+-\d+-\d+)[\s,]+([A-Za-z\p{L}]+([\s'-][A-Za-z\p{L}]+)*[\s,]+)+(Road|Rd|Street|St|Avenue|Ave|Boulevard|Blvd|Drive|Dr|Lane|Ln|Place|Pl|Rue|Via|Viale|Strasse|Straße|Calle|Avenida)\b` + addressTailRegexPattern
+
+	// What may follow the street word of an address found in running text: a unit and up
+	// to three capitalized comma-separated parts such as ", London, UK"
+	addressTailRegexPattern = `(?:\s+(?:Apt|Apartment|Suite|Ste|Unit)\.?\s*#?\w+|\s*#\w+)?(?:,\s*(?-i:\p{Lu}\p{L}*(?:[ '-]\p{Lu}\p{L}*)*)){0,3}`
+
+	// Comma-formatted full address ending in a country, as in "Hauptstraße 5, 10115 Berlin,
+	// Germany"; used by column inference, where the cell holds nothing but the address
+	addressCountryRegexPattern = `^[^,]*\d[^,]*(?:,[^,]+)*,\s*(?:` + countryNameRegexPattern + `|` + isoCountryCodeRegexPattern + `)\.?\s*$`
+
+	// Age in prose: "aged 45", "age: 45", or "45-year-old" and "45 years old"
+	ageExpressionRegexPattern = `(?i)\b(?:(aged?:?\s*)(\d{1,3})|(\d{1,3})([- ]years?[- ]old))\b`
+
+	// Relative date: "3 days ago", "in two weeks", "last Tuesday", "next month",
+	// "yesterday" or "tomorrow"
+	relativeDateCountRegexPattern = `(\d{1,3}|an?|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)`
+	relativeDateRegexPattern      = `(?i)\b(?:` + relativeDateCountRegexPattern + `\s+(day|week|month|year)s?\s+ago|in\s+` +
+		relativeDateCountRegexPattern + `\s+(day|week|month|year)s?|(last|next)\s+(monday|tuesday|wednesday|thursday|friday|saturday|sunday|week|month|year)|(yesterday|tomorrow))\b`
+
+	// GeneratorVersion tag WithVersionTag appends to the local part of generated emails
+	versionTagRegexPattern = `\.v\d+$`
+
+	// Unix timestamp in seconds or milliseconds, as a whole TypeDateTime value
+	unixTimestampRegexPattern = `^\d{10}(?:\d{3})?$`
+
+	// Number with an optional currency symbol or unit, such as "$1,234.50" or "12.5%"
+	numericValueRegexPattern = `^([^\d.,-]*)(-?\d{1,3}(?:,\d{3})+(?:\.\d+)?|-?\d+(?:\.\d+)?)(\D*)$`
+
+	// Run of separators between the digit groups of an SSN, phone or credit card number
+	separatorRunRegexPattern = `[\s.()/-]+`
+
+	// IPv4 address with an optional ":port" or "/mask" suffix; trailing dotted parts are
+	// matched so that longer dotted numbers fail to parse and are skipped as a whole
+	ipAddressRegexPattern = `\b\d{1,3}(?:\.\d{1,3}){3}(?:\.\d+)*(?:/\d{1,2}|:\d{1,5})?\b`
+
+	// Labeled coordinate pair such as "lat: 37.77, lng: -122.41" or `"lat": 37.77, "lon": ...`,
+	// and a single decimal coordinate within a TypeLatLong value
+	latLongLabeledRegexPattern = `(?i)("?\b(?:lat|latitude)"?\s*[:=]\s*)(-?\d{1,2}(?:\.\d+)?)(\s*[,;]?\s*"?\b(?:lng|lon|long|longitude)"?\s*[:=]\s*)(-?\d{1,3}(?:\.\d+)?)\b`
+	coordinateRegexPattern     = `-?\d{1,3}(?:\.\d+)?`
+
+	// HTML tag name at the start of a tag, and an attribute with its optional value
+	htmlTagNameRegexPattern   = `^<[A-Za-z][A-Za-z0-9-]*`
+	htmlAttributeRegexPattern = `([^\s"'<>/=]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'=<>` + "`" + `]+))?`
+
+	// Markdown code fence marker, link or image with its text, target and optional title,
+	// and the inline constructs Markdown scrubs separately: code spans and links
+	markdownFenceRegexPattern  = "^(?:```+|~~~+)"
+	markdownLinkRegexPattern   = `!?\[([^\]\n]*)\]\(\s*(<[^>\n]*>|[^\s)]*)(?:\s+"([^"\n]*)")?\s*\)`
+	markdownInlineRegexPattern = "``[^\n]*?``|`[^`\n]+`|" + markdownLinkRegexPattern
+
+	// Multi-line mailing address block: a street line, an optional unit line and a
+	// US-style "City, ST 12345" locality line
+	addressBlockRegexPattern    = `(?m)^[ \t]*\d+[A-Za-z]?[ \t]+[^\n]*\b(?:Street|St|Avenue|Ave|Road|Rd|Drive|Dr|Lane|Ln|Place|Pl|Boulevard|Blvd|Way|Court|Ct|Terrace|Ter|Circle|Cir|Parkway|Pkwy|Highway|Hwy)\b\.?[^\n]*\n(?:[ \t]*(?:Apt|Apartment|Suite|Ste|Unit|Floor|Fl|#)\.?[^\n]*\n)?[ \t]*[A-Z][A-Za-z .'-]*,?[ \t]+[A-Z]{2}[ \t]+\d{5}(?:-\d{4})?[ \t]*$`
+	addressLocalityRegexPattern = `^([ \t]*).+?,?[ \t]+([A-Z]{2})[ \t]+\d{5}(-\d{4})?[ \t]*$`
+
+	// Street type words, in any language, that end the street part of an address
+	streetTypeRegexPattern = `Street|St|Avenue|Ave|Road|Rd|Drive|Dr|Lane|Ln|Place|Pl|Boulevard|Blvd|Way|Plaza|Square|Sq|Court|Ct|Terrace|Ter|Circle|Cir|Alley|Row|Highway|Hwy|Parkway|Pkwy|Path|Trail|Tr|Crescent|Cres|Rue|Strasse|Straße|Calle|Via|Viale|Avenida|Carrer|Straat|Gasse|Weg|Camino|Ulica|Utca|Prospekt|Dori|Jalan|Marg|Dao|Jie|Lu|út|de la|del|di|van|von`
 
 	// Main address pattern to capture common formats across multiple countries
-	addressRegexPattern = `(?i)(\d+[-\s]?\w*|\d+-\d+-\d+)[\s,]+([A-Za-z\p{L}]+([\s'-][A-Za-z\p{L}]+)*[\s,]+)+(Street|St|Avenue|Ave|Road|Rd|Drive|Dr|Lane|Ln|Place|Pl|Boulevard|Blvd|Way|Plaza|Square|Sq|Court|Ct|Terrace|Ter|Circle|Cir|Alley|Row|Highway|Hwy|Parkway|Pkwy|Path|Trail|Tr|Crescent|Cres|Rue|Strasse|Straße|Calle|Via|Viale|Avenida|Carrer|Straat|Gasse|Weg|Camino|Ulica|Utca|Prospekt|Dori|Jalan|Marg|Dao|Jie|Lu|út|de la|del|di|van|von)(\s*,\s*|\s+)([A-Za-z\p{L}]+([\s'-][A-Za-z\p{L}]+)*)?(\s*,\s*|\s+)?(` + isoCountryCodeRegexPattern + `|` + countryNameRegexPattern + `)?`
+	addressRegexPattern = `(?i)(\d+[-\s]?\w*|\d+-\d+-\d+)[\s,]+([A-Za-z\p{L}]+([\s'-][A-Za-z\p{L}]+)*[\s,]+)+(` + streetTypeRegexPattern + `)(\s*,\s*|\s+|\b)(?:(?:Apt|Apartment|Suite|Ste|Unit)\.?\s*#?\w+(?:\s*,\s*|\s+|\b))?((?-i:\p{Lu}\p{L}*)([\s'-](?-i:\p{Lu}\p{L}*))*)?(\s*,\s*|\s+)?(` + isoCountryCodeRegexPattern + `|` + countryNameRegexPattern + `)?`
 )
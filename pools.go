@@ -0,0 +1,123 @@
+package deidentify
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// replacementPools holds the vocabularies generated names, streets and email domains
+// are drawn from. It starts with the built-in lists and can be replaced from files.
+type replacementPools struct {
+	firstNames []string
+	lastNames  []string
+	streets    []string
+	domains    []string
+	mutex      sync.RWMutex
+}
+
+// newReplacementPools creates pools holding the built-in vocabularies
+func newReplacementPools() *replacementPools {
+	return &replacementPools{
+		firstNames: firstNameOptions,
+		lastNames:  lastNameOptions,
+		streets:    streetNameOptions,
+		domains:    emailDomainOptions,
+	}
+}
+
+// LoadDomainPool replaces the email domains generated emails use with the
+// newline-delimited entries of the file at path. If the file cannot be read or holds
+// no entries, the built-in domains are used and the error is returned. Values mapped
+// before the call keep their replacements.
+func (d *Deidentifier) LoadDomainPool(path string) error {
+	domains, err := readPoolFile(path)
+
+	d.pools.mutex.Lock()
+	defer d.pools.mutex.Unlock()
+	if err != nil {
+		d.pools.domains = emailDomainOptions
+		return err
+	}
+	d.pools.domains = domains
+	return nil
+}
+
+// LoadNamePools replaces the first and last names generated names use with the
+// newline-delimited entries of the files at firstPath and lastPath. If either file
+// cannot be read or holds no entries, both built-in pools are used and the error is
+// returned. Values mapped before the call keep their replacements.
+func (d *Deidentifier) LoadNamePools(firstPath, lastPath string) error {
+	firstNames, err := readPoolFile(firstPath)
+	var lastNames []string
+	if err == nil {
+		lastNames, err = readPoolFile(lastPath)
+	}
+
+	d.pools.mutex.Lock()
+	defer d.pools.mutex.Unlock()
+	if err != nil {
+		d.pools.firstNames, d.pools.lastNames = firstNameOptions, lastNameOptions
+		return err
+	}
+	d.pools.firstNames, d.pools.lastNames = firstNames, lastNames
+	return nil
+}
+
+// LoadStreetPool replaces the street names generated addresses use with the
+// newline-delimited entries of the file at path. If the file cannot be read or holds
+// no entries, the built-in streets are used and the error is returned. Values mapped
+// before the call keep their replacements.
+func (d *Deidentifier) LoadStreetPool(path string) error {
+	streets, err := readPoolFile(path)
+
+	d.pools.mutex.Lock()
+	defer d.pools.mutex.Unlock()
+	if err != nil {
+		d.pools.streets = streetNameOptions
+		return err
+	}
+	d.pools.streets = streets
+	return nil
+}
+
+// readPoolFile reads the non-blank lines of a newline-delimited pool file
+func readPoolFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replacement pool: %w", err)
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if entry := strings.TrimSpace(line); entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("replacement pool %s is empty", path)
+	}
+	return entries, nil
+}
+
+// domainPool returns the current email domain pool
+func (p *replacementPools) domainPool() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.domains
+}
+
+// namePools returns the current first and last name pools
+func (p *replacementPools) namePools() (first, last []string) {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.firstNames, p.lastNames
+}
+
+// streetPool returns the current street name pool
+func (p *replacementPools) streetPool() []string {
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	return p.streets
+}
@@ -0,0 +1,110 @@
+package deidentify
+
+import "strings"
+
+// profileUnmatchedSamples caps how many unmatched cells a ColumnProfile keeps
+const profileUnmatchedSamples = 5
+
+// ProfileReport summarizes what detection finds in a sample: its row count and one
+// ColumnProfile per column
+type ProfileReport struct {
+	Rows    int
+	Columns []ColumnProfile
+}
+
+// ColumnProfile describes one column of a profiled sample. InferredType is the type
+// Slices would infer and Confidence the share of non-empty cells that match it.
+// TypeCounts counts the cells in which Text finds each type, and UnmatchedSamples holds
+// the first cells in which neither finds anything. The samples are original values, so
+// the report must be handled like the source data.
+type ColumnProfile struct {
+	Index            int
+	InferredType     DataType
+	Confidence       float64
+	Cells            int
+	TypeCounts       map[DataType]int
+	Unmatched        int
+	UnmatchedSamples []string
+}
+
+// Profile reports how well detection covers a sample before the library is trusted with
+// a new data source: per column, the inferred type and its confidence, which types Text
+// finds in the cells, and samples of cells that matched nothing. Rows are all treated as
+// data, so leave out a header row. Stored mappings are neither used nor changed.
+func (d *Deidentifier) Profile(data [][]string) ProfileReport {
+	numCols := 0
+	for _, row := range data {
+		numCols = max(numCols, len(row))
+	}
+
+	scanner := d.detached()
+	patterns := d.compilePatterns()
+	report := ProfileReport{Rows: len(data), Columns: make([]ColumnProfile, numCols)}
+	for col := range report.Columns {
+		report.Columns[col] = scanner.profileColumn(data, col, patterns)
+	}
+	return report
+}
+
+// profileCellTypes returns the types Text finds in a cell. A failing cell counts as
+// finding nothing.
+func (d *Deidentifier) profileCellTypes(value string) map[DataType]bool {
+	found := make(map[DataType]bool)
+	run := &textRun{collect: true}
+	if _, err := d.runText(run, value); err != nil {
+		return found
+	}
+	for _, finding := range run.findings {
+		found[finding.Type] = true
+	}
+	return found
+}
+
+// profileColumn builds the ColumnProfile of one column
+func (d *Deidentifier) profileColumn(data [][]string, col int, patterns *patternSet) ColumnProfile {
+	profile := ColumnProfile{
+		Index:        col,
+		InferredType: d.inferSingleColumnType(data, col, patterns),
+		TypeCounts:   make(map[DataType]int),
+	}
+
+	matching := 0
+	for row := range data {
+		if !d.isValidValue(data, row, col) {
+			continue
+		}
+		value := strings.TrimSpace(data[row][col])
+		profile.Cells++
+
+		scores := d.initializeTypeScores()
+		d.scoreValue(value, patterns, scores)
+		if profile.InferredType != TypeGeneric && scores[profile.InferredType] > 0 {
+			matching++
+		}
+
+		found := d.profileCellTypes(value)
+		for dataType := range found {
+			profile.TypeCounts[dataType]++
+		}
+		if len(found) == 0 && !d.scoredAny(scores) {
+			profile.Unmatched++
+			if len(profile.UnmatchedSamples) < profileUnmatchedSamples {
+				profile.UnmatchedSamples = append(profile.UnmatchedSamples, value)
+			}
+		}
+	}
+	if profile.Cells > 0 {
+		profile.Confidence = float64(matching) / float64(profile.Cells)
+	}
+	return profile
+}
+
+// scoredAny reports whether any type scored for a value
+func (d *Deidentifier) scoredAny(scores map[DataType]int) bool {
+	for _, score := range scores {
+		if score > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package deidentify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// presidioEntityTypes maps the Presidio entity names a recognizer may declare as its
+// supported_entity to the DataType its matches are replaced as
+var presidioEntityTypes = map[string]DataType{
+	"PERSON": TypeName, "EMAIL_ADDRESS": TypeEmail, "PHONE_NUMBER": TypePhone, "US_SSN": TypeSSN,
+	"CREDIT_CARD": TypeCreditCard, "LOCATION": TypeAddress, "CRYPTO": TypeCryptoAddress,
+	"UK_NINO": TypeNINO, "CA_SIN": TypeSIN, "SWIFT_CODE": TypeSWIFT,
+	"IP_ADDRESS": TypeIPAddress,
+}
+
+// recognizerConfig is one recognizer of a LoadRecognizers config: either a single
+// pattern, or a Presidio-style list of patterns sharing a type
+type recognizerConfig struct {
+	Name            string              `json:"name"`
+	Pattern         string              `json:"pattern"`
+	Type            string              `json:"type"`
+	Score           float64             `json:"score"`
+	SupportedEntity string              `json:"supported_entity"`
+	Patterns        []recognizerPattern `json:"patterns"`
+}
+
+// recognizerPattern is one pattern of a Presidio-style recognizer
+type recognizerPattern struct {
+	Name  string  `json:"name"`
+	Regex string  `json:"regex"`
+	Score float64 `json:"score"`
+}
+
+// LoadRecognizers registers the custom regex recognizers described by config with
+// RegisterPattern, so detection rules can be managed as data or migrated from Presidio.
+// config is JSON, which YAML tools can emit, holding a list of recognizers or an object
+// with a "recognizers" list. A recognizer has a name, a pattern, a type named as in
+// SupportedTypes, and a score; or, as in Presidio, a supported_entity and a list of
+// patterns each with a name, regex and score. Presidio entities such as PERSON or
+// US_SSN map to their DataType, and unknown ones, or a missing type, to
+// TypeFormattedID. Patterns use Go regexp syntax, so lookarounds are rejected. Higher
+// scores are registered first, so stronger patterns claim overlapping text. Nothing is
+// registered unless the whole config is valid.
+func (d *Deidentifier) LoadRecognizers(config []byte) error {
+	var recognizers []recognizerConfig
+	var err error
+	if trimmed := bytes.TrimSpace(config); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &recognizers)
+	} else {
+		var wrapper struct {
+			Recognizers []recognizerConfig `json:"recognizers"`
+		}
+		err = json.Unmarshal(trimmed, &wrapper)
+		recognizers = wrapper.Recognizers
+	}
+	if err != nil {
+		return fmt.Errorf("invalid recognizer config: %w", err)
+	}
+
+	var patterns []customPattern
+	var scores []float64
+	for i, recognizer := range recognizers {
+		dataType, err := d.recognizerType(recognizer)
+		if err != nil {
+			return fmt.Errorf("recognizer %d (%s): %w", i, recognizer.Name, err)
+		}
+		for _, pattern := range d.recognizerPatterns(recognizer) {
+			if pattern.Name == "" || pattern.Regex == "" {
+				return fmt.Errorf("recognizer %d (%s): every pattern needs a name and a regex", i, recognizer.Name)
+			}
+			re, err := regexp.Compile(pattern.Regex)
+			if err != nil {
+				return fmt.Errorf("recognizer %d (%s): invalid pattern %q: %w", i, recognizer.Name, pattern.Name, err)
+			}
+			patterns = append(patterns, customPattern{name: pattern.Name, regex: re, dataType: dataType})
+			scores = append(scores, pattern.Score)
+		}
+	}
+
+	order := make([]int, len(patterns))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return scores[order[a]] > scores[order[b]] })
+	for _, i := range order {
+		d.RegisterPattern(patterns[i].name, patterns[i].regex, patterns[i].dataType)
+	}
+	return nil
+}
+
+// recognizerPatterns returns the patterns of a recognizer, named after it when they
+// have no name of their own so their matches share its mapping namespace
+func (d *Deidentifier) recognizerPatterns(recognizer recognizerConfig) []recognizerPattern {
+	patterns := recognizer.Patterns
+	if recognizer.Pattern != "" {
+		patterns = append([]recognizerPattern{{Regex: recognizer.Pattern, Score: recognizer.Score}}, patterns...)
+	}
+	for i := range patterns {
+		if patterns[i].Name == "" {
+			patterns[i].Name = recognizer.Name
+		}
+	}
+	return patterns
+}
+
+// recognizerType returns the DataType a recognizer's matches are replaced as: its type,
+// else the DataType of its Presidio entity, else TypeFormattedID
+func (d *Deidentifier) recognizerType(recognizer recognizerConfig) (DataType, error) {
+	if recognizer.Type != "" {
+		return ParseDataType(recognizer.Type)
+	}
+	if dataType, ok := presidioEntityTypes[recognizer.SupportedEntity]; ok {
+		return dataType, nil
+	}
+	return TypeFormattedID, nil
+}
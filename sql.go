@@ -0,0 +1,66 @@
+package deidentify
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Rows reads a database/sql result set to the end and returns its rows deidentified as
+// strings. Column names come from rows.Columns() and double as mapping namespaces, as
+// in Slices. typeHints gives the type of a column by name; other columns are inferred
+// from the values read. NULL becomes an empty string. Rows is closed before returning.
+func (d *Deidentifier) Rows(rows *sql.Rows, typeHints map[string]DataType) ([][]string, error) {
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+
+	data, err := d.scanRows(rows, len(names))
+	if err != nil || len(data) == 0 {
+		return data, err
+	}
+
+	columnTypes, err := d.inferColumnTypes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to infer column types: %w", err)
+	}
+	for i, name := range names {
+		if dataType, ok := typeHints[name]; ok {
+			columnTypes[i] = dataType
+		}
+	}
+
+	config := &slicesConfig{columnTypes: columnTypes, columnNames: names, numCols: len(names)}
+	for i, row := range data {
+		if err := d.fillSliceRow(row, row, config, i); err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// scanRows reads every remaining row of rows as strings
+func (d *Deidentifier) scanRows(rows *sql.Rows, numCols int) ([][]string, error) {
+	data := [][]string{}
+	values := make([]sql.NullString, numCols)
+	dest := make([]interface{}, numCols)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan row %d: %w", len(data), err)
+		}
+		row := make([]string, numCols)
+		for i, value := range values {
+			row[i] = value.String
+		}
+		data = append(data, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,160 @@
+package deidentify
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// vcardFieldTypes maps vCard properties holding a single value to their DataType and
+// the canonical column name shared with Text
+var vcardFieldTypes = map[string]struct {
+	dataType DataType
+	column   string
+}{
+	"FN":    {TypeName, "name"},
+	"TEL":   {TypePhone, "phone"},
+	"EMAIL": {TypeEmail, "email"},
+}
+
+// vcardTextFields lists vCard properties holding free text or handles, which go through Text
+var vcardTextFields = map[string]bool{
+	"IMPP": true, "NICKNAME": true, "NOTE": true, "X-SOCIALPROFILE": true,
+}
+
+// VCard deidentifies a vCard (.vcf) document field by field while keeping its grammar:
+// FN and N hold names, TEL phones, EMAIL emails and ADR addresses, whose street and
+// postal code are replaced while locality, region and country are kept. Notes,
+// nicknames and social profiles go through Text. Folded lines are unfolded, and lines
+// that are not properties, or properties carrying no PII, pass through unchanged.
+func (d *Deidentifier) VCard(card string) (string, error) {
+	unfolded := regexp.MustCompile(`\r?\n[ \t]`).ReplaceAllString(card, "")
+	lines := strings.SplitAfter(unfolded, "\n")
+	for i, line := range lines {
+		content := strings.TrimRight(line, "\r\n")
+		scrubbed, err := d.scrubVCardLine(content)
+		if err != nil {
+			return "", fmt.Errorf("error deidentifying vCard line %d: %w", i+1, err)
+		}
+		lines[i] = scrubbed + line[len(content):]
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// scrubVCardAddress replaces the street and postal code of a structured ADR value
+// (PO box;extended;street;locality;region;postal code;country) and drops the extended
+// address, which holds unit numbers
+func (d *Deidentifier) scrubVCardAddress(value string) (string, error) {
+	parts := strings.Split(value, ";")
+	if len(parts) < 7 {
+		return d.Text(value)
+	}
+
+	parts[1] = ""
+	if parts[2] != "" {
+		street, err := d.deidentifyValue(parts[2], TypeAddress, "address")
+		if err != nil {
+			return "", err
+		}
+		parts[2] = street
+	}
+	if parts[5] != "" {
+		postalCode, err := d.deidentifyValue(parts[5], TypeFormattedID, "postal_code")
+		if err != nil {
+			return "", err
+		}
+		parts[5] = postalCode
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// scrubVCardField replaces a single-valued field, keeping a tel: or mailto: URI scheme
+// as vCard 4 writes them
+func (d *Deidentifier) scrubVCardField(value string, dataType DataType, column string) (string, error) {
+	scheme, rest, found := strings.Cut(value, ":")
+	if !found || !strings.EqualFold(scheme, "tel") && !strings.EqualFold(scheme, "mailto") {
+		return d.deidentifyValue(value, dataType, column)
+	}
+
+	replaced, err := d.deidentifyValue(rest, dataType, column)
+	if err != nil {
+		return "", err
+	}
+	return scheme + ":" + replaced, nil
+}
+
+// scrubVCardLine deidentifies the value of a single unfolded vCard property line
+func (d *Deidentifier) scrubVCardLine(line string) (string, error) {
+	colon := d.vcardValueStart(line)
+	if colon < 0 {
+		return line, nil
+	}
+	name, value := line[:colon], line[colon+1:]
+	property := strings.ToUpper(strings.SplitN(name, ";", 2)[0])
+	if dot := strings.LastIndex(property, "."); dot >= 0 {
+		property = property[dot+1:] // drop a group prefix such as "item1."
+	}
+
+	var scrubbed string
+	var err error
+	switch field, ok := vcardFieldTypes[property]; {
+	case value == "":
+		return line, nil
+	case ok:
+		scrubbed, err = d.scrubVCardField(value, field.dataType, field.column)
+	case property == "N":
+		scrubbed, err = d.scrubVCardName(value)
+	case property == "ADR":
+		scrubbed, err = d.scrubVCardAddress(value)
+	case vcardTextFields[property]:
+		scrubbed, err = d.Text(value)
+	default:
+		return line, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return name + ":" + scrubbed, nil
+}
+
+// scrubVCardName replaces a structured N value (family;given;additional;prefixes;
+// suffixes) with the name FN would get, dropping additional names. The last word of the
+// replacement is the family name and the rest the given names, so longer names from
+// WithNameWordCount stay whole; a single word, such as a redaction token, is the family
+// name alone.
+func (d *Deidentifier) scrubVCardName(value string) (string, error) {
+	parts := strings.Split(value, ";")
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return d.Text(value)
+	}
+
+	fake, err := d.deidentifyValue(parts[1]+" "+parts[0], TypeName, "name")
+	if err != nil {
+		return "", err
+	}
+	parts[0], parts[1] = fake, ""
+	if space := strings.LastIndex(fake, " "); space >= 0 {
+		parts[0], parts[1] = fake[space+1:], fake[:space]
+	}
+	if len(parts) > 2 {
+		parts[2] = ""
+	}
+	return strings.Join(parts, ";"), nil
+}
+
+// vcardValueStart returns the index of the colon separating a property's name and
+// parameters from its value, skipping colons inside quoted parameter values, or -1
+func (d *Deidentifier) vcardValueStart(line string) int {
+	quoted := false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case '"':
+			quoted = !quoted
+		case ':':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
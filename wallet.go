@@ -0,0 +1,191 @@
+package deidentify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+)
+
+// Alphabets used by cryptocurrency address encodings
+const (
+	base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	bech32Alphabet = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+)
+
+// Checksum constants for bech32 (witness version 0) and bech32m (version 1+)
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// bech32Checksum computes the six checksum values for hrp and data
+func (d *Deidentifier) bech32Checksum(hrp string, data []byte, checksumConst uint32) []byte {
+	values := make([]byte, 0, len(hrp)*2+1+len(data)+6)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+	values = append(values, 0, 0, 0, 0, 0, 0)
+
+	polymod := d.bech32Polymod(values) ^ checksumConst
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(polymod >> (5 * (5 - i)) & 31)
+	}
+	return checksum
+}
+
+// bech32Polymod is the BCH checksum function from BIP 173
+func (d *Deidentifier) bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, value := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(value)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+// convertBits regroups data from fromBits-wide to toBits-wide values, padding the tail
+func (d *Deidentifier) convertBits(data []byte, fromBits, toBits uint) []byte {
+	var acc, bits uint
+	maxValue := uint(1)<<toBits - 1
+	var out []byte
+	for _, value := range data {
+		acc = acc<<fromBits | uint(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxValue))
+		}
+	}
+	if bits > 0 {
+		out = append(out, byte(acc<<(toBits-bits)&maxValue))
+	}
+	return out
+}
+
+// decodeBase58 decodes a Base58 string, reporting false on characters outside the alphabet
+func (d *Deidentifier) decodeBase58(encoded string) ([]byte, bool) {
+	value := new(big.Int)
+	base := big.NewInt(58)
+	for i := 0; i < len(encoded); i++ {
+		digit := strings.IndexByte(base58Alphabet, encoded[i])
+		if digit < 0 {
+			return nil, false
+		}
+		value.Mul(value, base)
+		value.Add(value, big.NewInt(int64(digit)))
+	}
+
+	leadingZeros := 0
+	for leadingZeros < len(encoded) && encoded[leadingZeros] == base58Alphabet[0] {
+		leadingZeros++
+	}
+	return append(make([]byte, leadingZeros), value.Bytes()...), true
+}
+
+// encodeBase58Check encodes version and payload with a double-SHA256 checksum
+func (d *Deidentifier) encodeBase58Check(version byte, payload []byte) string {
+	raw := append([]byte{version}, payload...)
+	first := sha256.Sum256(raw)
+	second := sha256.Sum256(first[:])
+	raw = append(raw, second[:4]...)
+
+	var encoded []byte
+	value := new(big.Int).SetBytes(raw)
+	base := big.NewInt(58)
+	mod := new(big.Int)
+	for value.Sign() > 0 {
+		value.DivMod(value, base, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range raw {
+		if b != 0 {
+			break
+		}
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+	return string(encoded)
+}
+
+// generateBech32Address creates a segwit address with the witness version and program
+// length of original and a valid checksum
+func (d *Deidentifier) generateBech32Address(original string, hash []byte) string {
+	lower := strings.ToLower(original)
+	version := 0
+	if len(lower) > 3 {
+		version = strings.IndexByte(bech32Alphabet, lower[3])
+	}
+	if version < 0 || version > 16 {
+		version = 0
+	}
+
+	program := hash[:20]
+	if version != 0 || len(lower) > 42 {
+		program = d.deterministicHash(original + "\x00program")
+	}
+
+	data := append([]byte{byte(version)}, d.convertBits(program, 8, 5)...)
+	checksumConst := uint32(bech32Const)
+	if version != 0 {
+		checksumConst = bech32mConst
+	}
+
+	var b strings.Builder
+	b.WriteString("bc1")
+	for _, v := range append(data, d.bech32Checksum("bc", data, checksumConst)...) {
+		b.WriteByte(bech32Alphabet[v])
+	}
+	return b.String()
+}
+
+// generateCryptoAddress creates a deterministic fake wallet address of the same kind
+// as original: Ethereum hex, Bitcoin bech32 or Bitcoin Base58Check
+func (d *Deidentifier) generateCryptoAddress(original string) string {
+	hash := d.deterministicHash(original)
+
+	switch {
+	case strings.HasPrefix(strings.ToLower(original), "0x"):
+		return "0x" + hex.EncodeToString(hash[:20])
+	case strings.HasPrefix(strings.ToLower(original), "bc1"):
+		return d.generateBech32Address(original, hash)
+	default:
+		version := byte(0x00) // P2PKH, starts with 1
+		if strings.HasPrefix(original, "3") {
+			version = 0x05 // P2SH, starts with 3
+		}
+		return d.encodeBase58Check(version, hash[:20])
+	}
+}
+
+// isCryptoAddress checks a wallet address candidate. Base58 candidates must carry a
+// valid checksum, since long digit runs also fit the Base58 alphabet.
+func (d *Deidentifier) isCryptoAddress(candidate string) bool {
+	lower := strings.ToLower(candidate)
+	if strings.HasPrefix(lower, "0x") || strings.HasPrefix(lower, "bc1") {
+		return true
+	}
+
+	raw, ok := d.decodeBase58(candidate)
+	if !ok || len(raw) != 25 {
+		return false
+	}
+	first := sha256.Sum256(raw[:21])
+	second := sha256.Sum256(first[:])
+	return string(second[:4]) == string(raw[21:])
+}